@@ -54,7 +54,8 @@ var _ = Describe("AppFilesRepository", func() {
 		configRepo := testconfig.NewRepositoryWithDefaults()
 		configRepo.SetApiEndpoint(listFilesRedirectServer.URL)
 
-		gateway := net.NewCloudControllerGateway()
+		gateway := net.NewCloudControllerGateway(configRepo)
+		gateway.SetTrustedCerts(listFilesRedirectServer.TLS.Certificates)
 		repo := NewCloudControllerAppFilesRepository(configRepo, gateway)
 		list, err := repo.ListFiles("my-app-guid", "some/path")
 
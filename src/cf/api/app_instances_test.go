@@ -92,7 +92,8 @@ func createAppInstancesRepo(requests []testnet.TestRequest) (ts *httptest.Server
 	space.Guid = "my-space-guid"
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerAppInstancesRepository(configRepo, gateway)
 	return
 }
@@ -30,6 +30,10 @@ type ApplicationFromSummary struct {
 	Urls             []string
 	State            string
 	SpaceGuid        string `json:"space_guid"`
+	Command          string
+	Buildpack        string
+	EnvironmentJson  map[string]string `json:"environment_json"`
+	Services         []ServiceInstanceSummary
 }
 
 func (resource ApplicationFromSummary) ToFields() (app models.ApplicationFields) {
@@ -42,6 +46,9 @@ func (resource ApplicationFromSummary) ToFields() (app models.ApplicationFields)
 	app.RunningInstances = resource.RunningInstances
 	app.Memory = resource.Memory
 	app.SpaceGuid = resource.SpaceGuid
+	app.Command = resource.Command
+	app.BuildpackUrl = resource.Buildpack
+	app.EnvironmentVars = resource.EnvironmentJson
 
 	return
 }
@@ -54,6 +61,12 @@ func (resource ApplicationFromSummary) ToModel() (app models.AppSummary) {
 	}
 	app.RouteSummaries = routes
 
+	services := []models.ServiceInstance{}
+	for _, service := range resource.Services {
+		services = append(services, service.ToModel())
+	}
+	app.Services = services
+
 	return
 }
 
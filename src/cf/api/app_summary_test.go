@@ -52,8 +52,80 @@ var _ = Describe("AppSummaryRepository", func() {
 		Expect(app2.RunningInstances).To(Equal(1))
 		Expect(app2.Memory).To(Equal(uint64(512)))
 	})
+
+	It("TestGetAppSummary", func() {
+		getAppSummaryRequest := testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+			Method:   "GET",
+			Path:     "/v2/apps/app-1-guid/summary",
+			Response: testnet.TestResponse{Status: http.StatusOK, Body: getAppSummaryResponseBody},
+		})
+
+		ts, handler, repo := createAppSummaryRepo([]testnet.TestRequest{getAppSummaryRequest})
+		defer ts.Close()
+
+		app, apiResponse := repo.GetSummary("app-1-guid")
+		Expect(handler.AllRequestsCalled()).To(BeTrue())
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+
+		Expect(app.Name).To(Equal("app1"))
+		Expect(app.Memory).To(Equal(uint64(128)))
+		Expect(app.DiskQuota).To(Equal(uint64(1024)))
+		Expect(app.InstanceCount).To(Equal(2))
+		Expect(app.Command).To(Equal("bundle exec rackup"))
+		Expect(app.BuildpackUrl).To(Equal("ruby_buildpack"))
+		Expect(app.EnvironmentVars).To(Equal(map[string]string{"RAILS_ENV": "production"}))
+
+		Expect(len(app.RouteSummaries)).To(Equal(1))
+		Expect(app.RouteSummaries[0].URL()).To(Equal("app1.cfapps.io"))
+
+		Expect(len(app.Services)).To(Equal(1))
+		Expect(app.Services[0].Name).To(Equal("my-db"))
+		Expect(app.Services[0].ServicePlan.Name).To(Equal("small"))
+		Expect(app.Services[0].ServiceOffering.Label).To(Equal("mysql"))
+	})
 })
 
+var getAppSummaryResponseBody = `
+{
+  "guid":"app-1-guid",
+  "name":"app1",
+  "routes":[
+    {
+      "guid":"route-1-guid",
+      "host":"app1",
+      "domain":{
+        "guid":"domain-1-guid",
+        "name":"cfapps.io"
+      }
+    }
+  ],
+  "services":[
+    {
+      "guid":"my-db-guid",
+      "name":"my-db",
+      "service_plan":{
+        "guid":"small-guid",
+        "name":"small",
+        "service":{
+          "label":"mysql",
+          "provider":"core",
+          "version":"5.5"
+        }
+      }
+    }
+  ],
+  "running_instances":2,
+  "memory":128,
+  "disk_quota":1024,
+  "instances":2,
+  "state":"STARTED",
+  "command":"bundle exec rackup",
+  "buildpack":"ruby_buildpack",
+  "environment_json":{
+    "RAILS_ENV":"production"
+  }
+}`
+
 var getAppSummariesResponseBody = `
 {
   "apps":[
@@ -113,7 +185,8 @@ func createAppSummaryRepo(requests []testnet.TestRequest) (ts *httptest.Server,
 	ts, handler = testnet.NewTLSServer(requests)
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerAppSummaryRepository(configRepo, gateway)
 	return
 }
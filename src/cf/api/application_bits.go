@@ -114,8 +114,15 @@ func (repo CloudControllerApplicationBitsRepository) uploadBits(appGuid string,
 		contentType := fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
 		request.HttpReq.Header.Set("Content-Type", contentType)
 
+		// processing bits takes longer than other jobs, so wait at least
+		// five minutes unless CF_ASYNC_TIMEOUT asks for more
+		timeout := 5 * time.Minute
+		if repo.gateway.AsyncTimeout > timeout {
+			timeout = repo.gateway.AsyncTimeout
+		}
+
 		response := &Resource{}
-		_, apiResponse = repo.gateway.PerformPollingRequestForJSONResponse(request, response, 5*time.Minute)
+		_, apiResponse = repo.gateway.PerformPollingRequestForJSONResponse(request, response, timeout)
 		if apiResponse.IsNotSuccessful() {
 			return
 		}
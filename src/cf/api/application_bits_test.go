@@ -202,7 +202,8 @@ func testUploadApp(dir string, requests []testnet.TestRequest) (app models.Appli
 
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	gateway.PollingThrottle = time.Duration(0)
 	zipper := cf.ApplicationZipper{}
 	repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, zipper)
@@ -228,7 +229,7 @@ func testUploadApp(dir string, requests []testnet.TestRequest) (app models.Appli
 var _ = Describe("Testing with ginkgo", func() {
 	It("TestUploadWithInvalidDirectory", func() {
 		config := testconfig.NewRepository()
-		gateway := net.NewCloudControllerGateway()
+		gateway := net.NewCloudControllerGateway(config)
 		zipper := &cf.ApplicationZipper{}
 
 		repo := NewCloudControllerApplicationBitsRepository(config, gateway, zipper)
@@ -285,7 +285,8 @@ func createAppRepo(requests []testnet.TestRequest) (ts *httptest.Server, handler
 	ts, handler = testnet.NewTLSServer(requests)
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerApplicationRepository(configRepo, gateway)
 	return
 }
@@ -6,16 +6,31 @@ import (
 	"cf/terminal"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
-	"os"
 	"strings"
 )
 
 type AuthenticationRepository interface {
 	Authenticate(email string, password string) (apiResponse net.ApiResponse)
+	AuthenticateClient(clientId string, clientSecret string) (apiResponse net.ApiResponse)
+	AuthenticateWithPasscode(passcode string) (apiResponse net.ApiResponse)
+	GetLoginPrompts() (prompts map[string]AuthPrompt, apiResponse net.ApiResponse)
 	RefreshAuthToken() (updatedToken string, apiResponse net.ApiResponse)
 }
 
+const (
+	defaultClientId   = "cf"
+	maxReauthAttempts = 3
+)
+
+// AuthPrompt is one of the credentials the login server asks for. The
+// display name of the passcode prompt tells SSO users where to get a code.
+type AuthPrompt struct {
+	Type        string
+	DisplayName string
+}
+
 type UAAAuthenticationRepository struct {
 	config  configuration.ReadWriter
 	gateway net.Gateway
@@ -35,32 +50,148 @@ func (uaa UAAAuthenticationRepository) Authenticate(email string, password strin
 		"scope":      {""},
 	}
 
-	apiResponse = uaa.getAuthToken(data)
-	if apiResponse.IsNotSuccessful() && apiResponse.StatusCode == 401 {
-		apiResponse.Message = "Password is incorrect, please try again."
+	apiResponse = uaa.getAuthToken(data, defaultClientId, "")
+	if net.IsAuthError(apiResponse.Err()) {
+		apiResponse = net.NewApiResponseWithTypedError(&net.AuthError{
+			Message: "Password is incorrect, please try again.",
+			Cause:   apiResponse.Err(),
+		})
+		return
+	}
+
+	if apiResponse.IsSuccessful() {
+		uaa.config.SetClientCredentials("", "")
 	}
 	return
 }
 
-func (uaa UAAAuthenticationRepository) RefreshAuthToken() (updatedToken string, apiResponse net.ApiResponse) {
+func (uaa UAAAuthenticationRepository) AuthenticateClient(clientId string, clientSecret string) (apiResponse net.ApiResponse) {
+	apiResponse = uaa.getClientCredentialsToken(clientId, clientSecret)
+	if net.IsAuthError(apiResponse.Err()) {
+		apiResponse = net.NewApiResponseWithTypedError(&net.AuthError{
+			Message: "Client credentials are incorrect, please try again.",
+			Cause:   apiResponse.Err(),
+		})
+		return
+	}
+
+	if apiResponse.IsSuccessful() {
+		uaa.config.SetClientCredentials(clientId, clientSecret)
+	}
+	return
+}
+
+// AuthenticateWithPasscode exchanges a one-time passcode, obtained by logging
+// in to the login server through SSO, using UAA's passcode flavour of the
+// password grant.
+func (uaa UAAAuthenticationRepository) AuthenticateWithPasscode(passcode string) (apiResponse net.ApiResponse) {
 	data := url.Values{
-		"refresh_token": {uaa.config.RefreshToken()},
-		"grant_type":    {"refresh_token"},
-		"scope":         {""},
+		"passcode":   {passcode},
+		"grant_type": {"password"},
+		"scope":      {""},
 	}
 
-	apiResponse = uaa.getAuthToken(data)
+	apiResponse = uaa.getAuthToken(data, defaultClientId, "")
+	if net.IsAuthError(apiResponse.Err()) {
+		apiResponse = net.NewApiResponseWithTypedError(&net.AuthError{
+			Message: "Passcode is incorrect or has expired, please try again.",
+			Cause:   apiResponse.Err(),
+		})
+		return
+	}
+
+	if apiResponse.IsSuccessful() {
+		uaa.config.SetClientCredentials("", "")
+	}
+	return
+}
+
+func (uaa UAAAuthenticationRepository) GetLoginPrompts() (prompts map[string]AuthPrompt, apiResponse net.ApiResponse) {
+	type loginInfoResponse struct {
+		Prompts map[string][]string `json:"prompts"`
+	}
+
+	path := fmt.Sprintf("%s/login", uaa.config.AuthorizationEndpoint())
+	request, apiResponse := uaa.gateway.NewRequest("GET", path, "", nil)
+	if apiResponse.IsNotSuccessful() {
+		return
+	}
+
+	response := new(loginInfoResponse)
+	_, apiResponse = uaa.gateway.PerformRequestForJSONResponse(request, response)
+	if apiResponse.IsNotSuccessful() {
+		return
+	}
+
+	prompts = map[string]AuthPrompt{}
+	for name, prompt := range response.Prompts {
+		if len(prompt) < 2 {
+			continue
+		}
+		prompts[name] = AuthPrompt{Type: prompt[0], DisplayName: prompt[1]}
+	}
+	return
+}
+
+// RefreshAuthToken uses the refresh token, or asks for a new token with the
+// stored client credentials when authenticated as a client. When UAA rejects
+// the refresh the session is over, which is reported as an AuthError.
+func (uaa UAAAuthenticationRepository) RefreshAuthToken() (updatedToken string, apiResponse net.ApiResponse) {
+	if uaa.config.ClientId() != "" {
+		apiResponse = uaa.getClientCredentialsToken(uaa.config.ClientId(), uaa.config.ClientSecret())
+	} else {
+		data := url.Values{
+			"refresh_token": {uaa.config.RefreshToken()},
+			"grant_type":    {"refresh_token"},
+			"scope":         {""},
+		}
+		apiResponse = uaa.getAuthToken(data, defaultClientId, "")
+	}
 	updatedToken = uaa.config.AccessToken()
 
-	if apiResponse.IsError() {
-		fmt.Printf("%s\n\n", terminal.NotLoggedInText())
-		os.Exit(1)
+	if net.IsAuthError(apiResponse.Err()) || apiResponse.StatusCode == http.StatusBadRequest {
+		apiResponse = net.NewApiResponseWithTypedError(&net.AuthError{
+			Message: terminal.NotLoggedInText(),
+			Cause:   apiResponse.Err(),
+		})
 	}
+	return
+}
+
+// Reauthenticate asks the user for their password to start a new session
+// after the old one could not be refreshed. Clients have no password to ask
+// for, so they keep the error.
+func (uaa UAAAuthenticationRepository) Reauthenticate(ui terminal.UI) (updatedToken string, apiResponse net.ApiResponse) {
+	username := uaa.config.Username()
+	if uaa.config.ClientId() != "" || username == "" {
+		apiResponse = net.NewApiResponseWithTypedError(&net.AuthError{Message: terminal.NotLoggedInText()})
+		return
+	}
+
+	ui.Say("Your session has expired, please log in again as %s", terminal.EntityNameColor(username))
+
+	for i := 0; i < maxReauthAttempts; i++ {
+		password := ui.AskForPassword("Password%s", terminal.PromptColor(">"))
 
+		apiResponse = uaa.Authenticate(username, password)
+		if apiResponse.IsSuccessful() {
+			updatedToken = uaa.config.AccessToken()
+			return
+		}
+
+		ui.Say(apiResponse.Message)
+	}
 	return
 }
 
-func (uaa UAAAuthenticationRepository) getAuthToken(data url.Values) (apiResponse net.ApiResponse) {
+func (uaa UAAAuthenticationRepository) getClientCredentialsToken(clientId string, clientSecret string) (apiResponse net.ApiResponse) {
+	data := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	return uaa.getAuthToken(data, clientId, clientSecret)
+}
+
+func (uaa UAAAuthenticationRepository) getAuthToken(data url.Values, clientId string, clientSecret string) (apiResponse net.ApiResponse) {
 	type uaaErrorResponse struct {
 		Code        string `json:"error"`
 		Description string `json:"error_description"`
@@ -74,7 +205,7 @@ func (uaa UAAAuthenticationRepository) getAuthToken(data url.Values) (apiRespons
 	}
 
 	path := fmt.Sprintf("%s/oauth/token", uaa.config.AuthorizationEndpoint())
-	request, apiResponse := uaa.gateway.NewRequest("POST", path, "Basic "+base64.StdEncoding.EncodeToString([]byte("cf:")), strings.NewReader(data.Encode()))
+	request, apiResponse := uaa.gateway.NewRequest("POST", path, "Basic "+base64.StdEncoding.EncodeToString([]byte(clientId+":"+clientSecret)), strings.NewReader(data.Encode()))
 	if apiResponse.IsNotSuccessful() {
 		return
 	}
@@ -10,8 +10,10 @@ import (
 	. "github.com/onsi/gomega"
 	"net/http"
 	"net/http/httptest"
+	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testnet "testhelpers/net"
+	testterm "testhelpers/terminal"
 )
 
 var _ = Describe("AuthenticationRepository", func() {
@@ -29,6 +31,198 @@ var _ = Describe("AuthenticationRepository", func() {
 		Expect(deps.config.RefreshToken()).To(Equal("my_refresh_token"))
 	})
 
+	It("forgets stored client credentials when logging in as a user", func() {
+		deps := setupAuthDependencies(successfulLoginRequest)
+		defer teardownAuthDependencies(deps)
+		deps.config.SetClientCredentials("my-client", "my-secret")
+
+		auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+		apiResponse := auth.Authenticate("foo@example.com", "bar")
+
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		Expect(deps.config.ClientId()).To(BeEmpty())
+		Expect(deps.config.ClientSecret()).To(BeEmpty())
+	})
+
+	Describe("client credentials", func() {
+		It("authenticates as a client and stores its credentials", func() {
+			deps := setupAuthDependencies(successfulClientCredentialsRequest)
+			defer teardownAuthDependencies(deps)
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			apiResponse := auth.AuthenticateClient("my-client", "my-secret")
+
+			Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(deps.config.AccessToken()).To(Equal("BEARER my_client_token"))
+			Expect(deps.config.RefreshToken()).To(BeEmpty())
+			Expect(deps.config.ClientId()).To(Equal("my-client"))
+			Expect(deps.config.ClientSecret()).To(Equal("my-secret"))
+		})
+
+		It("reports incorrect client credentials", func() {
+			deps := setupAuthDependencies(unsuccessfulLoginRequest)
+			defer teardownAuthDependencies(deps)
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			apiResponse := auth.AuthenticateClient("my-client", "wrong-secret")
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.Message).To(Equal("Client credentials are incorrect, please try again."))
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&net.AuthError{}))
+			Expect(deps.config.ClientId()).To(BeEmpty())
+		})
+
+		It("refreshes the token with the stored client credentials", func() {
+			deps := setupAuthDependencies(successfulClientCredentialsRequest)
+			defer teardownAuthDependencies(deps)
+			deps.config.SetClientCredentials("my-client", "my-secret")
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			updatedToken, apiResponse := auth.RefreshAuthToken()
+
+			Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(updatedToken).To(Equal("BEARER my_client_token"))
+		})
+	})
+
+	Describe("when the session cannot be refreshed", func() {
+		var rejectedRefreshRequest = testnet.TestRequest{
+			Method: "POST",
+			Path:   "/oauth/token",
+			Response: testnet.TestResponse{
+				Status: http.StatusUnauthorized,
+				Body:   `{"error":"invalid_token","error_description":"Invalid refresh token"}`,
+			},
+		}
+
+		It("returns an auth error instead of exiting", func() {
+			deps := setupAuthDependencies(rejectedRefreshRequest)
+			defer teardownAuthDependencies(deps)
+			deps.config.SetRefreshToken("expired-refresh-token")
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			_, apiResponse := auth.RefreshAuthToken()
+
+			Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&net.AuthError{}))
+			Expect(apiResponse.Message).To(ContainSubstring("Not logged in"))
+		})
+
+		It("asks the user for their password to log in again", func() {
+			deps := setupAuthDependencies(testnet.TestRequest{
+				Method: "POST",
+				Path:   "/oauth/token",
+				Header: authHeaders,
+				Matcher: func(request *http.Request) {
+					err := request.ParseForm()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(request.Form.Get("username")).To(Equal("my-user"))
+					Expect(request.Form.Get("password")).To(Equal("my-password"))
+				},
+				Response: successfulLoginRequest.Response,
+			})
+			defer teardownAuthDependencies(deps)
+
+			accessToken, err := testconfig.EncodeAccessToken(configuration.TokenInfo{Username: "my-user"})
+			Expect(err).NotTo(HaveOccurred())
+			deps.config.SetAccessToken(accessToken)
+
+			ui := &testterm.FakeUI{Inputs: []string{"my-password"}}
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			updatedToken, apiResponse := auth.Reauthenticate(ui)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(updatedToken).To(Equal("BEARER my_access_token"))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"session has expired", "my-user"},
+			})
+			Expect(ui.PasswordPrompts).To(HaveLen(1))
+		})
+
+		It("does not prompt clients for a password", func() {
+			deps := setupAuthDependencies(rejectedRefreshRequest)
+			defer teardownAuthDependencies(deps)
+			deps.config.SetClientCredentials("my-client", "my-secret")
+
+			ui := &testterm.FakeUI{}
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			_, apiResponse := auth.Reauthenticate(ui)
+
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&net.AuthError{}))
+			Expect(ui.PasswordPrompts).To(BeEmpty())
+		})
+	})
+
+	Describe("SSO passcodes", func() {
+		It("authenticates with a one-time passcode", func() {
+			deps := setupAuthDependencies(testnet.TestRequest{
+				Method: "POST",
+				Path:   "/oauth/token",
+				Header: authHeaders,
+				Matcher: func(request *http.Request) {
+					err := request.ParseForm()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(request.Form.Get("passcode")).To(Equal("my-passcode"))
+					Expect(request.Form.Get("grant_type")).To(Equal("password"))
+					Expect(request.Form.Get("username")).To(BeEmpty())
+				},
+				Response: successfulLoginRequest.Response,
+			})
+			defer teardownAuthDependencies(deps)
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			apiResponse := auth.AuthenticateWithPasscode("my-passcode")
+
+			Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(deps.config.AccessToken()).To(Equal("BEARER my_access_token"))
+			Expect(deps.config.RefreshToken()).To(Equal("my_refresh_token"))
+		})
+
+		It("reports an incorrect passcode", func() {
+			deps := setupAuthDependencies(unsuccessfulLoginRequest)
+			defer teardownAuthDependencies(deps)
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			apiResponse := auth.AuthenticateWithPasscode("expired-passcode")
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.Message).To(Equal("Passcode is incorrect or has expired, please try again."))
+		})
+
+		It("reads the login prompts from the login server", func() {
+			deps := setupAuthDependencies(testnet.TestRequest{
+				Method: "GET",
+				Path:   "/login",
+				Response: testnet.TestResponse{
+					Status: http.StatusOK,
+					Body: `
+{
+  "prompts": {
+    "username": ["text", "Email"],
+    "password": ["password", "Password"],
+    "passcode": ["password", "One Time Code (Get one at https://login.example.com/passcode)"]
+  }
+}`},
+			})
+			defer teardownAuthDependencies(deps)
+
+			auth := NewUAAAuthenticationRepository(deps.gateway, deps.config)
+			prompts, apiResponse := auth.GetLoginPrompts()
+
+			Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(prompts["passcode"]).To(Equal(AuthPrompt{
+				Type:        "password",
+				DisplayName: "One Time Code (Get one at https://login.example.com/passcode)",
+			}))
+			Expect(prompts["username"].DisplayName).To(Equal("Email"))
+		})
+	})
+
 	It("TestUnsuccessfullyLoggingIn", func() {
 		deps := setupAuthDependencies(unsuccessfulLoginRequest)
 		defer teardownAuthDependencies(deps)
@@ -39,6 +233,8 @@ var _ = Describe("AuthenticationRepository", func() {
 		Expect(deps.handler.AllRequestsCalled()).To(BeTrue())
 		Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
 		Expect(apiResponse.Message).To(Equal("Password is incorrect, please try again."))
+		Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&net.AuthError{}))
+		Expect(apiResponse.StatusCode).To(Equal(401))
 		Expect(deps.config.AccessToken()).To(BeEmpty())
 	})
 
@@ -105,6 +301,29 @@ var successfulLoginMatcher = func(request *http.Request) {
 	Expect(request.Form.Get("scope")).To(Equal(""), "Scope did not mathc.")
 }
 
+var successfulClientCredentialsRequest = testnet.TestRequest{
+	Method: "POST",
+	Path:   "/oauth/token",
+	Header: http.Header{
+		"accept":        {"application/json"},
+		"content-type":  {"application/x-www-form-urlencoded"},
+		"authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte("my-client:my-secret"))},
+	},
+	Matcher: func(request *http.Request) {
+		err := request.ParseForm()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.Form.Get("grant_type")).To(Equal("client_credentials"))
+	},
+	Response: testnet.TestResponse{
+		Status: http.StatusOK,
+		Body: `
+{
+  "access_token": "my_client_token",
+  "token_type": "BEARER",
+  "expires_in": 43199
+} `},
+}
+
 var unsuccessfulLoginRequest = testnet.TestRequest{
 	Method: "POST",
 	Path:   "/oauth/token",
@@ -144,7 +363,8 @@ func setupAuthDependencies(request testnet.TestRequest) (deps authDependencies)
 	deps.config = testconfig.NewRepository()
 	deps.config.SetAuthorizationEndpoint(deps.ts.URL)
 
-	deps.gateway = net.NewUAAGateway()
+	deps.gateway = net.NewUAAGateway(deps.config)
+	deps.gateway.SetTrustedCerts(deps.ts.TLS.Certificates)
 	return
 }
 
@@ -25,16 +25,17 @@ var _ = Describe("BuildpackBitsRepository", func() {
 	var (
 		buildpacksDir string
 		configRepo    configuration.Repository
+		gateway       net.Gateway
 		repo          CloudControllerBuildpackBitsRepository
 		buildpack     models.Buildpack
 	)
 
 	BeforeEach(func() {
-		gateway := net.NewCloudControllerGateway()
 		pwd, _ := os.Getwd()
 
 		buildpacksDir = filepath.Join(pwd, "../../fixtures/buildpacks")
 		configRepo = testconfig.NewRepositoryWithDefaults()
+		gateway = net.NewCloudControllerGateway(configRepo)
 		repo = NewCloudControllerBuildpackBitsRepository(configRepo, gateway, cf.ApplicationZipper{})
 		buildpack = models.Buildpack{Name: "my-cool-buildpack", Guid: "my-cool-buildpack-guid"}
 	})
@@ -59,6 +60,8 @@ var _ = Describe("BuildpackBitsRepository", func() {
 			})
 			defer ts.Close()
 			configRepo.SetApiEndpoint(ts.URL)
+			gateway.SetTrustedCerts(ts.TLS.Certificates)
+			repo = NewCloudControllerBuildpackBitsRepository(configRepo, gateway, cf.ApplicationZipper{})
 
 			apiResponse := repo.UploadBuildpack(buildpack, buildpackPath)
 			Expect(handler.AllRequestsCalled()).To(BeTrue())
@@ -74,6 +77,8 @@ var _ = Describe("BuildpackBitsRepository", func() {
 			defer ts.Close()
 
 			configRepo.SetApiEndpoint(ts.URL)
+			gateway.SetTrustedCerts(ts.TLS.Certificates)
+			repo = NewCloudControllerBuildpackBitsRepository(configRepo, gateway, cf.ApplicationZipper{})
 
 			apiResponse := repo.UploadBuildpack(buildpack, buildpackPath)
 			Expect(handler.AllRequestsCalled()).To(BeTrue())
@@ -90,6 +95,8 @@ var _ = Describe("BuildpackBitsRepository", func() {
 				defer ts.Close()
 
 				configRepo.SetApiEndpoint(ts.URL)
+				gateway.SetTrustedCerts(ts.TLS.Certificates)
+				repo = NewCloudControllerBuildpackBitsRepository(configRepo, gateway, cf.ApplicationZipper{})
 
 				apiResponse := repo.UploadBuildpack(buildpack, buildpackPath)
 				Expect(handler.AllRequestsCalled()).To(BeTrue())
@@ -106,6 +113,8 @@ var _ = Describe("BuildpackBitsRepository", func() {
 					uploadBuildpackRequest("example-buildpack.zip"),
 				})
 				configRepo.SetApiEndpoint(apiServer.URL)
+				gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+				repo = NewCloudControllerBuildpackBitsRepository(configRepo, gateway, cf.ApplicationZipper{})
 			})
 
 			AfterEach(func() {
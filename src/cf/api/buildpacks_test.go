@@ -333,7 +333,8 @@ func createBuildpackRepo(requests ...testnet.TestRequest) (ts *httptest.Server,
 	ts, handler = testnet.NewTLSServer(requests)
 	config := testconfig.NewRepositoryWithDefaults()
 	config.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(config)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerBuildpackRepository(config, gateway)
 	return
 }
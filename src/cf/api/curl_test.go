@@ -30,7 +30,7 @@ type curlDependencies struct {
 func newCurlDependencies() (deps curlDependencies) {
 	deps.config = testconfig.NewRepository()
 	deps.config.SetAccessToken("BEARER my_access_token")
-	deps.gateway = net.NewCloudControllerGateway()
+	deps.gateway = net.NewCloudControllerGateway(deps.config)
 	return
 }
 
@@ -48,6 +48,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 		deps := newCurlDependencies()
 		deps.config.SetApiEndpoint(ts.URL)
+		deps.gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		repo := NewCloudControllerCurlRepository(deps.config, deps.gateway)
 		headers, body, apiResponse := repo.Request("GET", "/v2/endpoint", "", "")
@@ -75,6 +76,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 		deps := newCurlDependencies()
 		deps.config.SetApiEndpoint(ts.URL)
+		deps.gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		repo := NewCloudControllerCurlRepository(deps.config, deps.gateway)
 		_, _, apiResponse := repo.Request("POST", "/v2/endpoint", "", `{"key":"val"}`)
@@ -98,6 +100,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 		deps := newCurlDependencies()
 		deps.config.SetApiEndpoint(ts.URL)
+		deps.gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		repo := NewCloudControllerCurlRepository(deps.config, deps.gateway)
 		_, body, _ := repo.Request("POST", "/v2/endpoint", "", `{"key":"val"}`)
@@ -122,6 +125,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 		deps := newCurlDependencies()
 		deps.config.SetApiEndpoint(ts.URL)
+		deps.gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		headers := "content-type: ascii/cats\nx-something-else:5"
 		repo := NewCloudControllerCurlRepository(deps.config, deps.gateway)
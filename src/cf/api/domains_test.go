@@ -549,7 +549,8 @@ func createDomainRepo(reqs []testnet.TestRequest) (ts *httptest.Server, handler
 	ts, handler = testnet.NewTLSServer(reqs)
 	config := testconfig.NewRepositoryWithDefaults()
 	config.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(config)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerDomainRepository(config, gateway)
 	return
 }
@@ -32,7 +32,7 @@ func (repo RemoteEndpointRepository) UpdateEndpoint(endpoint string) (finalEndpo
 		finalEndpoint = "https://" + endpoint
 		apiResponse = repo.attemptUpdate(finalEndpoint)
 
-		if apiResponse.IsNotSuccessful() {
+		if apiResponse.IsNotSuccessful() && !apiResponse.IsInvalidSSLCert() {
 			finalEndpoint = "http://" + endpoint
 			apiResponse = repo.attemptUpdate(finalEndpoint)
 		}
@@ -55,6 +55,7 @@ func (repo RemoteEndpointRepository) attemptUpdate(endpoint string) (apiResponse
 	serverResponse := new(struct {
 		ApiVersion            string `json:"api_version"`
 		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
 		LoggregatorEndpoint   string `json:"logging_endpoint"`
 	})
 	_, apiResponse = repo.gateway.PerformRequestForJSONResponse(request, &serverResponse)
@@ -69,6 +70,7 @@ func (repo RemoteEndpointRepository) attemptUpdate(endpoint string) (apiResponse
 	repo.config.SetApiEndpoint(endpoint)
 	repo.config.SetApiVersion(serverResponse.ApiVersion)
 	repo.config.SetAuthorizationEndpoint(serverResponse.AuthorizationEndpoint)
+	repo.config.SetTokenEndpoint(serverResponse.TokenEndpoint)
 	repo.config.SetLoggregatorEndpoint(serverResponse.LoggregatorEndpoint)
 
 	return
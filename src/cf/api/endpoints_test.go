@@ -28,6 +28,7 @@ var validApiInfoEndpoint = func(w http.ResponseWriter, r *http.Request) {
   "version": 2,
   "description": "Cloud Foundry sponsored by Pivotal",
   "authorization_endpoint": "https://login.example.com",
+  "token_endpoint": "https://uaa.example.com",
   "logging_endpoint": "wss://loggregator.foo.example.org:4443",
   "api_version": "42.0.0"
 } `
@@ -57,10 +58,11 @@ var invalidJsonResponseApiEndpoint = func(w http.ResponseWriter, r *http.Request
 }
 
 func createEndpointRepoForUpdate(config configuration.ReadWriter, endpoint func(w http.ResponseWriter, r *http.Request)) (ts *httptest.Server, repo EndpointRepository) {
+	gateway := net.NewCloudControllerGateway(config)
 	if endpoint != nil {
 		ts = httptest.NewTLSServer(http.HandlerFunc(endpoint))
+		gateway.SetTrustedCerts(ts.TLS.Certificates)
 	}
-	gateway := net.NewCloudControllerGateway()
 	return ts, NewEndpointRepository(config, gateway)
 }
 
@@ -68,7 +70,7 @@ func createInsecureEndpointRepoForUpdate(config configuration.ReadWriter, endpoi
 	if endpoint != nil {
 		ts = httptest.NewServer(http.HandlerFunc(endpoint))
 	}
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(config)
 	return ts, NewEndpointRepository(config, gateway)
 }
 
@@ -98,6 +100,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 		Expect(config.AccessToken()).To(Equal(""))
 		Expect(config.AuthorizationEndpoint()).To(Equal("https://login.example.com"))
+		Expect(config.TokenEndpoint()).To(Equal("https://uaa.example.com"))
 		Expect(config.LoggregatorEndpoint()).To(Equal("wss://loggregator.foo.example.org:4443"))
 		Expect(config.ApiEndpoint()).To(Equal(ts.URL))
 		Expect(config.ApiVersion()).To(Equal("42.0.0"))
@@ -164,6 +167,18 @@ var _ = Describe("Testing with ginkgo", func() {
 		Expect(config.ApiVersion()).To(Equal("42.0.0"))
 	})
 
+	It("TestUpdateEndpointWhenUrlIsMissingSchemeAndHttpsEndpointHasAnInvalidCert", func() {
+		ts := httptest.NewTLSServer(http.HandlerFunc(validApiInfoEndpoint))
+		defer ts.Close()
+		repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
+
+		schemelessURL := strings.Replace(ts.URL, "https://", "", 1)
+		_, apiResponse := repo.UpdateEndpoint(schemelessURL)
+
+		Expect(apiResponse.IsInvalidSSLCert()).To(BeTrue())
+		Expect(config.ApiEndpoint()).To(Equal(""))
+	})
+
 	It("TestUpdateEndpointWhenEndpointReturns404", func() {
 		ts, repo := createEndpointRepoForUpdate(config, func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusNotFound)
@@ -188,7 +203,7 @@ var _ = Describe("Testing with ginkgo", func() {
 	It("TestGetCloudControllerEndpoint", func() {
 		config.SetApiEndpoint("http://api.example.com")
 
-		repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+		repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 		endpoint, apiResponse := repo.GetCloudControllerEndpoint()
 
@@ -199,7 +214,7 @@ var _ = Describe("Testing with ginkgo", func() {
 	It("TestGetLoggregatorEndpoint", func() {
 		config.SetLoggregatorEndpoint("wss://loggregator.example.com:4443")
 
-		repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+		repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 		endpoint, apiResponse := repo.GetLoggregatorEndpoint()
 
@@ -215,7 +230,7 @@ var _ = Describe("Testing with ginkgo", func() {
 		It("extrapolates the loggregator URL based on the API URL (SSL API)", func() {
 			config.SetApiEndpoint("https://api.run.pivotal.io")
 
-			repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+			repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 			endpoint, apiResponse := repo.GetLoggregatorEndpoint()
 			Expect(apiResponse.IsSuccessful()).To(BeTrue())
@@ -225,7 +240,7 @@ var _ = Describe("Testing with ginkgo", func() {
 		It("extrapolates the loggregator URL based on the API URL (non-SSL API)", func() {
 			config.SetApiEndpoint("http://api.run.pivotal.io")
 
-			repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+			repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 			endpoint, apiResponse := repo.GetLoggregatorEndpoint()
 			Expect(apiResponse.IsSuccessful()).To(BeTrue())
@@ -237,7 +252,7 @@ var _ = Describe("Testing with ginkgo", func() {
 		config := testconfig.NewRepository()
 		config.SetAuthorizationEndpoint("https://login.example.com")
 
-		repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+		repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 		endpoint, apiResponse := repo.GetUAAEndpoint()
 
@@ -247,7 +262,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 	It("TestEndpointsReturnAnErrorWhenMissing", func() {
 		config := testconfig.NewRepository()
-		repo := NewEndpointRepository(config, net.NewCloudControllerGateway())
+		repo := NewEndpointRepository(config, net.NewCloudControllerGateway(config))
 
 		_, response := repo.GetLoggregatorEndpoint()
 		Expect(response.IsNotSuccessful()).To(BeTrue())
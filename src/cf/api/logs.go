@@ -2,6 +2,7 @@ package api
 
 import (
 	"cf/configuration"
+	"cf/net"
 	"cf/terminal"
 	"cf/trace"
 	"code.google.com/p/go.net/websocket"
@@ -22,6 +23,7 @@ type LogsRepository interface {
 type LoggregatorLogsRepository struct {
 	config       configuration.Reader
 	endpointRepo EndpointRepository
+	trustedCerts []tls.Certificate
 }
 
 func NewLoggregatorLogsRepository(config configuration.Reader, endpointRepo EndpointRepository) (repo LoggregatorLogsRepository) {
@@ -30,6 +32,10 @@ func NewLoggregatorLogsRepository(config configuration.Reader, endpointRepo Endp
 	return
 }
 
+func (repo *LoggregatorLogsRepository) SetTrustedCerts(certificates []tls.Certificate) {
+	repo.trustedCerts = certificates
+}
+
 func (repo LoggregatorLogsRepository) RecentLogsFor(appGuid string, onConnect func(), logChan chan *logmessage.Message) (err error) {
 	host, apiResponse := repo.endpointRepo.GetLoggregatorEndpoint()
 	if apiResponse.IsNotSuccessful() {
@@ -65,9 +71,12 @@ func (repo LoggregatorLogsRepository) connectToWebsocket(location string, onConn
 	}
 
 	wsConfig.Header.Add("Authorization", repo.config.AccessToken())
-	wsConfig.TlsConfig = &tls.Config{InsecureSkipVerify: true}
+	wsConfig.TlsConfig, err = net.NewTLSConfig(repo.config, repo.trustedCerts)
+	if err != nil {
+		return
+	}
 
-	ws, err := websocket.DialConfig(wsConfig)
+	ws, err := repo.dialWebsocket(wsConfig)
 	if err != nil {
 		return
 	}
@@ -91,6 +100,33 @@ func (repo LoggregatorLogsRepository) connectToWebsocket(location string, onConn
 	return
 }
 
+// dialWebsocket connects through the same proxy as the API gateways before
+// upgrading the connection, since websocket.DialConfig always dials directly.
+func (repo LoggregatorLogsRepository) dialWebsocket(wsConfig *websocket.Config) (ws *websocket.Conn, err error) {
+	conn, err := net.DialThroughProxy(repo.config, wsConfig.Location)
+	if err != nil {
+		return
+	}
+
+	if wsConfig.Location.Scheme == "wss" {
+		tlsConfig := wsConfig.TlsConfig.Clone()
+		tlsConfig.ServerName = wsConfig.Location.Hostname()
+		tlsConn := tls.Client(conn, tlsConfig)
+		err = tlsConn.Handshake()
+		if err != nil {
+			conn.Close()
+			return
+		}
+		conn = tlsConn
+	}
+
+	ws, err = websocket.NewClient(wsConfig, conn)
+	if err != nil {
+		conn.Close()
+	}
+	return
+}
+
 func (repo LoggregatorLogsRepository) processMessages(messageQueue *SortedMessageQueue, inputChan <-chan *logmessage.Message, outputChan chan *logmessage.Message, stopLoggingChan <-chan bool) {
 	for {
 		select {
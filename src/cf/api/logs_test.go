@@ -7,6 +7,9 @@ import (
 	"github.com/cloudfoundry/loggregatorlib/logmessage"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	testapi "testhelpers/api"
@@ -85,6 +88,44 @@ var _ = Describe("loggregator logs repository", func() {
 			Expect(messages).To(Equal([]string{"My message 1", "My message 2", "My message 3"}))
 		})
 	})
+
+	Describe("connecting through a proxy", func() {
+		It("tunnels the websocket through the configured proxy", func() {
+			var tunnelledHosts []string
+			proxyServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				Expect(request.Method).To(Equal("CONNECT"))
+				tunnelledHosts = append(tunnelledHosts, request.Host)
+
+				upstream, err := net.Dial("tcp", request.Host)
+				Expect(err).NotTo(HaveOccurred())
+				defer upstream.Close()
+
+				conn, _, err := writer.(http.Hijacker).Hijack()
+				Expect(err).NotTo(HaveOccurred())
+				defer conn.Close()
+
+				conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}))
+			defer proxyServer.Close()
+
+			config := testconfig.NewRepositoryWithDefaults()
+			config.SetProxy(proxyServer.URL)
+			endpointRepo := &testapi.FakeEndpointRepo{}
+			endpointRepo.LoggregatorEndpointReturns.Endpoint = strings.Replace(testServer.URL, "https", "wss", 1)
+			repo := NewLoggregatorLogsRepository(config, endpointRepo)
+			repo.SetTrustedCerts(testServer.TLS.Certificates)
+
+			err := repo.RecentLogsFor("my-app-guid", func() {}, logChan)
+			Expect(err).NotTo(HaveOccurred())
+			close(logChan)
+
+			Expect(tunnelledHosts).To(Equal([]string{strings.TrimPrefix(testServer.URL, "https://")}))
+			Expect(requestHandler.lastPath).To(Equal("/dump/"))
+			Expect(len(logChan)).To(Equal(3))
+		})
+	})
 })
 
 func parseMessage(msgBytes []byte) (msg *logmessage.Message) {
@@ -122,6 +163,7 @@ func setupTestServerAndLogsRepo(messages ...[]byte) (testServer *httptest.Server
 	endpointRepo.LoggregatorEndpointReturns.Endpoint = strings.Replace(testServer.URL, "https", "wss", 1)
 
 	repo := NewLoggregatorLogsRepository(configRepo, endpointRepo)
+	repo.SetTrustedCerts(testServer.TLS.Certificates)
 	logsRepo = &repo
 	return
 }
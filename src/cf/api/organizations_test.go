@@ -199,7 +199,8 @@ func createOrganizationRepo(reqs ...testnet.TestRequest) (ts *httptest.Server, h
 
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerOrganizationRepository(configRepo, gateway)
 	return
 }
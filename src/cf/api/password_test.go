@@ -36,7 +36,8 @@ func createPasswordRepo(req testnet.TestRequest) (passwordServer *httptest.Serve
 	endpointRepo := &testapi.FakeEndpointRepo{}
 	endpointRepo.UAAEndpointReturns.Endpoint = passwordServer.URL
 	configRepo := testconfig.NewRepositoryWithDefaults()
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(passwordServer.TLS.Certificates)
 	repo = NewCloudControllerPasswordRepository(configRepo, gateway, endpointRepo)
 	return
 }
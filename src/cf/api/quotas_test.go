@@ -69,7 +69,8 @@ func createQuotaRepo(req testnet.TestRequest) (ts *httptest.Server, handler *tes
 
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerQuotaRepository(configRepo, gateway)
 	return
 }
@@ -323,7 +323,8 @@ func createRoutesRepo(requests ...testnet.TestRequest) (ts *httptest.Server, han
 
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	domainRepo = &testapi.FakeDomainRepository{}
 
 	repo = NewCloudControllerRouteRepository(configRepo, gateway, domainRepo)
@@ -169,7 +169,8 @@ func createServiceAuthTokenRepo(request testnet.TestRequest) (ts *httptest.Serve
 	ts, handler = testnet.NewTLSServer([]testnet.TestRequest{request})
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerServiceAuthTokenRepository(configRepo, gateway)
 	return
 }
@@ -107,7 +107,8 @@ func createServiceBindingRepo(requests []testnet.TestRequest) (ts *httptest.Serv
 	ts, handler = testnet.NewTLSServer(requests)
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerServiceBindingRepository(configRepo, gateway)
 	return
 }
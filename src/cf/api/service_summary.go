@@ -14,25 +14,8 @@ type ServiceInstancesSummaries struct {
 
 func (resource ServiceInstancesSummaries) ToModels() (instances []models.ServiceInstance) {
 	for _, instanceSummary := range resource.ServiceInstances {
-		applicationNames := resource.findApplicationNamesForInstance(instanceSummary.Name)
-
-		planSummary := instanceSummary.ServicePlan
-		servicePlan := models.ServicePlanFields{}
-		servicePlan.Name = planSummary.Name
-		servicePlan.Guid = planSummary.Guid
-
-		offeringSummary := planSummary.ServiceOffering
-		serviceOffering := models.ServiceOfferingFields{}
-		serviceOffering.Label = offeringSummary.Label
-		serviceOffering.Provider = offeringSummary.Provider
-		serviceOffering.Version = offeringSummary.Version
-
-		instance := models.ServiceInstance{}
-		instance.Name = instanceSummary.Name
-		instance.ApplicationNames = applicationNames
-		instance.ServicePlan = servicePlan
-		instance.ServiceOffering = serviceOffering
-
+		instance := instanceSummary.ToModel()
+		instance.ApplicationNames = resource.findApplicationNamesForInstance(instanceSummary.Name)
 		instances = append(instances, instance)
 	}
 
@@ -57,10 +40,30 @@ type ServiceInstanceSummaryApp struct {
 }
 
 type ServiceInstanceSummary struct {
+	Guid        string
 	Name        string
 	ServicePlan ServicePlanSummary `json:"service_plan"`
 }
 
+func (resource ServiceInstanceSummary) ToModel() (instance models.ServiceInstance) {
+	planSummary := resource.ServicePlan
+	servicePlan := models.ServicePlanFields{}
+	servicePlan.Name = planSummary.Name
+	servicePlan.Guid = planSummary.Guid
+
+	offeringSummary := planSummary.ServiceOffering
+	serviceOffering := models.ServiceOfferingFields{}
+	serviceOffering.Label = offeringSummary.Label
+	serviceOffering.Provider = offeringSummary.Provider
+	serviceOffering.Version = offeringSummary.Version
+
+	instance.Guid = resource.Guid
+	instance.Name = resource.Name
+	instance.ServicePlan = servicePlan
+	instance.ServiceOffering = serviceOffering
+	return
+}
+
 type ServicePlanSummary struct {
 	Name            string
 	Guid            string
@@ -724,12 +724,13 @@ func createServiceRepo(reqs []testnet.TestRequest) (ts *httptest.Server, handler
 }
 
 func createServiceRepoWithConfig(reqs []testnet.TestRequest, config configuration.ReadWriter) (ts *httptest.Server, handler *testnet.TestHandler, repo ServiceRepository) {
+	gateway := net.NewCloudControllerGateway(config)
 	if len(reqs) > 0 {
 		ts, handler = testnet.NewTLSServer(reqs)
 		config.SetApiEndpoint(ts.URL)
+		gateway.SetTrustedCerts(ts.TLS.Certificates)
 	}
 
-	gateway := net.NewCloudControllerGateway()
 	repo = NewCloudControllerServiceRepository(config, gateway)
 	return
 }
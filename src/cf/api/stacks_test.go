@@ -108,7 +108,8 @@ func createStackRepo(req testnet.TestRequest) (ts *httptest.Server, handler *tes
 
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCloudControllerStackRepository(configRepo, gateway)
 	return
 }
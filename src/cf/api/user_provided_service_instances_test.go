@@ -127,7 +127,8 @@ func createUserProvidedServiceInstanceRepo(req testnet.TestRequest) (ts *httptes
 	ts, handler = testnet.NewTLSServer([]testnet.TestRequest{req})
 	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ts.URL)
-	gateway := net.NewCloudControllerGateway()
+	gateway := net.NewCloudControllerGateway(configRepo)
+	gateway.SetTrustedCerts(ts.TLS.Certificates)
 	repo = NewCCUserProvidedServiceInstanceRepository(configRepo, gateway)
 	return
 }
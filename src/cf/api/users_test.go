@@ -64,7 +64,7 @@ var _ = Describe("UserRepository", func() {
 					Method: "GET",
 					Path:   "/v2/organizations/my-org-guid/managers",
 					Response: testnet.TestResponse{
-						Status: http.StatusGatewayTimeout,
+						Status: http.StatusInternalServerError,
 					},
 				}),
 			}
@@ -75,7 +75,7 @@ var _ = Describe("UserRepository", func() {
 			_, apiResponse := repo.ListUsersInOrgForRole("my-org-guid", models.ORG_MANAGER)
 
 			Expect(ccHandler.AllRequestsCalled()).To(BeTrue())
-			Expect(apiResponse.StatusCode).To(Equal(http.StatusGatewayTimeout))
+			Expect(apiResponse.StatusCode).To(Equal(http.StatusInternalServerError))
 		})
 
 		It("returns an error when the UAA endpoint cannot be determined", func() {
@@ -86,8 +86,9 @@ var _ = Describe("UserRepository", func() {
 			configRepo := testconfig.NewRepositoryWithDefaults()
 			configRepo.SetApiEndpoint(ts.URL)
 
-			ccGateway := net.NewCloudControllerGateway()
-			uaaGateway := net.NewUAAGateway()
+			ccGateway := net.NewCloudControllerGateway(configRepo)
+			ccGateway.SetTrustedCerts(ts.TLS.Certificates)
+			uaaGateway := net.NewUAAGateway(configRepo)
 			endpointRepo := &testapi.FakeEndpointRepo{}
 			endpointRepo.UAAEndpointReturns.ApiResponse = net.NewApiResponseWithError("Failed to get endpoint!", errors.New("Failed!"))
 
@@ -433,19 +434,22 @@ func createUsersRepo(ccReqs []testnet.TestRequest, uaaReqs []testnet.TestRequest
 	ccTarget := ""
 	uaaTarget := ""
 
+	configRepo := testconfig.NewRepositoryWithDefaults()
+	ccGateway := net.NewCloudControllerGateway(configRepo)
+	uaaGateway := net.NewUAAGateway(configRepo)
+
 	if len(ccReqs) > 0 {
 		cc, ccHandler = testnet.NewTLSServer(ccReqs)
 		ccTarget = cc.URL
+		ccGateway.SetTrustedCerts(cc.TLS.Certificates)
 	}
 	if len(uaaReqs) > 0 {
 		uaa, uaaHandler = testnet.NewTLSServer(uaaReqs)
 		uaaTarget = uaa.URL
+		uaaGateway.SetTrustedCerts(uaa.TLS.Certificates)
 	}
 
-	configRepo := testconfig.NewRepositoryWithDefaults()
 	configRepo.SetApiEndpoint(ccTarget)
-	ccGateway := net.NewCloudControllerGateway()
-	uaaGateway := net.NewUAAGateway()
 	endpointRepo := &testapi.FakeEndpointRepo{}
 	endpointRepo.UAAEndpointReturns.Endpoint = uaaTarget
 	repo = NewCloudControllerUserRepository(configRepo, uaaGateway, ccGateway, endpointRepo)
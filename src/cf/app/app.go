@@ -3,6 +3,7 @@ package app
 import (
 	"cf"
 	"cf/commands"
+	"cf/configuration"
 	"cf/terminal"
 	"cf/trace"
 	"fmt"
@@ -33,12 +34,20 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 	app.Usage = cf.Usage
 	app.Version = cf.Version
 	app.Action = helpCommand.Action
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{Name: "timings", Usage: "Print a summary of API request timings after the command"},
+		NewStringFlag("profile", "Run the command against the named profile instead of the current one"),
+	}
 	app.Commands = []cli.Command{
 		helpCommand,
 		{
 			Name:        "api",
 			Description: "Set or view target api url",
-			Usage:       fmt.Sprintf("%s api [URL]", cf.Name()),
+			Usage:       fmt.Sprintf("%s api [URL] [--skip-ssl-validation] [--ca-cert PATH]", cf.Name()),
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "skip-ssl-validation", Usage: "Skip verification of the API endpoint. Not recommended!"},
+				NewStringFlag("ca-cert", "Path to a PEM file of additional CA certificates to trust"),
+			},
 			Action: func(c *cli.Context) {
 				cmdRunner.RunCmdByName("api", c)
 			},
@@ -63,11 +72,18 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 		{
 			Name:        "auth",
 			Description: "Authenticate user non-interactively",
-			Usage: fmt.Sprintf("%s auth USERNAME PASSWORD\n\n", cf.Name()) +
+			Usage: fmt.Sprintf("%s auth USERNAME PASSWORD\n", cf.Name()) +
+				fmt.Sprintf("   %s auth --client-credentials CLIENT_ID CLIENT_SECRET\n\n", cf.Name()) +
 				terminal.WarningColor("WARNING:\n   Providing your password as a command line option is highly discouraged\n   Your password may be visible to others and may be recorded in your shell history\n\n") +
 				"EXAMPLE:\n" +
 				fmt.Sprintf("   %s auth name@example.com \"my password\" (use quotes for passwords with a space)\n", cf.Name()) +
-				fmt.Sprintf("   %s auth name@example.com \"\\\"password\\\"\" (escape quotes if used in password)", cf.Name()),
+				fmt.Sprintf("   %s auth name@example.com \"\\\"password\\\"\" (escape quotes if used in password)\n", cf.Name()) +
+				fmt.Sprintf("   %s auth --client-credentials my-ci-client \"my secret\" (authenticate as a UAA client)\n\n", cf.Name()) +
+				"TIP:\n" +
+				fmt.Sprintf("   With --client-credentials and no arguments, the %s and %s env vars are used", configuration.CF_CLIENT_ID, configuration.CF_CLIENT_SECRET),
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "client-credentials", Usage: "Authenticate as a UAA client with the client_credentials grant"},
+			},
 			Action: func(c *cli.Context) {
 				cmdRunner.RunCmdByName("auth", c)
 			},
@@ -89,6 +105,31 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 				cmdRunner.RunCmdByName("buildpacks", c)
 			},
 		},
+		{
+			Name:        "config",
+			Description: "Write default values to the config",
+			Usage: fmt.Sprintf("%s config --proxy URL\n\n", cf.Name()) +
+				"EXAMPLE:\n" +
+				fmt.Sprintf("   %s config --proxy socks5://proxy.example.com:1080\n", cf.Name()) +
+				fmt.Sprintf("   %s config --proxy \"\" (clear the proxy)", cf.Name()),
+			Flags: []cli.Flag{
+				NewStringFlag("proxy", "Proxy for API and log requests (http, https or socks5 URL), used instead of HTTP_PROXY/HTTPS_PROXY/ALL_PROXY"),
+			},
+			Action: func(c *cli.Context) {
+				cmdRunner.RunCmdByName("config", c)
+			},
+		},
+		{
+			Name:        "create-app-manifest",
+			Description: "Create an app manifest for an app that has been pushed successfully",
+			Usage:       fmt.Sprintf("%s create-app-manifest APP [-p MANIFEST_PATH]", cf.Name()),
+			Flags: []cli.Flag{
+				NewStringFlag("p", "Path to write the manifest to, defaults to manifest.yml in the current directory"),
+			},
+			Action: func(c *cli.Context) {
+				cmdRunner.RunCmdByName("create-app-manifest", c)
+			},
+		},
 		{
 			Name:        "create-buildpack",
 			Description: "Create a buildpack",
@@ -377,13 +418,14 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 			Name:        "login",
 			ShortName:   "l",
 			Description: "Log user in",
-			Usage: fmt.Sprintf("%s login [-a API_URL] [-u USERNAME] [-p PASSWORD] [-o ORG] [-s SPACE]\n\n", cf.Name()) +
+			Usage: fmt.Sprintf("%s login [-a API_URL] [-u USERNAME] [-p PASSWORD] [-o ORG] [-s SPACE] [--sso] [--skip-ssl-validation]\n\n", cf.Name()) +
 				terminal.WarningColor("WARNING:\n   Providing your password as a command line option is highly discouraged\n   Your password may be visible to others and may be recorded in your shell history\n\n") +
 				"EXAMPLE:\n" +
 				fmt.Sprintf("   %s login (omit username and password to login interactively -- %s will prompt for both)\n", cf.Name(), cf.Name()) +
 				fmt.Sprintf("   %s login -u name@example.com -p pa55woRD (specify username and password as arguments)\n", cf.Name()) +
 				fmt.Sprintf("   %s login -u name@example.com -p \"my password\" (use quotes for passwords with a space)\n", cf.Name()) +
-				fmt.Sprintf("   %s login -u name@example.com -p \"\\\"password\\\"\" (escape quotes if used in password)", cf.Name()),
+				fmt.Sprintf("   %s login -u name@example.com -p \"\\\"password\\\"\" (escape quotes if used in password)\n", cf.Name()) +
+				fmt.Sprintf("   %s login --sso (prompt for a one-time passcode from your single sign-on provider)", cf.Name()),
 			Flags: []cli.Flag{
 				StringFlagWithNoDefault{cli.StringFlag{
 					Name: "a", Usage: "API endpoint (e.g. https://api.example.com)",
@@ -392,6 +434,8 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 				NewStringFlag("p", "Password"),
 				NewStringFlag("o", "Org"),
 				NewStringFlag("s", "Space"),
+				cli.BoolFlag{Name: "sso", Usage: "Use a one-time passcode to login"},
+				cli.BoolFlag{Name: "skip-ssl-validation", Usage: "Skip verification of the API endpoint. Not recommended!"},
 			},
 			Action: func(c *cli.Context) {
 				cmdRunner.RunCmdByName("login", c)
@@ -474,6 +518,26 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 				cmdRunner.RunCmdByName("passwd", c)
 			},
 		},
+		{
+			Name:        "profile",
+			Description: "Create a named profile or switch to it",
+			Usage: fmt.Sprintf("%s profile create PROFILE\n", cf.Name()) +
+				fmt.Sprintf("   %s profile use PROFILE\n\n", cf.Name()) +
+				"TIP:\n" +
+				"   Each profile keeps its own API endpoint, login and targeted org and space.\n" +
+				fmt.Sprintf("   Use '%s --profile PROFILE COMMAND' to run a single command against another profile.", cf.Name()),
+			Action: func(c *cli.Context) {
+				cmdRunner.RunCmdByName("profile", c)
+			},
+		},
+		{
+			Name:        "profiles",
+			Description: "List all profiles",
+			Usage:       fmt.Sprintf("%s profiles", cf.Name()),
+			Action: func(c *cli.Context) {
+				cmdRunner.RunCmdByName("profiles", c)
+			},
+		},
 		{
 			Name:        "purge-service-offering",
 			Description: "Recursively remove a service and child objects from Cloud Foundry database without making requests to a service broker",
@@ -495,17 +559,19 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 			Usage: "Push a single app (with or without a manifest):\n" +
 				fmt.Sprintf("   %s push APP [-b BUILDPACK_NAME] [-c COMMAND] [-d DOMAIN] [-f MANIFEST_PATH]\n", cf.Name()) +
 				"   [-i NUM_INSTANCES] [-m MEMORY] [-n HOST] [-p PATH] [-s STACK] [-t TIMEOUT]\n" +
-				"   [--no-hostname] [--no-manifest] [--no-route] [--no-start]" +
+				"   [--route URL] [--no-hostname] [--no-manifest] [--no-route] [--no-start]\n" +
+				"   [--unmap-unlisted-routes]" +
 				"\n\n   Push multiple apps with a manifest:\n" +
-				fmt.Sprintf("   %s push [-f MANIFEST_PATH]\n", cf.Name()),
+				fmt.Sprintf("   %s push [-f MANIFEST_PATH] [--var NAME=VALUE] [--vars-file VARS_FILE_PATH]\n", cf.Name()) +
+				"\n   Manifest ((variables)) are set with --var, --vars-file or CF_VAR_NAME env vars\n",
 			Flags: []cli.Flag{
 				NewStringFlag("b", "Custom buildpack by name (e.g. my-buildpack) or GIT URL (e.g. https://github.com/heroku/heroku-buildpack-play.git)"),
 				NewStringFlag("c", "Startup command, set to null to reset to default start command"),
-				NewStringFlag("d", "Domain (e.g. example.com)"),
+				NewStringSliceFlag("d", "Domain (e.g. example.com), flag can be specified multiple times"),
 				NewStringFlag("f", "Path to manifest"),
 				NewStringFlag("i", "Number of instances"),
 				NewStringFlag("m", "Memory limit (e.g. 256M, 1024M, 1G)"),
-				NewStringFlag("n", "Hostname (e.g. my-subdomain)"),
+				NewStringSliceFlag("n", "Hostname (e.g. my-subdomain), flag can be specified multiple times"),
 				NewStringFlag("p", "Path of app directory or zip file"),
 				NewStringFlag("s", "Stack to use"),
 				NewStringFlag("t", "Start timeout in seconds"),
@@ -513,6 +579,10 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 				cli.BoolFlag{Name: "no-manifest", Usage: "Ignore manifest file"},
 				cli.BoolFlag{Name: "no-route", Usage: "Do not map a route to this app"},
 				cli.BoolFlag{Name: "no-start", Usage: "Do not start an app after pushing"},
+				NewStringSliceFlag("route", "Route to map to the app by URL (e.g. www.example.com), flag can be specified multiple times"),
+				cli.BoolFlag{Name: "unmap-unlisted-routes", Usage: "Unmap the routes of the app that are not given in the manifest or with flags"},
+				NewStringSliceFlag("var", "Variable to fill in the manifest with (e.g. host=my-app), flag can be specified multiple times"),
+				NewStringSliceFlag("vars-file", "Path to a YAML file of manifest variables, flag can be specified multiple times"),
 			},
 			Action: func(c *cli.Context) {
 				cmdRunner.RunCmdByName("push", c)
@@ -862,6 +932,19 @@ func NewApp(cmdRunner commands.Runner) (app *cli.App, err error) {
 				cmdRunner.RunCmdByName("update-user-provided-service", c)
 			},
 		},
+		{
+			Name:        "validate-manifest",
+			Description: "Check a manifest for errors without pushing it",
+			Usage:       fmt.Sprintf("%s validate-manifest [-f MANIFEST_PATH] [--var NAME=VALUE] [--vars-file VARS_FILE_PATH]", cf.Name()),
+			Flags: []cli.Flag{
+				NewStringFlag("f", "Path to manifest"),
+				NewStringSliceFlag("var", "Variable to fill in the manifest with (e.g. host=my-app), flag can be specified multiple times"),
+				NewStringSliceFlag("vars-file", "Path to a YAML file of manifest variables, flag can be specified multiple times"),
+			},
+			Action: func(c *cli.Context) {
+				cmdRunner.RunCmdByName("validate-manifest", c)
+			},
+		},
 	}
 	return
 }
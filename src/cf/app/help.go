@@ -22,17 +22,45 @@ var appHelpTemplate = `{{.Title "NAME:"}}
 {{range .}}   {{.Name}} {{.Description}}
 {{end}}{{end}}{{end}}
 {{.Title "ENVIRONMENT VARIABLES"}}
+   CF_API=api.example.com             API endpoint to use instead of the targeted one
+   CF_ASYNC_TIMEOUT=20                Max wait time for async API jobs such as deletes, in seconds
+   CF_CLIENT_ID=my-ci-client          Client id to log in with, also used by 'cf auth --client-credentials'
+   CF_CLIENT_SECRET=my-secret         Client secret to log in with, also used by 'cf auth --client-credentials'
    CF_COLOR=false                     Do not colorize output
+   CF_CONFIG_KEY=my-passphrase        Encrypt the tokens in the config file with this passphrase
+   CF_CONFIG_KEY_FILE=path/to/key     Encrypt the tokens in the config file with the passphrase in this file
    CF_HOME=path/to/dir/               Override path to default config directory
+   CF_HTTP_CONNECT_TIMEOUT=30         Max wait time to connect to the API, in seconds
+   CF_HTTP_TIMEOUT=60                 Max wait time for an API request, in seconds (no limit by default)
+   CF_HTTP_TLS_TIMEOUT=10             Max wait time for the TLS handshake, in seconds
+   CF_MAX_REDIRECTS=3                 Max redirects followed for an API request
+   CF_MAX_RETRIES=3                   Max retries for failed idempotent API requests
+   CF_ORG=my-org                      Org to target instead of the targeted one
+   CF_PASSWORD=my-password            Password to log in with, together with CF_USERNAME
+   CF_PROFILE=staging                 Use the named profile instead of the current one
+   CF_RATE_LIMIT_MAX_WAIT=120         Max total wait time when rate limited by the API, in seconds
+   CF_RECORD=path/to/cassette.json    Record sanitized API requests and responses to a file
+   CF_REPLAY=path/to/cassette.json    Serve API responses from a recorded file
+   CF_RETRY_MAX_BACKOFF=10            Max wait time between retries, in seconds
+   CF_SPACE=my-space                  Space to target instead of the targeted one
    CF_STAGING_TIMEOUT=15              Max wait time for buildpack staging, in minutes
    CF_STARTUP_TIMEOUT=5               Max wait time for app instance startup, in minutes
+   CF_TIMINGS=true                    Print a summary of API request timings after each command
    CF_TRACE=true                      Print API request diagnostics to stdout
    CF_TRACE=path/to/trace.log         Append API request diagnostics to a log file
+   CF_TRACE_FORMAT=json               Trace one JSON record per request (or har for a HAR trace file)
+   CF_USERNAME=my-user                User to log in as, together with CF_PASSWORD
+   CF_VAR_NAME=value                  Fill in ((NAME)) in manifests
+   ALL_PROXY=socks5://proxy:1080      Proxy API and log requests through SOCKS5 (or HTTP)
    HTTP_PROXY=proxy.example.com:8080  Enable HTTP proxying for API requests
+   HTTPS_PROXY=proxy.example.com:443  Enable HTTP proxying for https API and log requests
+   NO_PROXY=.example.com,10.0.0.0/8   Hosts, domains and networks reached without a proxy
 
 {{.Title "GLOBAL OPTIONS"}}
    --version, -v                      Print the version
    --help, -h                         Show help
+   --timings                          Print a summary of API request timings after the command
+   --profile PROFILE                  Run the command against the named profile instead of the current one
 `
 
 type groupedCommands struct {
@@ -109,6 +137,9 @@ func newAppPresenter(app *cli.App) (presenter appPresenter) {
 				}, {
 					newCmdPresenter(app, maxNameLen, "api"),
 					newCmdPresenter(app, maxNameLen, "auth"),
+				}, {
+					newCmdPresenter(app, maxNameLen, "profiles"),
+					newCmdPresenter(app, maxNameLen, "profile"),
 				},
 			},
 		}, {
@@ -119,6 +150,8 @@ func newAppPresenter(app *cli.App) (presenter appPresenter) {
 					newCmdPresenter(app, maxNameLen, "app"),
 				}, {
 					newCmdPresenter(app, maxNameLen, "push"),
+					newCmdPresenter(app, maxNameLen, "validate-manifest"),
+					newCmdPresenter(app, maxNameLen, "create-app-manifest"),
 					newCmdPresenter(app, maxNameLen, "scale"),
 					newCmdPresenter(app, maxNameLen, "delete"),
 					newCmdPresenter(app, maxNameLen, "rename"),
@@ -261,6 +294,7 @@ func newAppPresenter(app *cli.App) (presenter appPresenter) {
 			CommandSubGroups: [][]cmdPresenter{
 				{
 					newCmdPresenter(app, maxNameLen, "curl"),
+					newCmdPresenter(app, maxNameLen, "config"),
 				},
 			},
 		},
@@ -12,14 +12,14 @@ import (
 type Api struct {
 	ui           terminal.UI
 	endpointRepo api.EndpointRepository
-	config       configuration.Reader
+	config       configuration.ReadWriter
 }
 
 type ApiEndpointSetter interface {
 	SetApiEndpoint(endpoint string)
 }
 
-func NewApi(ui terminal.UI, config configuration.Reader, endpointRepo api.EndpointRepository) (cmd Api) {
+func NewApi(ui terminal.UI, config configuration.ReadWriter, endpointRepo api.EndpointRepository) (cmd Api) {
 	cmd.ui = ui
 	cmd.config = config
 	cmd.endpointRepo = endpointRepo
@@ -42,6 +42,8 @@ func (cmd Api) Run(c *cli.Context) {
 		return
 	}
 
+	cmd.config.SetSSLDisabled(c.Bool("skip-ssl-validation"))
+	cmd.config.SetCACertFile(c.String("ca-cert"))
 	cmd.SetApiEndpoint(c.Args()[0])
 }
 
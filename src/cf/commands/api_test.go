@@ -3,6 +3,7 @@ package commands_test
 import (
 	. "cf/commands"
 	"cf/configuration"
+	"cf/net"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	testapi "testhelpers/api"
@@ -13,7 +14,7 @@ import (
 	testterm "testhelpers/terminal"
 )
 
-func callApi(args []string, config configuration.Reader, endpointRepo *testapi.FakeEndpointRepo) (ui *testterm.FakeUI) {
+func callApi(args []string, config configuration.ReadWriter, endpointRepo *testapi.FakeEndpointRepo) (ui *testterm.FakeUI) {
 	ui = new(testterm.FakeUI)
 
 	cmd := NewApi(ui, config, endpointRepo)
@@ -52,6 +53,41 @@ var _ = Describe("Testing with ginkgo", func() {
 		})
 	})
 
+	It("TestApiWhenSkippingSSLValidation", func() {
+		config := testconfig.NewRepository()
+		endpointRepo := &testapi.FakeEndpointRepo{Config: config}
+
+		callApi([]string{"--skip-ssl-validation", "https://example.com"}, config, endpointRepo)
+
+		Expect(endpointRepo.UpdateEndpointReceived).To(Equal("https://example.com"))
+		Expect(config.IsSSLDisabled()).To(BeTrue())
+	})
+
+	It("TestApiWithCACertFile", func() {
+		config := testconfig.NewRepository()
+		config.SetSSLDisabled(true)
+		endpointRepo := &testapi.FakeEndpointRepo{Config: config}
+
+		callApi([]string{"--ca-cert", "/path/to/ca.pem", "https://example.com"}, config, endpointRepo)
+
+		Expect(config.IsSSLDisabled()).To(BeFalse())
+		Expect(config.CACertFile()).To(Equal("/path/to/ca.pem"))
+	})
+
+	It("TestApiWhenTheEndpointHasAnInvalidSSLCert", func() {
+		config := testconfig.NewRepository()
+		endpointRepo := &testapi.FakeEndpointRepo{Config: config}
+		endpointRepo.UpdateEndpointError = net.NewInvalidSSLCertApiResponse("example.com")
+
+		ui := callApi([]string{"https://example.com"}, config, endpointRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid SSL Cert", "example.com"},
+			{"TIP", "--skip-ssl-validation"},
+		})
+	})
+
 	It("TestApiWithTrailingSlash", func() {
 		config := testconfig.NewRepository()
 		endpointRepo := &testapi.FakeEndpointRepo{Config: config}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"cf/api"
+	"cf/configuration"
+	"cf/manifest"
+	"cf/models"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+	"os"
+	"path/filepath"
+)
+
+type CreateAppManifest struct {
+	ui             terminal.UI
+	config         configuration.Reader
+	appSummaryRepo api.AppSummaryRepository
+	manifestRepo   manifest.ManifestRepository
+	appReq         requirements.ApplicationRequirement
+}
+
+func NewCreateAppManifest(ui terminal.UI, config configuration.Reader, appSummaryRepo api.AppSummaryRepository, manifestRepo manifest.ManifestRepository) (cmd *CreateAppManifest) {
+	cmd = new(CreateAppManifest)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appSummaryRepo = appSummaryRepo
+	cmd.manifestRepo = manifestRepo
+	return
+}
+
+func (cmd *CreateAppManifest) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "create-app-manifest")
+		return
+	}
+
+	cmd.appReq = reqFactory.NewApplicationRequirement(c.Args()[0])
+
+	reqs = []requirements.Requirement{
+		reqFactory.NewLoginRequirement(),
+		reqFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *CreateAppManifest) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+
+	cmd.ui.Say("Creating an app manifest from current settings of app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	summary, apiResponse := cmd.appSummaryRepo.GetSummary(app.Guid)
+	if apiResponse.IsNotSuccessful() {
+		cmd.ui.Failed(apiResponse.Message)
+		return
+	}
+
+	path := manifestOutputPath(c.String("p"))
+
+	m := manifest.NewEmptyManifest()
+	m.Applications = []models.AppParams{cmd.appParams(app, summary)}
+
+	err := cmd.manifestRepo.WriteManifest(m, path)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+	cmd.ui.Say("Manifest file created successfully at %s", terminal.EntityNameColor(path))
+}
+
+// appParams holds the settings of a deployed app that a manifest can set. The
+// summary has the routes and services; the stack comes from the app itself.
+func (cmd *CreateAppManifest) appParams(app models.Application, summary models.AppSummary) (params models.AppParams) {
+	params.Name = &summary.Name
+	params.Memory = &summary.Memory
+	params.DiskQuota = &summary.DiskQuota
+	params.InstanceCount = &summary.InstanceCount
+
+	if summary.Command != "" {
+		params.Command = &summary.Command
+	}
+	if summary.BuildpackUrl != "" {
+		params.BuildpackUrl = &summary.BuildpackUrl
+	}
+	if app.Stack.Name != "" {
+		params.StackName = &app.Stack.Name
+	}
+	if len(summary.EnvironmentVars) > 0 {
+		params.EnvironmentVars = &summary.EnvironmentVars
+	}
+
+	switch len(summary.RouteSummaries) {
+	case 0:
+		noRoute := true
+		params.NoRoute = &noRoute
+	case 1:
+		route := summary.RouteSummaries[0]
+		params.Host = &route.Host
+		params.Domain = &route.Domain.Name
+	default:
+		routes := []string{}
+		for _, route := range summary.RouteSummaries {
+			routes = append(routes, route.URL())
+		}
+		params.Routes = &routes
+	}
+
+	if len(summary.Services) > 0 {
+		services := []string{}
+		instances := []models.ServiceInstanceParams{}
+		for _, service := range summary.Services {
+			services = append(services, service.Name)
+			if !service.IsUserProvided() {
+				instances = append(instances, models.ServiceInstanceParams{
+					Name:     service.Name,
+					Label:    service.ServiceOffering.Label,
+					Provider: service.ServiceOffering.Provider,
+					Plan:     service.ServicePlan.Name,
+				})
+			}
+		}
+		params.Services = &services
+		if len(instances) > 0 {
+			params.ServiceInstances = &instances
+		}
+	}
+	return
+}
+
+func manifestOutputPath(path string) string {
+	if path == "" {
+		return "manifest.yml"
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err == nil && fileInfo.IsDir() {
+		return filepath.Join(path, "manifest.yml")
+	}
+	return path
+}
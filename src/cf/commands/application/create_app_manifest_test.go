@@ -0,0 +1,177 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/manifest"
+	"cf/models"
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testmanifest "testhelpers/manifest"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("create-app-manifest command", func() {
+	var (
+		reqFactory     *testreq.FakeReqFactory
+		appSummaryRepo *testapi.FakeAppSummaryRepo
+		manifestRepo   *testmanifest.FakeManifestRepository
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		app.Stack = models.Stack{Name: "cflinuxfs"}
+		reqFactory = &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true, Application: app}
+
+		summary := models.AppSummary{}
+		summary.Name = "my-app"
+		summary.Guid = "my-app-guid"
+		summary.Memory = 256
+		summary.DiskQuota = 1024
+		summary.InstanceCount = 2
+		summary.Command = "bundle exec rackup"
+		summary.BuildpackUrl = "ruby_buildpack"
+		summary.EnvironmentVars = map[string]string{"RAILS_ENV": "production"}
+		summary.RouteSummaries = []models.RouteSummary{
+			{RouteFields: models.RouteFields{Host: "my-app"}, Domain: models.DomainFields{Name: "example.com"}},
+		}
+		db := models.ServiceInstance{}
+		db.Name = "my-db"
+		summary.Services = []models.ServiceInstance{db}
+		appSummaryRepo = &testapi.FakeAppSummaryRepo{GetSummarySummary: summary}
+
+		manifestRepo = &testmanifest.FakeManifestRepository{}
+	})
+
+	It("requires a login, a targeted space and the app", func() {
+		callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+		Expect(testcmd.CommandDidPassRequirements).To(BeTrue())
+		Expect(reqFactory.ApplicationName).To(Equal("my-app"))
+
+		reqFactory.LoginSuccess = false
+		callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+		Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+	})
+
+	It("fails with usage when not given an app name", func() {
+		ui := callCreateAppManifest([]string{}, reqFactory, appSummaryRepo, manifestRepo)
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	It("writes the settings of the app to manifest.yml", func() {
+		ui := callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		Expect(appSummaryRepo.GetSummaryAppGuid).To(Equal("my-app-guid"))
+		Expect(manifestRepo.WriteManifestArgs.Path).To(Equal("manifest.yml"))
+
+		apps := manifestRepo.WriteManifestArgs.Manifest.Applications
+		Expect(len(apps)).To(Equal(1))
+		app := apps[0]
+		Expect(*app.Name).To(Equal("my-app"))
+		Expect(*app.Memory).To(Equal(uint64(256)))
+		Expect(*app.DiskQuota).To(Equal(uint64(1024)))
+		Expect(*app.InstanceCount).To(Equal(2))
+		Expect(*app.Command).To(Equal("bundle exec rackup"))
+		Expect(*app.BuildpackUrl).To(Equal("ruby_buildpack"))
+		Expect(*app.StackName).To(Equal("cflinuxfs"))
+		Expect(*app.EnvironmentVars).To(Equal(map[string]string{"RAILS_ENV": "production"}))
+		Expect(*app.Host).To(Equal("my-app"))
+		Expect(*app.Domain).To(Equal("example.com"))
+		Expect(*app.Services).To(Equal([]string{"my-db"}))
+		Expect(app.NoRoute).To(BeNil())
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Creating an app manifest", "my-app", "my-org", "my-space", "my-user"},
+			{"OK"},
+			{"Manifest file created successfully at", "manifest.yml"},
+		})
+	})
+
+	It("writes the plan of the service instances, so they can be created again", func() {
+		cache := models.ServiceInstance{}
+		cache.Name = "my-cache"
+		cache.ServicePlan = models.ServicePlanFields{Name: "small", Guid: "small-guid"}
+		cache.ServiceOffering.Label = "redis"
+		appSummaryRepo.GetSummarySummary.Services = append(appSummaryRepo.GetSummarySummary.Services, cache)
+
+		callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		app := manifestRepo.WriteManifestArgs.Manifest.Applications[0]
+		Expect(*app.Services).To(Equal([]string{"my-db", "my-cache"}))
+		Expect(*app.ServiceInstances).To(Equal([]models.ServiceInstanceParams{
+			{Name: "my-cache", Label: "redis", Plan: "small"},
+		}))
+	})
+
+	It("writes the manifest to the path given with -p", func() {
+		callCreateAppManifest([]string{"-p", "my-app-manifest.yml", "my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+		Expect(manifestRepo.WriteManifestArgs.Path).To(Equal("my-app-manifest.yml"))
+	})
+
+	It("leaves out the settings the app does not have", func() {
+		appSummaryRepo.GetSummarySummary.Command = ""
+		appSummaryRepo.GetSummarySummary.BuildpackUrl = ""
+		appSummaryRepo.GetSummarySummary.EnvironmentVars = nil
+		appSummaryRepo.GetSummarySummary.Services = nil
+		appSummaryRepo.GetSummarySummary.RouteSummaries = nil
+
+		callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		app := manifestRepo.WriteManifestArgs.Manifest.Applications[0]
+		Expect(app.Command).To(BeNil())
+		Expect(app.BuildpackUrl).To(BeNil())
+		Expect(app.EnvironmentVars).To(BeNil())
+		Expect(app.Services).To(BeNil())
+		Expect(app.Host).To(BeNil())
+		Expect(*app.NoRoute).To(BeTrue())
+	})
+
+	It("lists the routes by URL when the app has several", func() {
+		appSummaryRepo.GetSummarySummary.RouteSummaries = append(appSummaryRepo.GetSummarySummary.RouteSummaries,
+			models.RouteSummary{RouteFields: models.RouteFields{Host: "www"}, Domain: models.DomainFields{Name: "example.org"}})
+
+		callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		app := manifestRepo.WriteManifestArgs.Manifest.Applications[0]
+		Expect(app.Host).To(BeNil())
+		Expect(app.Domain).To(BeNil())
+		Expect(*app.Routes).To(Equal([]string{"my-app.example.com", "www.example.org"}))
+	})
+
+	It("fails when the app summary cannot be read", func() {
+		appSummaryRepo.GetSummaryErrorCode = "some-error"
+
+		ui := callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		Expect(manifestRepo.WriteManifestArgs.Manifest).To(BeNil())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{{"FAILED"}})
+	})
+
+	It("fails when the manifest cannot be written", func() {
+		manifestRepo.WriteManifestReturns.Error = errors.New("Error writing manifest file manifest.yml")
+
+		ui := callCreateAppManifest([]string{"my-app"}, reqFactory, appSummaryRepo, manifestRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Error writing manifest file"},
+		})
+	})
+})
+
+func callCreateAppManifest(args []string, reqFactory *testreq.FakeReqFactory, appSummaryRepo *testapi.FakeAppSummaryRepo, manifestRepo manifest.ManifestRepository) (ui *testterm.FakeUI) {
+	ui = new(testterm.FakeUI)
+	ctxt := testcmd.NewContext("create-app-manifest", args)
+	config := testconfig.NewRepositoryWithDefaults()
+
+	cmd := NewCreateAppManifest(ui, config, appSummaryRepo, manifestRepo)
+	testcmd.RunCommand(cmd, ctxt, reqFactory)
+	return
+}
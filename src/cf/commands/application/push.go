@@ -1,6 +1,7 @@
 package application
 
 import (
+	"cf"
 	"cf/api"
 	"cf/commands/service"
 	"cf/configuration"
@@ -21,25 +22,27 @@ import (
 )
 
 type Push struct {
-	ui             terminal.UI
-	config         configuration.Reader
-	manifestRepo   manifest.ManifestRepository
-	starter        ApplicationStarter
-	stopper        ApplicationStopper
-	binder         service.ServiceBinder
-	appRepo        api.ApplicationRepository
-	domainRepo     api.DomainRepository
-	routeRepo      api.RouteRepository
-	serviceRepo    api.ServiceRepository
-	stackRepo      api.StackRepository
-	appBitsRepo    api.ApplicationBitsRepository
-	globalServices []models.ServiceInstance
+	ui                      terminal.UI
+	config                  configuration.Reader
+	manifestRepo            manifest.ManifestRepository
+	starter                 ApplicationStarter
+	stopper                 ApplicationStopper
+	binder                  service.ServiceBinder
+	appRepo                 api.ApplicationRepository
+	domainRepo              api.DomainRepository
+	routeRepo               api.RouteRepository
+	serviceRepo             api.ServiceRepository
+	userProvidedServiceRepo api.UserProvidedServiceInstanceRepository
+	stackRepo               api.StackRepository
+	appBitsRepo             api.ApplicationBitsRepository
+	globalServices          []models.ServiceInstance
 }
 
 func NewPush(ui terminal.UI, config configuration.Reader, manifestRepo manifest.ManifestRepository,
 	starter ApplicationStarter, stopper ApplicationStopper, binder service.ServiceBinder,
 	appRepo api.ApplicationRepository, domainRepo api.DomainRepository, routeRepo api.RouteRepository,
-	stackRepo api.StackRepository, serviceRepo api.ServiceRepository, appBitsRepo api.ApplicationBitsRepository) (cmd *Push) {
+	stackRepo api.StackRepository, serviceRepo api.ServiceRepository, userProvidedServiceRepo api.UserProvidedServiceInstanceRepository,
+	appBitsRepo api.ApplicationBitsRepository) (cmd *Push) {
 	cmd = &Push{}
 	cmd.ui = ui
 	cmd.config = config
@@ -51,6 +54,7 @@ func NewPush(ui terminal.UI, config configuration.Reader, manifestRepo manifest.
 	cmd.domainRepo = domainRepo
 	cmd.routeRepo = routeRepo
 	cmd.serviceRepo = serviceRepo
+	cmd.userProvidedServiceRepo = userProvidedServiceRepo
 	cmd.stackRepo = stackRepo
 	cmd.appBitsRepo = appBitsRepo
 	return
@@ -72,7 +76,7 @@ func (cmd *Push) Run(c *cli.Context) {
 
 		app := cmd.createOrUpdateApp(appParams)
 
-		cmd.bindAppToRoute(app, appParams, c)
+		cmd.bindAppToRoutes(app, appParams, c)
 
 		cmd.ui.Say("Uploading %s...", terminal.EntityNameColor(app.Name))
 
@@ -84,17 +88,22 @@ func (cmd *Push) Run(c *cli.Context) {
 		cmd.ui.Ok()
 
 		if appParams.Services != nil {
-			cmd.bindAppToServices(*appParams.Services, app)
+			cmd.bindAppToServices(appParams, app)
 		}
 
 		cmd.restart(app, appParams, c)
 	}
 }
 
-func (cmd *Push) bindAppToServices(services []string, app models.Application) {
-	for _, serviceName := range services {
+func (cmd *Push) bindAppToServices(params models.AppParams, app models.Application) {
+	for _, serviceName := range *params.Services {
 		serviceInstance, response := cmd.serviceRepo.FindInstanceByName(serviceName)
 
+		if instanceParams, found := params.FindServiceInstanceParams(serviceName); found && response.IsNotFound() {
+			cmd.createServiceInstance(instanceParams)
+			serviceInstance, response = cmd.serviceRepo.FindInstanceByName(serviceName)
+		}
+
 		if response.IsNotSuccessful() {
 			cmd.ui.Failed("Could not find service %s to bind to %s", serviceName, app.Name)
 			return
@@ -104,13 +113,66 @@ func (cmd *Push) bindAppToServices(services []string, app models.Application) {
 		bindResponse := cmd.binder.BindApplication(app, serviceInstance)
 		cmd.ui.Ok()
 
-		if bindResponse.IsNotSuccessful() && bindResponse.ErrorCode != service.AppAlreadyBoundErrorCode {
+		if bindResponse.IsNotSuccessful() && bindResponse.ErrorCode != cf.APP_ALREADY_BOUND {
 			cmd.ui.Failed("Could not find to service %s\nError: %s", serviceName, bindResponse.Message)
 			return
 		}
 	}
 }
 
+// createServiceInstance creates a service instance the manifest declares, from
+// a plan of a service offering or as a user provided service.
+func (cmd *Push) createServiceInstance(params models.ServiceInstanceParams) {
+	cmd.ui.Say("Creating service %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(params.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	var apiResponse net.ApiResponse
+	if params.IsUserProvided() {
+		apiResponse = cmd.userProvidedServiceRepo.Create(params.Name, params.SysLogDrainUrl, params.Credentials)
+	} else {
+		var plan models.ServicePlanFields
+		plan, apiResponse = cmd.findServicePlan(params)
+		if apiResponse.IsSuccessful() {
+			_, apiResponse = cmd.serviceRepo.CreateServiceInstance(params.Name, plan.Guid)
+		}
+	}
+
+	if apiResponse.IsNotSuccessful() {
+		cmd.ui.Failed("Could not create service %s\n%s", params.Name, apiResponse.Message)
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+}
+
+func (cmd *Push) findServicePlan(params models.ServiceInstanceParams) (plan models.ServicePlanFields, apiResponse net.ApiResponse) {
+	offerings, apiResponse := cmd.serviceRepo.GetAllServiceOfferings()
+	if apiResponse.IsNotSuccessful() {
+		return
+	}
+
+	for _, offering := range offerings {
+		if offering.Label != params.Label || (params.Provider != "" && offering.Provider != params.Provider) {
+			continue
+		}
+
+		for _, candidate := range offering.Plans {
+			if candidate.Name == params.Plan {
+				plan = candidate
+				return
+			}
+		}
+	}
+
+	apiResponse = net.NewNotFoundApiResponse("Could not find plan %s of service %s", params.Plan, params.Label)
+	return
+}
+
 func (cmd *Push) describeUploadOperation(path string, zipFileBytes, fileCount uint64) {
 	humanReadableBytes := formatters.ByteSize(zipFileBytes)
 	cmd.ui.Say("Uploading from: %s\n%s, %d files", path, humanReadableBytes, fileCount)
@@ -134,7 +196,7 @@ func (cmd *Push) fetchStackGuid(appParams *models.AppParams) {
 	appParams.StackGuid = &stack.Guid
 }
 
-func (cmd *Push) bindAppToRoute(app models.Application, params models.AppParams, c *cli.Context) {
+func (cmd *Push) bindAppToRoutes(app models.Application, params models.AppParams, c *cli.Context) {
 	if c.Bool("no-route") {
 		return
 	}
@@ -144,36 +206,119 @@ func (cmd *Push) bindAppToRoute(app models.Application, params models.AppParams,
 		return
 	}
 
-	routeFlagsPresent := c.String("n") != "" || c.String("d") != "" || c.Bool("no-hostname")
-	if len(app.Routes) > 0 && !routeFlagsPresent {
+	routeFlagsPresent := len(c.StringSlice("n")) > 0 || len(c.StringSlice("d")) > 0 ||
+		len(c.StringSlice("route")) > 0 || c.Bool("no-hostname")
+	routesListed := params.Hosts != nil || params.Domains != nil || params.Routes != nil
+	if len(app.Routes) > 0 && !routeFlagsPresent && !routesListed {
 		return
 	}
 
-	var defaultHostname string
-	if params.Host != nil {
-		defaultHostname = *params.Host
-	} else {
-		defaultHostname = hostNameForString(app.Name)
+	routes := cmd.routesForApp(app, params, c)
+	for _, route := range routes {
+		cmd.bindRoute(app, route)
 	}
 
-	var domainName string
-	if params.Domain != nil {
-		domainName = *params.Domain
-	} else {
-		domainName = c.String("d")
+	if c.Bool("unmap-unlisted-routes") {
+		cmd.unbindUnlistedRoutes(app, routes)
+	}
+}
+
+// routesForApp finds or creates the routes of an app: each host on each
+// domain, followed by the routes given as URLs. Flags replace the manifest
+// settings of the same kind. When only URLs are given, the app gets no route
+// on the default domain.
+func (cmd *Push) routesForApp(app models.Application, params models.AppParams, c *cli.Context) (routes []models.Route) {
+	hostNames := flagOrParamValues(c.StringSlice("n"), params.Host, params.Hosts)
+	domainNames := flagOrParamValues(c.StringSlice("d"), params.Domain, params.Domains)
+	urls := flagOrParamValues(c.StringSlice("route"), nil, params.Routes)
+
+	if len(urls) == 0 || len(hostNames) > 0 || len(domainNames) > 0 || c.Bool("no-hostname") {
+		if c.Bool("no-hostname") {
+			hostNames = []string{""}
+		} else if len(hostNames) == 0 {
+			hostNames = []string{hostNameForString(app.Name)}
+		}
+
+		if len(domainNames) == 0 {
+			domainNames = []string{""}
+		}
+
+		for _, domainName := range domainNames {
+			domain := cmd.domain(c, domainName)
+			for _, hostName := range hostNames {
+				routes = appendRoute(routes, cmd.route(hostName, domain))
+			}
+		}
 	}
 
-	hostName := cmd.hostname(c, defaultHostname)
-	domain := cmd.domain(c, domainName)
-	route := cmd.route(hostName, domain)
+	for _, url := range urls {
+		hostName, domain := cmd.hostAndDomainForUrl(url)
+		routes = appendRoute(routes, cmd.route(hostName, domain))
+	}
+	return
+}
+
+func flagOrParamValues(flagValues []string, param *string, params *[]string) (values []string) {
+	if len(flagValues) > 0 {
+		return flagValues
+	}
+	if param != nil {
+		values = append(values, *param)
+	}
+	if params != nil {
+		values = append(values, *params...)
+	}
+	return
+}
+
+func appendRoute(routes []models.Route, route models.Route) []models.Route {
+	for _, listedRoute := range routes {
+		if listedRoute.Guid == route.Guid {
+			return routes
+		}
+	}
+	return append(routes, route)
+}
+
+// hostAndDomainForUrl splits a route URL into a host and a domain of the org.
+// The longest domain that exists wins, as a domain can be a subdomain of
+// another one. Routes are made of a host and a domain only, so URLs with a
+// path or a port are rejected.
+func (cmd *Push) hostAndDomainForUrl(url string) (hostName string, domain models.DomainFields) {
+	url = strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+	url = strings.TrimSuffix(url, "/")
 
+	if strings.ContainsAny(url, "/:?#") {
+		cmd.ui.Failed("Route %s can not have a path or a port, only a host and a domain", url)
+		return
+	}
+
+	labels := strings.Split(url, ".")
+	for index := range labels {
+		var apiResponse net.ApiResponse
+		domain, apiResponse = cmd.domainRepo.FindByNameInOrg(strings.Join(labels[index:], "."), cmd.config.OrganizationFields().Guid)
+		if apiResponse.IsSuccessful() {
+			hostName = strings.Join(labels[:index], ".")
+			return
+		}
+		if !apiResponse.IsNotFound() {
+			cmd.ui.Failed(apiResponse.Message)
+			return
+		}
+	}
+
+	cmd.ui.Failed("Could not find a domain for route %s", url)
+	return
+}
+
+func (cmd *Push) bindRoute(app models.Application, route models.Route) {
 	for _, boundRoute := range app.Routes {
 		if boundRoute.Guid == route.Guid {
 			return
 		}
 	}
 
-	cmd.ui.Say("Binding %s to %s...", terminal.EntityNameColor(domain.UrlForHost(hostName)), terminal.EntityNameColor(app.Name))
+	cmd.ui.Say("Binding %s to %s...", terminal.EntityNameColor(route.URL()), terminal.EntityNameColor(app.Name))
 
 	apiResponse := cmd.routeRepo.Bind(route.Guid, app.Guid)
 	if apiResponse.IsNotSuccessful() {
@@ -185,6 +330,32 @@ func (cmd *Push) bindAppToRoute(app models.Application, params models.AppParams,
 	cmd.ui.Say("")
 }
 
+func (cmd *Push) unbindUnlistedRoutes(app models.Application, routes []models.Route) {
+	for _, boundRoute := range app.Routes {
+		listed := false
+		for _, route := range routes {
+			if route.Guid == boundRoute.Guid {
+				listed = true
+				break
+			}
+		}
+		if listed {
+			continue
+		}
+
+		cmd.ui.Say("Unbinding %s from %s...", terminal.EntityNameColor(boundRoute.URL()), terminal.EntityNameColor(app.Name))
+
+		apiResponse := cmd.routeRepo.Unbind(boundRoute.Guid, app.Guid)
+		if apiResponse.IsNotSuccessful() {
+			cmd.ui.Failed(apiResponse.Message)
+			return
+		}
+
+		cmd.ui.Ok()
+		cmd.ui.Say("")
+	}
+}
+
 var forbiddenHostCharRegex = regexp.MustCompile("[^a-z0-9-]")
 var whitespaceRegex = regexp.MustCompile(`[\s_]+`)
 
@@ -220,10 +391,16 @@ func (cmd *Push) route(hostName string, domain models.DomainFields) (route model
 		cmd.ui.Say("Creating route %s...", terminal.EntityNameColor(domain.UrlForHost(hostName)))
 
 		route, apiResponse = cmd.routeRepo.Create(hostName, domain.Guid)
+		if apiResponse.ErrorCode == cf.ROUTE_HOST_TAKEN {
+			cmd.ui.Failed("Route %s is taken by another space, choose a different host with -n", domain.UrlForHost(hostName))
+			return
+		}
 		if apiResponse.IsNotSuccessful() {
 			cmd.ui.Failed(apiResponse.Message)
 			return
 		}
+		route.Host = hostName
+		route.Domain = domain
 
 		cmd.ui.Ok()
 		cmd.ui.Say("")
@@ -285,19 +462,6 @@ func (cmd *Push) findDefaultDomain() (domain models.DomainFields, err error) {
 	return
 }
 
-func (cmd *Push) hostname(c *cli.Context, defaultName string) (hostName string) {
-	if c.Bool("no-hostname") {
-		return
-	}
-
-	hostName = c.String("n")
-	if hostName == "" {
-		hostName = defaultName
-	}
-
-	return
-}
-
 func (cmd *Push) createOrUpdateApp(appParams models.AppParams) (app models.Application) {
 	if appParams.Name == nil {
 		cmd.ui.Failed("Error: No name found for app")
@@ -419,7 +583,16 @@ func (cmd *Push) instantiateManifest(c *cli.Context) (m *manifest.Manifest) {
 		}
 	}
 
-	m, manifestPath, errs := cmd.manifestRepo.ReadManifest(path)
+	vars, err := manifestVariables(c)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	m, manifestPath, errs := cmd.manifestRepo.ReadManifest(path, vars)
+	for _, warning := range m.Warnings {
+		cmd.ui.Warn("%s", warning)
+	}
 
 	if !errs.Empty() {
 		if manifestPath == "" && c.String("f") == "" {
@@ -434,6 +607,28 @@ func (cmd *Push) instantiateManifest(c *cli.Context) (m *manifest.Manifest) {
 	return
 }
 
+// manifestVariables gathers the manifest variables from CF_VAR_ env vars,
+// then vars files, then --var flags, later ones taking precedence.
+func manifestVariables(c *cli.Context) (vars manifest.Variables, err error) {
+	vars = manifest.NewVariables()
+	vars.AddFromEnv()
+
+	for _, path := range c.StringSlice("vars-file") {
+		err = vars.AddFromFile(path)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, flag := range c.StringSlice("var") {
+		err = vars.AddFromFlag(flag)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (cmd *Push) createAppSetFromContextAndManifest(c *cli.Context, contextParams models.AppParams, m *manifest.Manifest) (appSet []models.AppParams, err error) {
 	if len(m.Applications) > 1 {
 		if contextParams.Name != nil {
@@ -37,8 +37,9 @@ var _ = Describe("Push Command", func() {
 		stackRepo := deps.stackRepo
 		appBitsRepo := deps.appBitsRepo
 		serviceRepo := deps.serviceRepo
+		userProvidedServiceRepo := deps.userProvidedServiceRepo
 
-		cmd := NewPush(ui, configRepo, manifestRepo, starter, stopper, binder, appRepo, domainRepo, routeRepo, stackRepo, serviceRepo, appBitsRepo)
+		cmd := NewPush(ui, configRepo, manifestRepo, starter, stopper, binder, appRepo, domainRepo, routeRepo, stackRepo, serviceRepo, userProvidedServiceRepo, appBitsRepo)
 		ctxt := testcmd.NewContext("push", []string{})
 
 		reqFactory := &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true}
@@ -293,6 +294,24 @@ var _ = Describe("Push Command", func() {
 		Expect(updatedAppEnvVars["PATH"]).To(Equal("/u/apps/my-app/bin"))
 	})
 
+	It("warns about unknown keys in the manifest and pushes anyway", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+
+		m := singleAppManifest()
+		m.Warnings = manifest.ManifestErrors{
+			errors.New("manifest.yml:5: app manifest-app-name: Unknown key 'instance', did you mean 'instances'?"),
+		}
+		deps.manifestRepo.ReadManifestReturns.Manifest = m
+
+		ui := callPush([]string{}, deps)
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"manifest.yml:5", "Unknown key 'instance'"},
+		})
+		testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{{"FAILED"}})
+		Expect(*deps.appRepo.CreatedAppParams().Name).To(Equal("manifest-app-name"))
+	})
+
 	It("TestPushingAppWithSingleAppManifest", func() {
 		deps := getPushDependencies()
 		domain := models.DomainFields{}
@@ -452,6 +471,121 @@ var _ = Describe("Push Command", func() {
 		})
 	})
 
+	It("creates the service instances declared with a plan before binding them", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+
+		offering := models.ServiceOffering{}
+		offering.Label = "mysql"
+		offering.Plans = []models.ServicePlanFields{
+			{Name: "large", Guid: "large-guid"},
+			{Name: "small", Guid: "small-guid"},
+		}
+		deps.serviceRepo.GetAllServiceOfferingsReturns.ServiceOfferings = []models.ServiceOffering{offering}
+		deps.serviceRepo.FindInstanceByNameResponses = []net.ApiResponse{
+			net.NewNotFoundApiResponse("Service instance my-db not found"),
+			net.NewSuccessfulApiResponse(),
+		}
+		deps.serviceRepo.FindInstanceByNameServiceInstance = maker.NewServiceInstance("my-db")
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{
+				Name:             &name,
+				Services:         &[]string{"my-db"},
+				ServiceInstances: &[]models.ServiceInstanceParams{{Name: "my-db", Label: "mysql", Plan: "small"}},
+			}},
+		}
+
+		ui := callPush([]string{}, deps)
+
+		Expect(deps.serviceRepo.CreateServiceInstanceName).To(Equal("my-db"))
+		Expect(deps.serviceRepo.CreateServiceInstancePlanGuid).To(Equal("small-guid"))
+		Expect(deps.binder.InstancesToBindTo[0].Name).To(Equal("my-db"))
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Creating service", "my-db", "my-org", "my-space", "my-user"},
+			{"OK"},
+			{"Binding service", "my-db", "my-app"},
+			{"OK"},
+		})
+	})
+
+	It("creates the user provided service instances declared with credentials", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.serviceRepo.FindInstanceByNameResponses = []net.ApiResponse{
+			net.NewNotFoundApiResponse("Service instance my-creds not found"),
+			net.NewSuccessfulApiResponse(),
+		}
+		deps.serviceRepo.FindInstanceByNameServiceInstance = maker.NewServiceInstance("my-creds")
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{
+				Name:     &name,
+				Services: &[]string{"my-creds"},
+				ServiceInstances: &[]models.ServiceInstanceParams{{
+					Name:           "my-creds",
+					Credentials:    map[string]string{"user": "admin"},
+					SysLogDrainUrl: "syslog://logs.example.com",
+				}},
+			}},
+		}
+
+		callPush([]string{}, deps)
+
+		Expect(deps.userProvidedServiceRepo.CreateName).To(Equal("my-creds"))
+		Expect(deps.userProvidedServiceRepo.CreateParams).To(Equal(map[string]string{"user": "admin"}))
+		Expect(deps.userProvidedServiceRepo.CreateDrainUrl).To(Equal("syslog://logs.example.com"))
+		Expect(deps.serviceRepo.CreateServiceInstanceName).To(BeEmpty())
+		Expect(deps.binder.InstancesToBindTo[0].Name).To(Equal("my-creds"))
+	})
+
+	It("does not create the declared service instances that exist", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.serviceRepo.FindInstanceByNameServiceInstance = maker.NewServiceInstance("my-db")
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{
+				Name:             &name,
+				Services:         &[]string{"my-db"},
+				ServiceInstances: &[]models.ServiceInstanceParams{{Name: "my-db", Label: "mysql", Plan: "small"}},
+			}},
+		}
+
+		callPush([]string{}, deps)
+
+		Expect(deps.serviceRepo.CreateServiceInstanceName).To(BeEmpty())
+		Expect(deps.binder.InstancesToBindTo[0].Name).To(Equal("my-db"))
+	})
+
+	It("fails when the plan of a declared service instance does not exist", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.serviceRepo.FindInstanceByNameNotFound = true
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{
+				Name:             &name,
+				Services:         &[]string{"my-db"},
+				ServiceInstances: &[]models.ServiceInstanceParams{{Name: "my-db", Label: "mysql", Plan: "huge"}},
+			}},
+		}
+
+		ui := callPush([]string{}, deps)
+
+		Expect(deps.serviceRepo.CreateServiceInstanceName).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Could not create service my-db"},
+			{"Could not find plan huge of service mysql"},
+		})
+	})
+
 	It("TestPushingAppWithPath", func() {
 		deps := getPushDependencies()
 		deps.appRepo.ReadNotFound = true
@@ -558,6 +692,36 @@ var _ = Describe("Push Command", func() {
 		Expect(deps.manifestRepo.ReadManifestArgs.Path).To(Equal(cwd))
 	})
 
+	It("passes manifest variables from flags, vars files and the environment", func() {
+		os.Setenv("CF_VAR_instances", "2")
+		defer os.Unsetenv("CF_VAR_instances")
+
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.manifestRepo.ReadManifestReturns.Manifest = singleAppManifest()
+		deps.manifestRepo.ReadManifestReturns.Path = "manifest.yml"
+
+		callPush([]string{"--var", "host=my-host", "--var", "space=production", "--vars-file", "../../../fixtures/manifests/vars.yml"}, deps)
+
+		Expect(deps.manifestRepo.ReadManifestArgs.Vars).To(Equal(manifest.Variables{
+			"app_name":  "my-app",
+			"instances": 3,
+			"host":      "my-host",
+			"space":     "production",
+			"port":      8080,
+		}))
+	})
+
+	It("fails when a manifest variable flag is not name=value", func() {
+		deps := getPushDependencies()
+
+		ui := callPush([]string{"--var", "host"}, deps)
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid variable 'host'"},
+		})
+	})
+
 	It("TestPushingWithNoManifestFlag", func() {
 		deps := getPushDependencies()
 		deps.appRepo.ReadNotFound = true
@@ -963,6 +1127,203 @@ var _ = Describe("Push Command", func() {
 		Expect(deps.routeRepo.BoundRouteGuid).To(Equal("existing-app-route-guid"))
 	})
 
+	It("binds every host on every domain listed in the manifest", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com": models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+		}
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{
+				Name:    &name,
+				Hosts:   &[]string{"my-app", "www"},
+				Domains: &[]string{"example.com"},
+			}},
+		}
+
+		ui := callPush([]string{}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Creating route", "my-app.example.com"},
+			{"Creating route", "www.example.com"},
+			{"Binding", "my-app.example.com", "my-app"},
+			{"Binding", "www.example.com", "my-app"},
+		})
+		Expect(deps.routeRepo.CreatedHosts).To(Equal([]string{"my-app", "www"}))
+		Expect(deps.routeRepo.BoundRouteGuids).To(Equal([]string{"my-app-route-guid", "www-route-guid"}))
+	})
+
+	It("binds routes given as URLs, finding the domain of each", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com":     models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+			"api.example.com": models.DomainFields{Name: "api.example.com", Guid: "api-example-com-guid"},
+		}
+
+		ui := callPush([]string{"--route", "www.example.com", "--route", "v1.api.example.com", "my-app"}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Binding", "www.example.com", "my-app"},
+			{"Binding", "v1.api.example.com", "my-app"},
+		})
+		testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+			{"my-app.foo.cf-app.com"},
+		})
+		Expect(deps.routeRepo.CreatedHosts).To(Equal([]string{"www", "v1"}))
+		Expect(deps.routeRepo.CreatedDomainGuid).To(Equal("api-example-com-guid"))
+	})
+
+	It("fails when the route is taken by another space", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+		deps.routeRepo.CreateErr = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com": models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+		}
+
+		ui := callPush([]string{"--route", "www.example.com", "my-app"}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Route www.example.com is taken by another space"},
+		})
+	})
+
+	It("fails when no domain of the org matches a route", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{}
+
+		ui := callPush([]string{"--route", "www.unknown.com", "my-app"}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Could not find a domain for route www.unknown.com"},
+		})
+	})
+
+	It("fails when a route in the manifest has a path", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com": models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+		}
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{Name: &name, Routes: &[]string{"https://app.example.com/api"}}},
+		}
+
+		ui := callPush([]string{}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Route app.example.com/api can not have a path or a port"},
+		})
+		Expect(deps.routeRepo.CreatedHosts).To(BeEmpty())
+		Expect(deps.routeRepo.BoundRouteGuids).To(BeEmpty())
+	})
+
+	It("fails when a route has a port", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com": models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+		}
+
+		ui := callPush([]string{"--route", "app.example.com:8080", "my-app"}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Route app.example.com:8080 can not have a path or a port"},
+		})
+	})
+
+	It("accepts a route URL with a scheme and a trailing slash in the manifest", func() {
+		deps := getPushDependencies()
+		deps.appRepo.ReadNotFound = true
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+		deps.domainRepo.FindByNameInOrgDomains = map[string]models.DomainFields{
+			"example.com": models.DomainFields{Name: "example.com", Guid: "example-com-guid"},
+		}
+
+		name := "my-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{Name: &name, Routes: &[]string{"https://app.example.com/"}}},
+		}
+
+		ui := callPush([]string{}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Binding", "app.example.com", "my-app"},
+		})
+		Expect(deps.routeRepo.CreatedHosts).To(Equal([]string{"app"}))
+	})
+
+	It("binds the routes listed in the manifest to an app that already has routes", func() {
+		deps := getPushDependencies()
+
+		existingRoute := models.RouteSummary{}
+		existingRoute.Guid = "old-route-guid"
+		existingRoute.Host = "old"
+		existingRoute.Domain = models.DomainFields{Name: "foo.cf-app.com"}
+
+		existingApp := models.Application{}
+		existingApp.Name = "existing-app"
+		existingApp.Guid = "existing-app-guid"
+		existingApp.Routes = []models.RouteSummary{existingRoute}
+
+		deps.appRepo.ReadApp = existingApp
+		deps.appRepo.UpdateAppResult = existingApp
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+
+		name := "existing-app"
+		deps.manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+			Applications: []models.AppParams{{Name: &name, Hosts: &[]string{"new"}}},
+		}
+
+		callPush([]string{}, deps)
+
+		Expect(deps.routeRepo.BoundRouteGuids).To(Equal([]string{"new-route-guid"}))
+		Expect(deps.routeRepo.UnboundRouteGuids).To(BeEmpty())
+	})
+
+	It("unbinds the routes that are no longer listed with --unmap-unlisted-routes", func() {
+		deps := getPushDependencies()
+
+		existingRoute := models.RouteSummary{}
+		existingRoute.Guid = "old-route-guid"
+		existingRoute.Host = "old"
+		existingRoute.Domain = models.DomainFields{Name: "foo.cf-app.com"}
+
+		existingApp := models.Application{}
+		existingApp.Name = "existing-app"
+		existingApp.Guid = "existing-app-guid"
+		existingApp.Routes = []models.RouteSummary{existingRoute}
+
+		deps.appRepo.ReadApp = existingApp
+		deps.appRepo.UpdateAppResult = existingApp
+		deps.routeRepo.FindByHostAndDomainNotFound = true
+
+		ui := callPush([]string{"-n", "new", "--unmap-unlisted-routes", "existing-app"}, deps)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Binding", "new.foo.cf-app.com", "existing-app"},
+			{"Unbinding", "old.foo.cf-app.com", "existing-app"},
+			{"OK"},
+		})
+		Expect(deps.routeRepo.BoundRouteGuids).To(Equal([]string{"new-route-guid"}))
+		Expect(deps.routeRepo.UnboundRouteGuids).To(Equal([]string{"old-route-guid"}))
+		Expect(deps.routeRepo.UnboundAppGuid).To(Equal("existing-app-guid"))
+	})
+
 	It("TestPushingAppWithInvalidPath", func() {
 		deps := getPushDependencies()
 		deps.appBitsRepo.UploadAppErr = true
@@ -1058,16 +1419,17 @@ func manifestWithServicesAndEnv() *manifest.Manifest {
 }
 
 type pushDependencies struct {
-	manifestRepo *testmanifest.FakeManifestRepository
-	starter      *testcmd.FakeAppStarter
-	stopper      *testcmd.FakeAppStopper
-	binder       *testcmd.FakeAppBinder
-	appRepo      *testapi.FakeApplicationRepository
-	domainRepo   *testapi.FakeDomainRepository
-	routeRepo    *testapi.FakeRouteRepository
-	stackRepo    *testapi.FakeStackRepository
-	appBitsRepo  *testapi.FakeApplicationBitsRepository
-	serviceRepo  *testapi.FakeServiceRepo
+	manifestRepo            *testmanifest.FakeManifestRepository
+	starter                 *testcmd.FakeAppStarter
+	stopper                 *testcmd.FakeAppStopper
+	binder                  *testcmd.FakeAppBinder
+	appRepo                 *testapi.FakeApplicationRepository
+	domainRepo              *testapi.FakeDomainRepository
+	routeRepo               *testapi.FakeRouteRepository
+	stackRepo               *testapi.FakeStackRepository
+	appBitsRepo             *testapi.FakeApplicationBitsRepository
+	serviceRepo             *testapi.FakeServiceRepo
+	userProvidedServiceRepo *testapi.FakeUserProvidedServiceInstanceRepo
 }
 
 func getPushDependencies() (deps pushDependencies) {
@@ -1085,6 +1447,7 @@ func getPushDependencies() (deps pushDependencies) {
 	deps.stackRepo = &testapi.FakeStackRepository{}
 	deps.appBitsRepo = &testapi.FakeApplicationBitsRepository{}
 	deps.serviceRepo = &testapi.FakeServiceRepo{}
+	deps.userProvidedServiceRepo = &testapi.FakeUserProvidedServiceInstanceRepo{}
 
 	return
 }
@@ -1097,7 +1460,7 @@ func callPush(args []string, deps pushDependencies) (ui *testterm.FakeUI) {
 
 	cmd := NewPush(ui, configRepo, deps.manifestRepo, deps.starter,
 		deps.stopper, deps.binder, deps.appRepo, deps.domainRepo,
-		deps.routeRepo, deps.stackRepo, deps.serviceRepo, deps.appBitsRepo)
+		deps.routeRepo, deps.stackRepo, deps.serviceRepo, deps.userProvidedServiceRepo, deps.appBitsRepo)
 
 	reqFactory := &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true}
 	testcmd.RunCommand(cmd, ctxt, reqFactory)
@@ -0,0 +1,70 @@
+package application
+
+import (
+	"cf/manifest"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+	"os"
+)
+
+// ValidateManifest reads a manifest the way push does, without a target, so
+// manifests can be checked before they are used. It is strict about unknown
+// keys, which push only warns about.
+type ValidateManifest struct {
+	ui           terminal.UI
+	manifestRepo manifest.ManifestRepository
+}
+
+func NewValidateManifest(ui terminal.UI, manifestRepo manifest.ManifestRepository) (cmd *ValidateManifest) {
+	cmd = new(ValidateManifest)
+	cmd.ui = ui
+	cmd.manifestRepo = manifestRepo
+	return
+}
+
+func (cmd *ValidateManifest) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 0 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "validate-manifest")
+		return
+	}
+	return
+}
+
+func (cmd *ValidateManifest) Run(c *cli.Context) {
+	path := c.String("f")
+	if path == "" {
+		var err error
+		path, err = os.Getwd()
+		if err != nil {
+			cmd.ui.Failed("Could not determine the current working directory!", err)
+			return
+		}
+	}
+
+	vars, err := manifestVariables(c)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Say("Validating manifest %s...", terminal.EntityNameColor(path))
+
+	m, manifestPath, errs := cmd.manifestRepo.ReadManifest(path, vars)
+	errs = append(errs, m.Warnings...)
+	if !errs.Empty() {
+		cmd.ui.Failed("Invalid manifest:\n%s", errs)
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+	cmd.ui.Say("Manifest file %s is valid", terminal.EntityNameColor(manifestPath))
+	for _, app := range m.Applications {
+		if app.Name != nil {
+			cmd.ui.Say("  app %s", terminal.EntityNameColor(*app.Name))
+		}
+	}
+}
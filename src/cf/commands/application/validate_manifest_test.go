@@ -0,0 +1,116 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/manifest"
+	"cf/models"
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testmanifest "testhelpers/manifest"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("validate-manifest command", func() {
+	var manifestRepo *testmanifest.FakeManifestRepository
+
+	BeforeEach(func() {
+		manifestRepo = &testmanifest.FakeManifestRepository{}
+	})
+
+	It("does not require a login or a target", func() {
+		callValidateManifest([]string{}, manifestRepo)
+		Expect(testcmd.CommandDidPassRequirements).To(BeTrue())
+	})
+
+	It("fails with usage when given arguments", func() {
+		ui := callValidateManifest([]string{"my-app"}, manifestRepo)
+		Expect(ui.FailedWithUsage).To(BeTrue())
+		Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+	})
+
+	It("reads the manifest from the current directory by default", func() {
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+
+		callValidateManifest([]string{}, manifestRepo)
+		Expect(manifestRepo.ReadManifestArgs.Path).To(Equal(cwd))
+	})
+
+	It("reads the manifest given with -f and the manifest variables", func() {
+		callValidateManifest([]string{"-f", "../some/manifest.yml", "--var", "host=my-host"}, manifestRepo)
+
+		Expect(manifestRepo.ReadManifestArgs.Path).To(Equal("../some/manifest.yml"))
+		Expect(manifestRepo.ReadManifestArgs.Vars["host"]).To(Equal("my-host"))
+	})
+
+	It("reports a valid manifest and its apps", func() {
+		appName := "my-app"
+		manifestRepo.ReadManifestReturns.Manifest = manifest.NewEmptyManifest()
+		manifestRepo.ReadManifestReturns.Manifest.Applications = []models.AppParams{{Name: &appName}}
+		manifestRepo.ReadManifestReturns.Path = "/some/manifest.yml"
+
+		ui := callValidateManifest([]string{"-f", "/some"}, manifestRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Validating manifest", "/some"},
+			{"OK"},
+			{"/some/manifest.yml", "is valid"},
+			{"my-app"},
+		})
+	})
+
+	It("fails with every error in the manifest", func() {
+		manifestRepo.ReadManifestReturns.Errors = manifest.ManifestErrors{
+			errors.New("manifest.yml:6: app my-app: Invalid value for 'memory'"),
+			errors.New("manifest.yml:7: app my-app: Expected no-route to be a boolean."),
+		}
+
+		ui := callValidateManifest([]string{"-f", "manifest.yml"}, manifestRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid manifest"},
+			{"manifest.yml:6", "memory"},
+			{"manifest.yml:7", "no-route"},
+		})
+	})
+
+	It("fails on unknown keys, which push only warns about", func() {
+		m := manifest.NewEmptyManifest()
+		m.Warnings = manifest.ManifestErrors{
+			errors.New("manifest.yml:5: app my-app: Unknown key 'instance', did you mean 'instances'?"),
+		}
+		manifestRepo.ReadManifestReturns.Manifest = m
+
+		ui := callValidateManifest([]string{"-f", "manifest.yml"}, manifestRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid manifest"},
+			{"manifest.yml:5", "Unknown key 'instance'"},
+		})
+	})
+
+	It("fails when a variable is malformed", func() {
+		ui := callValidateManifest([]string{"--var", "no-value"}, manifestRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid variable 'no-value'"},
+		})
+	})
+})
+
+func callValidateManifest(args []string, manifestRepo manifest.ManifestRepository) (ui *testterm.FakeUI) {
+	ui = new(testterm.FakeUI)
+	ctxt := testcmd.NewContext("validate-manifest", args)
+
+	cmd := NewValidateManifest(ui, manifestRepo)
+	testcmd.RunCommand(cmd, ctxt, &testreq.FakeReqFactory{})
+	return
+}
@@ -4,10 +4,12 @@ import (
 	"cf"
 	"cf/api"
 	"cf/configuration"
+	"cf/net"
 	"cf/requirements"
 	"cf/terminal"
 	"errors"
 	"github.com/codegangsta/cli"
+	"os"
 )
 
 type Authenticate struct {
@@ -24,7 +26,12 @@ func NewAuthenticate(ui terminal.UI, config configuration.Reader, authenticator
 }
 
 func (cmd Authenticate) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
-	if len(c.Args()) != 2 {
+	argsOk := len(c.Args()) == 2
+	if c.Bool("client-credentials") && len(c.Args()) == 0 {
+		argsOk = os.Getenv(configuration.CF_CLIENT_ID) != "" && os.Getenv(configuration.CF_CLIENT_SECRET) != ""
+	}
+
+	if !argsOk {
 		err = errors.New("Incorrect Usage")
 		cmd.ui.FailWithUsage(c, "auth")
 		return
@@ -37,11 +44,19 @@ func (cmd Authenticate) GetRequirements(reqFactory requirements.Factory, c *cli.
 func (cmd Authenticate) Run(c *cli.Context) {
 	cmd.ui.Say("API endpoint: %s", terminal.EntityNameColor(cmd.config.ApiEndpoint()))
 
-	username := c.Args()[0]
-	password := c.Args()[1]
-
 	cmd.ui.Say("Authenticating...")
-	apiResponse := cmd.authenticator.Authenticate(username, password)
+
+	var apiResponse net.ApiResponse
+	if c.Bool("client-credentials") {
+		clientId, clientSecret := os.Getenv(configuration.CF_CLIENT_ID), os.Getenv(configuration.CF_CLIENT_SECRET)
+		if len(c.Args()) == 2 {
+			clientId, clientSecret = c.Args()[0], c.Args()[1]
+		}
+		apiResponse = cmd.authenticator.AuthenticateClient(clientId, clientSecret)
+	} else {
+		apiResponse = cmd.authenticator.Authenticate(c.Args()[0], c.Args()[1])
+	}
+
 	if apiResponse.IsNotSuccessful() {
 		cmd.ui.Failed(apiResponse.Message)
 		return
@@ -5,6 +5,7 @@ import (
 	"cf/configuration"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"os"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
@@ -71,6 +72,63 @@ var _ = Describe("auth command", func() {
 			Expect(repo.Password).To(Equal("password"))
 		})
 
+		Describe("with --client-credentials", func() {
+			AfterEach(func() {
+				os.Unsetenv(configuration.CF_CLIENT_ID)
+				os.Unsetenv(configuration.CF_CLIENT_SECRET)
+			})
+
+			It("authenticates as a client", func() {
+				context := testcmd.NewContext("auth", []string{"--client-credentials", "my-client", "my-secret"})
+				testcmd.RunCommand(cmd, context, reqFactory)
+
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"Authenticating..."},
+					{"OK"},
+				})
+				Expect(repo.ClientId).To(Equal("my-client"))
+				Expect(repo.ClientSecret).To(Equal("my-secret"))
+				Expect(repo.Email).To(BeEmpty())
+				Expect(config.ClientId()).To(Equal("my-client"))
+			})
+
+			It("reads the client credentials from the environment when not given", func() {
+				os.Setenv(configuration.CF_CLIENT_ID, "env-client")
+				os.Setenv(configuration.CF_CLIENT_SECRET, "env-secret")
+
+				context := testcmd.NewContext("auth", []string{"--client-credentials"})
+				testcmd.RunCommand(cmd, context, reqFactory)
+
+				Expect(ui.FailedWithUsage).To(BeFalse())
+				Expect(repo.ClientId).To(Equal("env-client"))
+				Expect(repo.ClientSecret).To(Equal("env-secret"))
+			})
+
+			It("saves the token obtained with the client credentials from the environment", func() {
+				os.Setenv(configuration.CF_CLIENT_ID, "env-client")
+				os.Setenv(configuration.CF_CLIENT_SECRET, "env-secret")
+
+				persistor := testconfig.NewFakePersistor()
+				config = configuration.NewRepositoryFromPersistor(persistor, func(err error) { panic(err) })
+				config.SetApiEndpoint("foo.example.org/authenticate")
+				repo.Config = config
+				cmd = NewAuthenticate(ui, config, repo)
+
+				context := testcmd.NewContext("auth", []string{"--client-credentials"})
+				testcmd.RunCommand(cmd, context, reqFactory)
+
+				Expect(persistor.SaveArgs.Data.AccessToken).To(Equal("my-access-token"))
+				Expect(persistor.SaveArgs.Data.ClientId).To(Equal("env-client"))
+			})
+
+			It("fails with usage when the client credentials are missing", func() {
+				context := testcmd.NewContext("auth", []string{"--client-credentials"})
+				testcmd.RunCommand(cmd, context, reqFactory)
+
+				Expect(ui.FailedWithUsage).To(BeTrue())
+			})
+		})
+
 		It("TestUnsuccessfullyAuthenticatingWithoutInteractivity", func() {
 			repo.AuthError = true
 			context := testcmd.NewContext("auth", []string{"username", "password"})
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"cf/configuration"
+	"cf/net"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+)
+
+type Config struct {
+	ui     terminal.UI
+	config configuration.ReadWriter
+}
+
+func NewConfig(ui terminal.UI, config configuration.ReadWriter) (cmd Config) {
+	cmd.ui = ui
+	cmd.config = config
+	return
+}
+
+func (cmd Config) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if !c.IsSet("proxy") {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "config")
+	}
+	return
+}
+
+func (cmd Config) Run(c *cli.Context) {
+	proxy := c.String("proxy")
+	if proxy == "" {
+		cmd.ui.Say("Clearing proxy...")
+		cmd.config.SetProxy("")
+		cmd.ui.Ok()
+		return
+	}
+
+	_, err := net.ParseProxyURL(proxy)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Say("Setting proxy to %s...", terminal.EntityNameColor(proxy))
+	cmd.config.SetProxy(proxy)
+	cmd.ui.Ok()
+}
@@ -0,0 +1,69 @@
+package commands_test
+
+import (
+	. "cf/commands"
+	"cf/configuration"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("config command", func() {
+	var (
+		ui     *testterm.FakeUI
+		config configuration.ReadWriter
+	)
+
+	runCommand := func(args ...string) {
+		cmd := NewConfig(ui, config)
+		testcmd.RunCommand(cmd, testcmd.NewContext("config", args), &testreq.FakeReqFactory{})
+	}
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+		config = testconfig.NewRepository()
+	})
+
+	It("fails with usage when no setting is given", func() {
+		runCommand()
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	It("persists the proxy", func() {
+		runCommand("--proxy", "socks5://proxy.example.com:1080")
+
+		Expect(config.Proxy()).To(Equal("socks5://proxy.example.com:1080"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Setting proxy to", "socks5://proxy.example.com:1080"},
+			{"OK"},
+		})
+	})
+
+	It("clears the proxy when given an empty value", func() {
+		config.SetProxy("http://proxy.example.com:8080")
+
+		runCommand("--proxy", "")
+
+		Expect(config.Proxy()).To(Equal(""))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Clearing proxy"},
+			{"OK"},
+		})
+	})
+
+	It("fails when the proxy URL is not supported", func() {
+		config.SetProxy("http://proxy.example.com:8080")
+
+		runCommand("--proxy", "ftp://proxy.example.com")
+
+		Expect(config.Proxy()).To(Equal("http://proxy.example.com:8080"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Unsupported proxy scheme ftp"},
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"cf"
 	"cf/api"
 	"cf/configuration"
 	"cf/requirements"
@@ -50,6 +51,15 @@ func (cmd *CreateDomain) Run(c *cli.Context) {
 	)
 
 	_, apiResponse := cmd.domainRepo.Create(domainName, owningOrg.Guid)
+	if apiResponse.ErrorCode == cf.DOMAIN_NAME_TAKEN {
+		_, findApiResponse := cmd.domainRepo.FindByNameInOrg(domainName, owningOrg.Guid)
+		if findApiResponse.IsSuccessful() {
+			cmd.ui.Ok()
+			cmd.ui.Warn("Domain %s already exists", domainName)
+			return
+		}
+	}
+
 	if apiResponse.IsNotSuccessful() {
 		cmd.ui.Failed(apiResponse.Message)
 		return
@@ -1,11 +1,14 @@
 package domain_test
 
 import (
+	"cf"
 	"cf/commands/domain"
 	"cf/configuration"
 	"cf/models"
+	"cf/net"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"net/http"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
@@ -57,6 +60,41 @@ var _ = Describe("Testing with ginkgo", func() {
 			{"OK"},
 		})
 	})
+
+	It("warns when the org already has the domain", func() {
+		org := models.Organization{}
+		org.Name = "myOrg"
+		org.Guid = "myOrg-guid"
+		reqFactory := &testreq.FakeReqFactory{LoginSuccess: true, Organization: org}
+		domainRepo := &testapi.FakeDomainRepository{
+			CreateApiResponse:     net.NewApiResponse("The domain name is taken: example.com", cf.DOMAIN_NAME_TAKEN, http.StatusBadRequest),
+			FindByNameInOrgDomain: models.DomainFields{Name: "example.com", Guid: "example-domain-guid"},
+		}
+		ui := callCreateDomain([]string{"myOrg", "example.com"}, reqFactory, domainRepo)
+
+		Expect(domainRepo.FindByNameInOrgGuid).To(Equal("myOrg-guid"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"OK"},
+			{"example.com", "already exists"},
+		})
+	})
+
+	It("fails when the domain is taken by another org", func() {
+		org := models.Organization{}
+		org.Name = "myOrg"
+		org.Guid = "myOrg-guid"
+		reqFactory := &testreq.FakeReqFactory{LoginSuccess: true, Organization: org}
+		domainRepo := &testapi.FakeDomainRepository{
+			CreateApiResponse:          net.NewApiResponse("The domain name is taken: example.com", cf.DOMAIN_NAME_TAKEN, http.StatusBadRequest),
+			FindByNameInOrgApiResponse: net.NewNotFoundApiResponse("Domain example.com not found"),
+		}
+		ui := callCreateDomain([]string{"myOrg", "example.com"}, reqFactory, domainRepo)
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"The domain name is taken"},
+		})
+	})
 })
 
 func callCreateDomain(args []string, reqFactory *testreq.FakeReqFactory, domainRepo *testapi.FakeDomainRepository) (fakeUI *testterm.FakeUI) {
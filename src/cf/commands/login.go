@@ -90,6 +90,12 @@ func (cmd Login) setApi(c *cli.Context) (apiResponse net.ApiResponse) {
 	api := c.String("a")
 	if api == "" {
 		api = cmd.config.ApiEndpoint()
+	} else {
+		cmd.config.SetSSLDisabled(false)
+	}
+
+	if c.Bool("skip-ssl-validation") {
+		cmd.config.SetSSLDisabled(true)
 	}
 
 	if api == "" {
@@ -108,6 +114,10 @@ func (cmd Login) setApi(c *cli.Context) (apiResponse net.ApiResponse) {
 }
 
 func (cmd Login) authenticate(c *cli.Context) (apiResponse net.ApiResponse) {
+	if c.Bool("sso") {
+		return cmd.authenticateWithPasscode()
+	}
+
 	username := c.String("u")
 	if username == "" {
 		username = cmd.ui.Ask("Username%s", terminal.PromptColor(">"))
@@ -134,6 +144,37 @@ func (cmd Login) authenticate(c *cli.Context) (apiResponse net.ApiResponse) {
 	return
 }
 
+func (cmd Login) authenticateWithPasscode() (apiResponse net.ApiResponse) {
+	prompts, apiResponse := cmd.authenticator.GetLoginPrompts()
+	if apiResponse.IsNotSuccessful() {
+		cmd.ui.Say(apiResponse.Message)
+		return
+	}
+
+	prompt, found := prompts["passcode"]
+	if !found {
+		apiResponse = net.NewApiResponseWithMessage("The login server does not support SSO passcodes")
+		cmd.ui.Say(apiResponse.Message)
+		return
+	}
+
+	for i := 0; i < maxLoginTries; i++ {
+		passcode := cmd.ui.AskForPassword("%s%s", prompt.DisplayName, terminal.PromptColor(">"))
+
+		cmd.ui.Say("Authenticating...")
+
+		apiResponse = cmd.authenticator.AuthenticateWithPasscode(passcode)
+		if apiResponse.IsSuccessful() {
+			cmd.ui.Ok()
+			cmd.ui.Say("")
+			break
+		}
+
+		cmd.ui.Say(apiResponse.Message)
+	}
+	return
+}
+
 func (cmd Login) setOrganization(c *cli.Context, userChanged bool) (err error) {
 	orgName := c.String("o")
 
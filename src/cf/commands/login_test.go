@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"cf/api"
 	. "cf/commands"
 	"cf/configuration"
 	"cf/models"
@@ -175,6 +176,67 @@ var _ = Describe("Testing with ginkgo", func() {
 		Expect(c.ui.ShowConfigurationCalled).To(BeTrue())
 	})
 
+	Describe("with --sso", func() {
+		It("asks for a one-time passcode with the login server's prompt", func() {
+			c := setUpLoginTestContext()
+			c.authRepo.LoginPrompts = map[string]api.AuthPrompt{
+				"passcode": api.AuthPrompt{Type: "password", DisplayName: "One Time Code (Get one at https://login.example.com/passcode)"},
+			}
+			c.Flags = []string{"--sso", "-a", "api.example.com", "-o", "my-org", "-s", "my-space"}
+			c.ui.Inputs = []string{"my-passcode"}
+
+			callLogin(c)
+
+			testassert.SliceContains(c.ui.PasswordPrompts, testassert.Lines{
+				{"One Time Code (Get one at https://login.example.com/passcode)"},
+			})
+			Expect(c.authRepo.Passcode).To(Equal("my-passcode"))
+			Expect(c.authRepo.Email).To(BeEmpty())
+
+			Expect(c.Config.AccessToken()).To(Equal("my_access_token"))
+			Expect(c.Config.OrganizationFields().Guid).To(Equal("my-org-guid"))
+			Expect(c.Config.SpaceFields().Guid).To(Equal("my-space-guid"))
+			Expect(c.ui.ShowConfigurationCalled).To(BeTrue())
+		})
+
+		It("fails when the login server has no passcode prompt", func() {
+			c := setUpLoginTestContext()
+			c.authRepo.LoginPrompts = map[string]api.AuthPrompt{}
+			c.Flags = []string{"--sso", "-a", "api.example.com"}
+
+			callLogin(c)
+
+			testassert.SliceContains(c.ui.Outputs, testassert.Lines{
+				{"does not support SSO passcodes"},
+				{"FAILED"},
+				{"Unable to authenticate."},
+			})
+			Expect(c.Config.AccessToken()).To(BeEmpty())
+		})
+	})
+
+	It("TestLoggingInWithSkipSSLValidation", func() {
+		c := setUpLoginTestContext()
+
+		c.Flags = []string{"-a", "https://api.example.com", "--skip-ssl-validation", "-u", "user@example.com", "-p", "password", "-o", "my-org", "-s", "my-space"}
+
+		callLogin(c)
+
+		Expect(c.Config.IsSSLDisabled()).To(BeTrue())
+		Expect(c.endpointRepo.UpdateEndpointReceived).To(Equal("https://api.example.com"))
+	})
+
+	It("TestLoggingInToANewEndpointReenablesSSLValidation", func() {
+		c := setUpLoginTestContext()
+		c.Config.SetSSLDisabled(true)
+
+		c.Flags = []string{"-a", "https://api.example.com", "-u", "user@example.com", "-p", "password", "-o", "my-org", "-s", "my-space"}
+
+		callLogin(c)
+
+		Expect(c.Config.IsSSLDisabled()).To(BeFalse())
+	})
+
 	It("TestSuccessfullyLoggingInWithEndpointSetInConfig", func() {
 		c := setUpLoginTestContext()
 
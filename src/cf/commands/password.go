@@ -3,6 +3,7 @@ package commands
 import (
 	"cf/api"
 	"cf/configuration"
+	"cf/net"
 	"cf/requirements"
 	"cf/terminal"
 	"github.com/codegangsta/cli"
@@ -29,6 +30,11 @@ func (cmd Password) GetRequirements(reqFactory requirements.Factory, c *cli.Cont
 }
 
 func (cmd Password) Run(c *cli.Context) {
+	if cmd.config.ClientId() != "" {
+		cmd.ui.Failed("Cannot change the password when authenticated with client credentials")
+		return
+	}
+
 	oldPassword := cmd.ui.AskForPassword("Current Password%s", terminal.PromptColor(">"))
 	newPassword := cmd.ui.AskForPassword("New Password%s", terminal.PromptColor(">"))
 	verifiedPassword := cmd.ui.AskForPassword("Verify Password%s", terminal.PromptColor(">"))
@@ -42,9 +48,10 @@ func (cmd Password) Run(c *cli.Context) {
 	apiResponse := cmd.pwdRepo.UpdatePassword(oldPassword, newPassword)
 
 	if apiResponse.IsNotSuccessful() {
-		if apiResponse.StatusCode == 401 {
+		switch apiResponse.Err().(type) {
+		case *net.AuthError:
 			cmd.ui.Failed("Current password did not match")
-		} else {
+		default:
 			cmd.ui.Failed(apiResponse.Message)
 		}
 		return
@@ -77,6 +77,17 @@ var _ = Describe("password command", func() {
 			Expect(deps.PwdRepo.UpdateNewPassword).To(Equal(""))
 		})
 
+		It("fails when authenticated with client credentials", func() {
+			deps.Config.SetClientCredentials("my-client", "my-secret")
+			ui := callPassword([]string{}, deps)
+
+			Expect(ui.PasswordPrompts).To(BeEmpty())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"client credentials"},
+			})
+		})
+
 		It("fails when the current password does not match", func() {
 			deps.PwdRepo.UpdateUnauthorized = true
 			ui := callPassword([]string{"old-password", "new-password", "new-password"}, deps)
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"cf"
+	"cf/configuration"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+)
+
+type Profile struct {
+	ui     terminal.UI
+	config configuration.ReadWriter
+}
+
+func NewProfile(ui terminal.UI, config configuration.ReadWriter) (cmd Profile) {
+	cmd.ui = ui
+	cmd.config = config
+	return
+}
+
+func (cmd Profile) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 2 || (c.Args()[0] != "create" && c.Args()[0] != "use") {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "profile")
+	}
+	return
+}
+
+func (cmd Profile) Run(c *cli.Context) {
+	name := c.Args()[1]
+
+	switch c.Args()[0] {
+	case "create":
+		cmd.create(name)
+	case "use":
+		cmd.use(name)
+	}
+}
+
+func (cmd Profile) create(name string) {
+	cmd.ui.Say("Creating profile %s...", terminal.EntityNameColor(name))
+
+	err := cmd.config.CreateProfile(name)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("\nTIP: Use '%s profile use %s' to switch to it, then '%s api' and '%s login' to set it up", cf.Name(), name, cf.Name(), cf.Name())
+}
+
+func (cmd Profile) use(name string) {
+	cmd.ui.Say("Switching to profile %s...", terminal.EntityNameColor(name))
+
+	err := cmd.config.UseProfile(name)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.ShowConfiguration(cmd.config)
+}
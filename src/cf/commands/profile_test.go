@@ -0,0 +1,87 @@
+package commands_test
+
+import (
+	. "cf/commands"
+	"cf/configuration"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("profile command", func() {
+	var (
+		ui     *testterm.FakeUI
+		config configuration.ReadWriter
+	)
+
+	runCommand := func(args ...string) {
+		cmd := NewProfile(ui, config)
+		testcmd.RunCommand(cmd, testcmd.NewContext("profile", args), &testreq.FakeReqFactory{})
+	}
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+		config = testconfig.NewRepository()
+	})
+
+	It("fails with usage when not given an action and a profile name", func() {
+		runCommand()
+		Expect(ui.FailedWithUsage).To(BeTrue())
+
+		ui = &testterm.FakeUI{}
+		runCommand("create")
+		Expect(ui.FailedWithUsage).To(BeTrue())
+
+		ui = &testterm.FakeUI{}
+		runCommand("delete", "prod")
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	It("creates a profile", func() {
+		runCommand("create", "prod")
+
+		Expect(config.ProfileNames()).To(ContainElement("prod"))
+		Expect(config.ProfileName()).To(Equal(configuration.DEFAULT_PROFILE))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Creating profile", "prod"},
+			{"OK"},
+		})
+	})
+
+	It("fails when the profile already exists", func() {
+		config.CreateProfile("prod")
+		runCommand("create", "prod")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Profile prod already exists"},
+		})
+	})
+
+	It("switches to a profile", func() {
+		config.SetApiEndpoint("https://api.staging.example.com")
+		config.CreateProfile("prod")
+		runCommand("use", "prod")
+
+		Expect(config.ProfileName()).To(Equal("prod"))
+		Expect(config.ApiEndpoint()).To(Equal(""))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Switching to profile", "prod"},
+			{"OK"},
+		})
+	})
+
+	It("fails to switch to a profile that does not exist", func() {
+		runCommand("use", "prod")
+
+		Expect(config.ProfileName()).To(Equal(configuration.DEFAULT_PROFILE))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Profile prod does not exist"},
+		})
+	})
+})
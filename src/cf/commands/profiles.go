@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"cf/configuration"
+	"cf/requirements"
+	"cf/terminal"
+	"github.com/codegangsta/cli"
+)
+
+type ListProfiles struct {
+	ui     terminal.UI
+	config configuration.Reader
+}
+
+func NewListProfiles(ui terminal.UI, config configuration.Reader) (cmd ListProfiles) {
+	cmd.ui = ui
+	cmd.config = config
+	return
+}
+
+func (cmd ListProfiles) GetRequirements(reqFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	return
+}
+
+func (cmd ListProfiles) Run(c *cli.Context) {
+	cmd.ui.Say("Getting profiles...")
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+
+	table := [][]string{
+		[]string{"name", "api endpoint", "user"},
+	}
+
+	current := cmd.config.ProfileName()
+	for _, name := range cmd.config.ProfileNames() {
+		profile, _ := cmd.config.ProfileByName(name)
+		if name == current {
+			name = name + " (current)"
+		}
+
+		table = append(table, []string{
+			name,
+			profile.Target,
+			configuration.NewTokenInfo(profile.AccessToken).Username,
+		})
+	}
+
+	cmd.ui.DisplayTable(table)
+}
@@ -0,0 +1,32 @@
+package commands_test
+
+import (
+	. "cf/commands"
+	. "github.com/onsi/ginkgo"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("profiles command", func() {
+	It("lists every profile and marks the current one", func() {
+		config := testconfig.NewRepositoryWithDefaults()
+		config.CreateProfile("prod")
+		config.UseProfile("prod")
+		config.SetApiEndpoint("https://api.prod.example.com")
+
+		ui := &testterm.FakeUI{}
+		cmd := NewListProfiles(ui, config)
+		testcmd.RunCommand(cmd, testcmd.NewContext("profiles", []string{}), &testreq.FakeReqFactory{})
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Getting profiles"},
+			{"OK"},
+			{"name", "api endpoint", "user"},
+			{"default", "my-user"},
+			{"prod (current)", "https://api.prod.example.com"},
+		})
+	})
+})
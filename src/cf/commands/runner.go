@@ -1,11 +1,17 @@
 package commands
 
 import (
+	"cf/configuration"
+	"cf/formatters"
+	"cf/net"
 	"cf/requirements"
+	"cf/terminal"
 	"errors"
 	"fmt"
 	"github.com/codegangsta/cli"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Command interface {
@@ -18,13 +24,19 @@ type Runner interface {
 }
 
 type ConcreteRunner struct {
+	ui         terminal.UI
 	cmdFactory Factory
 	reqFactory requirements.Factory
+	timings    *net.TimingsRecorder
 }
 
-func NewRunner(cmdFactory Factory, reqFactory requirements.Factory) (runner ConcreteRunner) {
+// NewRunner takes the timings recorder set on the gateways, which it enables
+// for --timings.
+func NewRunner(ui terminal.UI, cmdFactory Factory, reqFactory requirements.Factory, timings *net.TimingsRecorder) (runner ConcreteRunner) {
+	runner.ui = ui
 	runner.cmdFactory = cmdFactory
 	runner.reqFactory = reqFactory
+	runner.timings = timings
 	return
 }
 
@@ -36,6 +48,17 @@ func (runner ConcreteRunner) RunCmdByName(cmdName string, c *cli.Context) (err e
 		return
 	}
 
+	if c.GlobalString("profile") != "" {
+		os.Setenv(configuration.CF_PROFILE, c.GlobalString("profile"))
+	}
+
+	// printed from an exit hook, so commands that fail get a summary too
+	if c.GlobalBool("timings") || os.Getenv(net.CF_TIMINGS) == "true" {
+		runner.timings.Enable()
+		terminal.OnExit(runner.printTimings)
+		defer terminal.RunExitHooks()
+	}
+
 	requirements, err := cmd.GetRequirements(runner.reqFactory, c)
 	if err != nil {
 		return
@@ -52,3 +75,51 @@ func (runner ConcreteRunner) RunCmdByName(cmdName string, c *cli.Context) (err e
 	cmd.Run(c)
 	return
 }
+
+func (runner ConcreteRunner) printTimings() {
+	runner.ui.Say("")
+	runner.ui.Say(terminal.HeaderColor("API timings:"))
+
+	var total time.Duration
+	var sent, received int64
+	rows := [][]string{}
+	requests := runner.timings.Requests()
+	for _, request := range requests {
+		status := ""
+		if request.StatusCode != 0 {
+			status = strconv.Itoa(request.StatusCode)
+		}
+
+		rows = append(rows, []string{
+			request.Method,
+			request.Path,
+			status,
+			formatDuration(request.Duration),
+			formatters.ByteSize(uint64(request.BytesSent)),
+			formatters.ByteSize(uint64(request.BytesReceived)),
+		})
+		total += request.Duration
+		sent += request.BytesSent
+		received += request.BytesReceived
+	}
+	rows = append(rows, []string{
+		"total",
+		fmt.Sprintf("%d requests", len(requests)),
+		"",
+		formatDuration(total),
+		formatters.ByteSize(uint64(sent)),
+		formatters.ByteSize(uint64(received)),
+	})
+	runner.ui.Table([]string{"method", "endpoint", "status", "duration", "sent", "received"}).Print(rows)
+
+	runner.ui.Say("")
+	rows = [][]string{}
+	for _, phase := range runner.timings.PhaseTotals() {
+		rows = append(rows, []string{phase.Phase, strconv.Itoa(phase.Requests), formatDuration(phase.Duration)})
+	}
+	runner.ui.Table([]string{"phase", "requests", "duration"}).Print(rows)
+}
+
+func formatDuration(duration time.Duration) string {
+	return fmt.Sprintf("%dms", duration/time.Millisecond)
+}
@@ -2,11 +2,17 @@ package commands_test
 
 import (
 	. "cf/commands"
+	"cf/net"
 	"cf/requirements"
 	"github.com/codegangsta/cli"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"net/http"
+	"os"
+	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
+	testterm "testhelpers/terminal"
+	"time"
 )
 
 type TestCommandFactory struct {
@@ -23,6 +29,7 @@ func (f *TestCommandFactory) GetByCmdName(cmdName string) (cmd Command, err erro
 type TestCommand struct {
 	Reqs       []requirements.Requirement
 	WasRunWith *cli.Context
+	RunFunc    func()
 }
 
 func (cmd *TestCommand) GetRequirements(factory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
@@ -32,6 +39,9 @@ func (cmd *TestCommand) GetRequirements(factory requirements.Factory, c *cli.Con
 
 func (cmd *TestCommand) Run(c *cli.Context) {
 	cmd.WasRunWith = c
+	if cmd.RunFunc != nil {
+		cmd.RunFunc()
+	}
 }
 
 type TestRequirement struct {
@@ -60,7 +70,7 @@ var _ = Describe("Requirements runner", func() {
 		}
 
 		cmdFactory := &TestCommandFactory{Cmd: &cmd}
-		runner := NewRunner(cmdFactory, nil)
+		runner := NewRunner(&testterm.FakeUI{}, cmdFactory, nil, net.NewTimingsRecorder())
 
 		ctxt := testcmd.NewContext("login", []string{})
 		err := runner.RunCmdByName("some-cmd", ctxt)
@@ -75,4 +85,84 @@ var _ = Describe("Requirements runner", func() {
 
 		Expect(err).To(HaveOccurred())
 	})
+
+	Describe("timings", func() {
+		var ui *testterm.FakeUI
+		var cmd TestCommand
+		var timings *net.TimingsRecorder
+
+		BeforeEach(func() {
+			ui = &testterm.FakeUI{}
+			timings = net.NewTimingsRecorder()
+			cmd = TestCommand{RunFunc: func() {
+				timings.Record(net.RequestTiming{
+					Method:        "PUT",
+					Path:          "/v2/resource_match",
+					Phase:         net.PHASE_RESOURCE_MATCH,
+					StatusCode:    http.StatusOK,
+					Duration:      1500 * time.Millisecond,
+					BytesSent:     2048,
+					BytesReceived: 10,
+				})
+				timings.Record(net.RequestTiming{
+					Method:     "GET",
+					Path:       "/v2/apps/my-app-guid/instances",
+					Phase:      net.PHASE_INSTANCE_POLLING,
+					StatusCode: http.StatusOK,
+					Duration:   250 * time.Millisecond,
+				})
+			}}
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(net.CF_TIMINGS)
+		})
+
+		It("prints a summary of the API calls when CF_TIMINGS is set", func() {
+			os.Setenv(net.CF_TIMINGS, "true")
+
+			runner := NewRunner(ui, &TestCommandFactory{Cmd: &cmd}, nil, timings)
+			runner.RunCmdByName("some-cmd", testcmd.NewContext("login", []string{}))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"API timings"},
+				{"method", "endpoint", "status", "duration", "sent", "received"},
+				{"PUT", "/v2/resource_match", "200", "1500ms", "2K", "10"},
+				{"GET", "/v2/apps/my-app-guid/instances", "200", "250ms", "0", "0"},
+				{"total", "2 requests", "1750ms", "2K", "10"},
+				{"phase", "requests", "duration"},
+				{"resource_match", "1", "1500ms"},
+				{"bits upload", "0", "0ms"},
+				{"job polling", "0", "0ms"},
+				{"instance polling", "1", "250ms"},
+			})
+		})
+
+		It("prints the summary when the command fails", func() {
+			os.Setenv(net.CF_TIMINGS, "true")
+			recordTimings := cmd.RunFunc
+			cmd.RunFunc = func() {
+				recordTimings()
+				ui.Failed("Something went wrong")
+			}
+
+			runner := NewRunner(ui, &TestCommandFactory{Cmd: &cmd}, nil, timings)
+			testassert.AssertPanic(testterm.FailedWasCalled, func() {
+				runner.RunCmdByName("some-cmd", testcmd.NewContext("login", []string{}))
+			})
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"API timings"},
+				{"total", "2 requests", "1750ms"},
+			})
+		})
+
+		It("does not print timings by default", func() {
+			runner := NewRunner(ui, &TestCommandFactory{Cmd: &cmd}, nil, timings)
+			runner.RunCmdByName("some-cmd", testcmd.NewContext("login", []string{}))
+
+			Expect(ui.Outputs).To(BeEmpty())
+		})
+	})
 })
@@ -12,8 +12,6 @@ import (
 	"github.com/codegangsta/cli"
 )
 
-const AppAlreadyBoundErrorCode = "90003"
-
 type BindService struct {
 	ui                 terminal.UI
 	config             configuration.Reader
@@ -64,13 +62,13 @@ func (cmd *BindService) Run(c *cli.Context) {
 	)
 
 	apiResponse := cmd.BindApplication(app, serviceInstance)
-	if apiResponse.IsNotSuccessful() && apiResponse.ErrorCode != AppAlreadyBoundErrorCode {
+	if apiResponse.IsNotSuccessful() && apiResponse.ErrorCode != cf.APP_ALREADY_BOUND {
 		cmd.ui.Failed(apiResponse.Message)
 	}
 
 	cmd.ui.Ok()
 
-	if apiResponse.ErrorCode == AppAlreadyBoundErrorCode {
+	if apiResponse.ErrorCode == cf.APP_ALREADY_BOUND {
 		cmd.ui.Warn("App %s is already bound to %s.", app.Name, serviceInstance.Name)
 		return
 	}
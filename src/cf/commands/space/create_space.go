@@ -86,6 +86,13 @@ func (cmd CreateSpace) Run(c *cli.Context) {
 	}
 	cmd.ui.Ok()
 
+	// clients authenticated with client credentials are not users and cannot
+	// be given space roles
+	if cmd.config.UserGuid() == "" {
+		cmd.ui.Say("\nTIP: Use '%s' to target new space", terminal.CommandColor(cf.Name()+" target -o "+orgName+" -s "+space.Name))
+		return
+	}
+
 	var err error
 
 	err = cmd.spaceRoleSetter.SetSpaceRole(space, models.SPACE_MANAGER, cmd.config.UserGuid(), cmd.config.Username())
@@ -4,12 +4,17 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 type TokenInfo struct {
-	Username string `json:"user_name"`
-	Email    string `json:"email"`
-	UserGuid string `json:"user_id"`
+	Username  string   `json:"user_name"`
+	Email     string   `json:"email"`
+	UserGuid  string   `json:"user_id"`
+	ClientId  string   `json:"client_id"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	Scope     []string `json:"scope,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
 }
 
 func NewTokenInfo(accessToken string) (info TokenInfo) {
@@ -23,6 +28,32 @@ func NewTokenInfo(accessToken string) (info TokenInfo) {
 	return
 }
 
+// Expiry is the zero time for tokens without an exp claim.
+func (info TokenInfo) Expiry() (expiry time.Time) {
+	if info.ExpiresAt == 0 {
+		return
+	}
+	return time.Unix(info.ExpiresAt, 0)
+}
+
+// ExpiresWithin reports whether the token will have expired window from now.
+// Tokens without an exp claim are never considered expiring.
+func (info TokenInfo) ExpiresWithin(window time.Duration) bool {
+	if info.ExpiresAt == 0 {
+		return false
+	}
+	return time.Now().Add(window).After(info.Expiry())
+}
+
+func (info TokenInfo) HasScope(scope string) bool {
+	for _, s := range info.Scope {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 func DecodeAccessToken(accessToken string) (tokenJson []byte, err error) {
 	tokenParts := strings.Split(accessToken, " ")
 
@@ -41,7 +72,10 @@ func DecodeAccessToken(accessToken string) (tokenJson []byte, err error) {
 	return base64Decode(encodedTokenJson)
 }
 
+// base64Decode accepts the unpadded URL-safe encoding used by JWTs, as well
+// as the standard encoding.
 func base64Decode(encodedData string) ([]byte, error) {
+	encodedData = strings.NewReplacer("-", "+", "_", "/").Replace(encodedData)
 	return base64.StdEncoding.DecodeString(restorePadding(encodedData))
 }
 
@@ -50,7 +84,7 @@ func restorePadding(seg string) string {
 	case 2:
 		seg = seg + "=="
 	case 3:
-		seg = seg + "==="
+		seg = seg + "="
 	}
 	return seg
 }
@@ -2,21 +2,146 @@ package configuration
 
 import (
 	"cf/models"
+	"errors"
+	"fmt"
+	"sort"
 )
 
-type Data struct {
-	ConfigVersion         int
+const (
+	CF_PROFILE       = "CF_PROFILE"
+	CF_CLIENT_ID     = "CF_CLIENT_ID"
+	CF_CLIENT_SECRET = "CF_CLIENT_SECRET"
+	DEFAULT_PROFILE  = "default"
+)
+
+// Profile holds everything that belongs to one target: its endpoints, the
+// tokens of the user logged in to it and the targeted org and space. ClientId
+// and ClientSecret are kept when authenticated as a UAA client, since client
+// tokens come without a refresh token.
+type Profile struct {
 	Target                string
 	ApiVersion            string
 	AuthorizationEndpoint string
-	LoggregatorEndPoint   string
+	TokenEndpoint         string
+	LoggregatorEndPoint   string `json:"LoggregatorEndpoint"`
 	AccessToken           string
 	RefreshToken          string
+	ClientId              string
+	ClientSecret          string
 	OrganizationFields    models.OrganizationFields
 	SpaceFields           models.SpaceFields
+	SSLDisabled           bool
+	CACertFile            string
+}
+
+// Data embeds the profile in use, so its settings read like top level fields.
+// Profiles holds the settings of every other profile, and DefaultProfile is
+// the one used unless --profile or CF_PROFILE picks another for a command.
+type Data struct {
+	ConfigVersion int
+	Profile
+	ActiveProfile  string
+	DefaultProfile string
+	Profiles       map[string]Profile
+	Proxy          string
 }
 
 func NewData() (data *Data) {
 	data = new(Data)
+	data.ActiveProfile = DEFAULT_PROFILE
+	data.DefaultProfile = DEFAULT_PROFILE
+	data.Profiles = map[string]Profile{}
+	return
+}
+
+func (data *Data) copy() (copied *Data) {
+	copied = new(Data)
+	*copied = *data
+	copied.Profiles = map[string]Profile{}
+	for name, profile := range data.Profiles {
+		copied.Profiles[name] = profile
+	}
+	return
+}
+
+// clearSession logs out of the profile in use and forgets its org and space.
+func (data *Data) clearSession() {
+	data.AccessToken = ""
+	data.RefreshToken = ""
+	data.ClientId = ""
+	data.ClientSecret = ""
+	data.OrganizationFields = models.OrganizationFields{}
+	data.SpaceFields = models.SpaceFields{}
+}
+
+// mapSecrets replaces the tokens and client secret of every profile with the
+// result of fn.
+func (data *Data) mapSecrets(fn func(string) (string, error)) (err error) {
+	mapProfile := func(profile *Profile) (err error) {
+		for _, field := range []*string{&profile.AccessToken, &profile.RefreshToken, &profile.ClientSecret} {
+			*field, err = fn(*field)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	err = mapProfile(&data.Profile)
+	if err != nil {
+		return
+	}
+
+	for name, profile := range data.Profiles {
+		err = mapProfile(&profile)
+		if err != nil {
+			return
+		}
+		data.Profiles[name] = profile
+	}
+	return
+}
+
+func (data *Data) HasProfile(name string) bool {
+	_, found := data.Profiles[name]
+	return found || name == data.ActiveProfile
+}
+
+func (data *Data) ProfileNames() (names []string) {
+	names = append(names, data.ActiveProfile)
+	for name, _ := range data.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func (data *Data) CreateProfile(name string) (err error) {
+	if name == "" {
+		err = errors.New("Profile name cannot be empty")
+		return
+	}
+	if data.HasProfile(name) {
+		err = errors.New(fmt.Sprintf("Profile %s already exists", name))
+		return
+	}
+
+	data.Profiles[name] = Profile{}
+	return
+}
+
+func (data *Data) SwitchProfile(name string) (err error) {
+	if !data.HasProfile(name) {
+		err = errors.New(fmt.Sprintf("Profile %s does not exist", name))
+		return
+	}
+	if name == data.ActiveProfile {
+		return
+	}
+
+	data.Profiles[data.ActiveProfile] = data.Profile
+	data.Profile = data.Profiles[name]
+	delete(data.Profiles, name)
+	data.ActiveProfile = name
 	return
 }
@@ -13,37 +13,135 @@ const (
 	dirPermissions  = 0700
 )
 
+// Persistor is shared by every cf process using the same CF_HOME. Lock and
+// Unlock hold the config across processes while it is modified and saved, and
+// Changed tells whether another process saved it since it was last loaded.
 type Persistor interface {
 	Delete()
+	Wipe() error
+	Lock() error
+	Unlock()
+	Changed() bool
 	Load() (*Data, error)
 	Save(*Data) error
 }
 
 type DiskPersistor struct {
 	filePath string
+	lockFile *os.File
+	loaded   os.FileInfo
+
+	wipeOnSave bool
 }
 
-func NewDiskPersistor(path string) (dp DiskPersistor) {
-	return DiskPersistor{filePath: path}
+func NewDiskPersistor(path string) (dp *DiskPersistor) {
+	return &DiskPersistor{filePath: path}
 }
 
-func (dp DiskPersistor) Delete() {
+func (dp *DiskPersistor) Delete() {
 	os.Remove(dp.filePath)
 }
 
-func (dp DiskPersistor) Load() (data *Data, err error) {
+// Lock takes an advisory lock on a file next to the config, blocking until
+// other cf processes release it.
+func (dp *DiskPersistor) Lock() (err error) {
+	err = os.MkdirAll(filepath.Dir(dp.filePath), dirPermissions)
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(dp.filePath+".lock", os.O_RDWR|os.O_CREATE, filePermissions)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error locking config file:%s\n%s", dp.filePath, err))
+		return
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		err = errors.New(fmt.Sprintf("Error locking config file:%s\n%s", dp.filePath, err))
+		return
+	}
+
+	dp.lockFile = file
+	return
+}
+
+func (dp *DiskPersistor) Unlock() {
+	if dp.lockFile == nil {
+		return
+	}
+
+	unlockFile(dp.lockFile)
+	dp.lockFile.Close()
+	dp.lockFile = nil
+}
+
+// Changed compares the file with the one last loaded or saved. Saves replace
+// the file, so a save by another process always shows up as a different file.
+func (dp *DiskPersistor) Changed() bool {
+	current, err := os.Stat(dp.filePath)
+	if err != nil {
+		return false
+	}
+	if dp.loaded == nil {
+		return true
+	}
+
+	return !os.SameFile(dp.loaded, current) ||
+		!dp.loaded.ModTime().Equal(current.ModTime()) ||
+		dp.loaded.Size() != current.Size()
+}
+
+// Wipe makes the next save clear the file it replaces, so the tokens it held
+// do not linger on disk. The new file is in place before the old copy is
+// cleared, so other processes never read a cleared file.
+func (dp *DiskPersistor) Wipe() error {
+	dp.wipeOnSave = true
+	return nil
+}
+
+// Load never writes over a file it cannot parse, since another process may be
+// replacing it; the next save, made under the lock, replaces it instead. A
+// missing file is created under the lock.
+func (dp *DiskPersistor) Load() (data *Data, err error) {
 	data, err = dp.read()
+	if os.IsNotExist(err) {
+		return dp.create()
+	}
 	if err != nil {
-		err = dp.write(data)
+		data, err = NewData(), nil
 	}
 	return
 }
 
-func (dp DiskPersistor) Save(data *Data) (err error) {
+func (dp *DiskPersistor) Save(data *Data) (err error) {
 	return dp.write(data)
 }
 
-func (dp DiskPersistor) read() (data *Data, err error) {
+func (dp *DiskPersistor) create() (data *Data, err error) {
+	if dp.lockFile == nil {
+		err = dp.Lock()
+		if err != nil {
+			data = NewData()
+			return
+		}
+		defer dp.Unlock()
+	}
+
+	data, err = dp.read()
+	if os.IsNotExist(err) {
+		data = NewData()
+		err = dp.write(data)
+		return
+	}
+	if err != nil {
+		data, err = NewData(), nil
+	}
+	return
+}
+
+func (dp *DiskPersistor) read() (data *Data, err error) {
 	data = NewData()
 
 	err = os.MkdirAll(filepath.Dir(dp.filePath), dirPermissions)
@@ -51,25 +149,137 @@ func (dp DiskPersistor) read() (data *Data, err error) {
 		return
 	}
 
-	jsonBytes, err := ioutil.ReadFile(dp.filePath)
+	file, err := os.Open(dp.filePath)
 	if err != nil {
 		return
 	}
+	defer file.Close()
 
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	jsonBytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return
+	}
+	dp.loaded = info
+
+	// a v2 file holds a single target, which becomes the default profile and
+	// is written back in the v3 format on the next save
 	err = JsonUnmarshalV2(jsonBytes, data)
+	if err != nil {
+		return
+	}
+
+	err = JsonUnmarshalV3(jsonBytes, data)
 	return
 }
 
-func (dp DiskPersistor) write(data *Data) (err error) {
-	bytes, err := JsonMarshalV2(data)
+func (dp *DiskPersistor) write(data *Data) (err error) {
+	bytes, err := JsonMarshalV3(data)
 	if err != nil {
 		return
 	}
 
-	err = ioutil.WriteFile(dp.filePath, bytes, filePermissions)
+	err = dp.writeAtomically(bytes)
 	if err != nil {
 		err = errors.New(fmt.Sprintf("Error writing to manifest file:%s\n%s", dp.filePath, err))
 		return
 	}
 	return
 }
+
+// writeAtomically writes a temp file and renames it over the config, so other
+// processes never read a partly written file.
+func (dp *DiskPersistor) writeAtomically(bytes []byte) (err error) {
+	tempFile, err := ioutil.TempFile(filepath.Dir(dp.filePath), filepath.Base(dp.filePath)+".tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(bytes)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
+	err = os.Chmod(tempFile.Name(), filePermissions)
+	if err != nil {
+		return
+	}
+
+	oldCopy := ""
+	if dp.wipeOnSave {
+		oldCopy, err = dp.setAside()
+		if err != nil {
+			return
+		}
+	}
+
+	err = os.Rename(tempFile.Name(), dp.filePath)
+	if err != nil {
+		if oldCopy != "" {
+			os.Rename(oldCopy, dp.filePath)
+		}
+		return
+	}
+
+	dp.loaded, err = os.Stat(dp.filePath)
+	if err != nil || oldCopy == "" {
+		return
+	}
+
+	err = wipeFile(oldCopy)
+	if err == nil {
+		dp.wipeOnSave = false
+	}
+	return
+}
+
+// setAside keeps a second name for the file about to be replaced, so it can be
+// wiped once the new one is in place. Where hard links are not supported the
+// file is moved aside instead, and cf processes reading it meanwhile wait for
+// the lock to create it.
+func (dp *DiskPersistor) setAside() (path string, err error) {
+	path = dp.filePath + ".wipe"
+	os.Remove(path)
+
+	err = os.Link(dp.filePath, path)
+	if err != nil {
+		err = os.Rename(dp.filePath, path)
+	}
+	if os.IsNotExist(err) {
+		path, err = "", nil
+	}
+	return
+}
+
+func wipeFile(path string) (err error) {
+	defer os.Remove(path)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, filePermissions)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(make([]byte, info.Size()))
+	if err != nil {
+		return
+	}
+	return file.Sync()
+}
@@ -5,6 +5,7 @@ import (
 	"fileutils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 )
@@ -67,4 +68,204 @@ var _ = Describe("Testing with ginkgo", func() {
 			Expect(configData.Target).To(Equal(""))
 		})
 	})
+
+	It("clears the file replaced by the save after a wipe, leaving the new file in place", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			configData.AccessToken = "bearer my_access_token"
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			oldFile, err := os.Open(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer oldFile.Close()
+			oldInfo, err := oldFile.Stat()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = repo.Wipe()
+			Expect(err).NotTo(HaveOccurred())
+
+			savedJson, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(savedJson)).To(ContainSubstring("my_access_token"))
+
+			configData.AccessToken = ""
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			savedConfig, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(savedConfig).To(Equal(configData))
+
+			oldJson, err := ioutil.ReadAll(oldFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(oldJson).To(Equal(make([]byte, oldInfo.Size())))
+
+			files, err := ioutil.ReadDir(filepath.Dir(configPath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(files)).To(Equal(2))
+			Expect(files[0].Name()).To(Equal("config.json"))
+			Expect(files[1].Name()).To(Equal("config.json.lock"))
+		})
+	})
+
+	It("does not write over a file it cannot parse", func() {
+		withFakeHome(func(configPath string) {
+			err := os.MkdirAll(filepath.Dir(configPath), 0700)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(configPath, []byte(`{"ConfigVersion": 3, "Profi`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			repo := NewDiskPersistor(configPath)
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configData).To(Equal(NewData()))
+
+			savedJson, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(savedJson)).To(Equal(`{"ConfigVersion": 3, "Profi`))
+		})
+	})
+
+	It("creates a missing file under the lock", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			otherRepo := NewDiskPersistor(configPath)
+
+			err := otherRepo.Lock()
+			Expect(err).NotTo(HaveOccurred())
+
+			loaded := make(chan bool)
+			go func() {
+				defer GinkgoRecover()
+				_, err := repo.Load()
+				Expect(err).NotTo(HaveOccurred())
+				close(loaded)
+			}()
+
+			Consistently(loaded, 0.1).ShouldNot(BeClosed())
+			_, err = os.Stat(configPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			otherRepo.Unlock()
+			Eventually(loaded).Should(BeClosed())
+			_, err = os.Stat(configPath)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	It("migrates a v2 config file into the default profile", func() {
+		withFakeHome(func(configPath string) {
+			err := os.MkdirAll(filepath.Dir(configPath), 0700)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(configPath, []byte(`{"ConfigVersion": 2, "Target": "https://api.example.com", "AccessToken": "the-token"}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			repo := NewDiskPersistor(configPath)
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configData.ActiveProfile).To(Equal(DEFAULT_PROFILE))
+			Expect(configData.Target).To(Equal("https://api.example.com"))
+			Expect(configData.AccessToken).To(Equal("the-token"))
+
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			savedJson, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(savedJson)).To(ContainSubstring(`"ConfigVersion":3`))
+
+			savedConfig, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(savedConfig).To(Equal(configData))
+		})
+	})
+
+	It("saves and loads every profile", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			configData.Target = "https://api.staging.example.com"
+			Expect(configData.CreateProfile("prod")).NotTo(HaveOccurred())
+			Expect(configData.SwitchProfile("prod")).NotTo(HaveOccurred())
+			configData.Target = "https://api.prod.example.com"
+			configData.DefaultProfile = "prod"
+
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			savedConfig, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(savedConfig).To(Equal(configData))
+			Expect(savedConfig.Profiles[DEFAULT_PROFILE].Target).To(Equal("https://api.staging.example.com"))
+		})
+	})
+
+	It("replaces the file when saving, leaving no temp files behind", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			configData.AccessToken = "bearer my_access_token"
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			files, err := ioutil.ReadDir(filepath.Dir(configPath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(files)).To(Equal(2))
+			Expect(files[0].Name()).To(Equal("config.json"))
+			Expect(files[1].Name()).To(Equal("config.json.lock"))
+			Expect(files[0].Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+	})
+
+	It("knows when another process saved the file since it was loaded", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			otherRepo := NewDiskPersistor(configPath)
+
+			configData, err := repo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.Changed()).To(BeFalse())
+
+			otherData, err := otherRepo.Load()
+			Expect(err).NotTo(HaveOccurred())
+			otherData.AccessToken = "bearer refreshed_token"
+			err = otherRepo.Save(otherData)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(repo.Changed()).To(BeTrue())
+			Expect(otherRepo.Changed()).To(BeFalse())
+
+			err = repo.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.Changed()).To(BeFalse())
+		})
+	})
+
+	It("holds the lock until it is unlocked", func() {
+		withFakeHome(func(configPath string) {
+			repo := NewDiskPersistor(configPath)
+			otherRepo := NewDiskPersistor(configPath)
+
+			err := repo.Lock()
+			Expect(err).NotTo(HaveOccurred())
+
+			locked := make(chan bool)
+			go func() {
+				otherRepo.Lock()
+				close(locked)
+			}()
+
+			Consistently(locked, 0.1).ShouldNot(BeClosed())
+			repo.Unlock()
+			Eventually(locked).Should(BeClosed())
+			otherRepo.Unlock()
+		})
+	})
 })
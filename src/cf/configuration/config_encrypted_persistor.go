@@ -0,0 +1,262 @@
+package configuration
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	CF_CONFIG_KEY      = "CF_CONFIG_KEY"
+	CF_CONFIG_KEY_FILE = "CF_CONFIG_KEY_FILE"
+
+	encryptedValuePrefix = "enc:v2:"
+
+	// PBKDF2 with HMAC-SHA256, at the work factor OWASP recommends. Values
+	// derived with fewer iterations, or with so many that loading the config
+	// would stall every command, are rejected.
+	keyDerivationIterations    = 600000
+	maxKeyDerivationIterations = 10 * keyDerivationIterations
+	keySize                    = 32
+	saltSize                   = 16
+)
+
+// EncryptedPersistor keeps the tokens and client secrets of every profile
+// encrypted with AES-GCM in the file written by the wrapped persistor. The
+// rest of the config stays readable. Values that are not encrypted yet are
+// read as they are, and encrypted the next time the config is saved.
+type EncryptedPersistor struct {
+	persistor  Persistor
+	passphrase []byte
+	keys       *derivedKeys
+}
+
+// derivedKeys caches the ciphers derived from the passphrase, since deriving
+// one is slow on purpose. Saves reuse the salt of a loaded value derived with
+// the current parameters, so most commands derive a single key.
+type derivedKeys struct {
+	mutex   sync.Mutex
+	ciphers map[string]cipher.AEAD
+	salt    []byte
+}
+
+func NewEncryptedPersistor(persistor Persistor, passphrase []byte) EncryptedPersistor {
+	return EncryptedPersistor{
+		persistor:  persistor,
+		passphrase: passphrase,
+		keys:       &derivedKeys{ciphers: map[string]cipher.AEAD{}},
+	}
+}
+
+// ConfigKeyFromEnv returns the passphrase set with CF_CONFIG_KEY or read from
+// the file CF_CONFIG_KEY_FILE points at, or nil when neither is set.
+func ConfigKeyFromEnv() (key []byte, err error) {
+	value, path := os.Getenv(CF_CONFIG_KEY), os.Getenv(CF_CONFIG_KEY_FILE)
+	switch {
+	case value != "" && path != "":
+		err = errors.New(fmt.Sprintf("Set only one of %s and %s", CF_CONFIG_KEY, CF_CONFIG_KEY_FILE))
+		return
+	case value != "":
+		key = []byte(value)
+		return
+	case path == "":
+		return
+	}
+
+	key, err = ioutil.ReadFile(path)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error reading config key file %s\n%s", path, err))
+		return
+	}
+
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		err = errors.New(fmt.Sprintf("Config key file %s is empty", path))
+	}
+	return
+}
+
+func (ep EncryptedPersistor) Delete() {
+	ep.persistor.Delete()
+}
+
+func (ep EncryptedPersistor) Wipe() error {
+	return ep.persistor.Wipe()
+}
+
+func (ep EncryptedPersistor) Lock() error {
+	return ep.persistor.Lock()
+}
+
+func (ep EncryptedPersistor) Unlock() {
+	ep.persistor.Unlock()
+}
+
+func (ep EncryptedPersistor) Changed() bool {
+	return ep.persistor.Changed()
+}
+
+func (ep EncryptedPersistor) Load() (data *Data, err error) {
+	data, err = ep.persistor.Load()
+	if err != nil {
+		return
+	}
+
+	err = data.mapSecrets(ep.decrypt)
+	return
+}
+
+func (ep EncryptedPersistor) Save(data *Data) (err error) {
+	salt, err := ep.saltForSave()
+	if err != nil {
+		return
+	}
+
+	aead, err := ep.cipher(keyDerivationIterations, salt)
+	if err != nil {
+		return
+	}
+
+	encrypted := data.copy()
+	err = encrypted.mapSecrets(func(value string) (string, error) {
+		return encrypt(value, salt, aead)
+	})
+	if err != nil {
+		return
+	}
+
+	return ep.persistor.Save(encrypted)
+}
+
+// encrypted values are the prefix and iteration count, followed by base64 of
+// salt, nonce and sealed value
+func encrypt(value string, salt []byte, aead cipher.AEAD) (encrypted string, err error) {
+	if value == "" {
+		return
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return
+	}
+
+	sealed := aead.Seal(nil, nonce, []byte(value), nil)
+	payload := append(append(append([]byte{}, salt...), nonce...), sealed...)
+	encrypted = fmt.Sprintf("%s%d:%s", encryptedValuePrefix, keyDerivationIterations, base64.StdEncoding.EncodeToString(payload))
+	return
+}
+
+func (ep EncryptedPersistor) decrypt(value string) (decrypted string, err error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		decrypted = value
+		return
+	}
+
+	var iterations int
+	var encoded string
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedValuePrefix), ":", 2)
+	if len(parts) == 2 {
+		iterations, _ = strconv.Atoi(parts[0])
+		encoded = parts[1]
+	}
+
+	errDecrypting := errors.New(fmt.Sprintf("Could not decrypt the config file, check the key in %s or %s", CF_CONFIG_KEY, CF_CONFIG_KEY_FILE))
+
+	if iterations < keyDerivationIterations || iterations > maxKeyDerivationIterations {
+		err = errors.New(fmt.Sprintf("Could not decrypt the config file, it asks for %d key derivation iterations but only %d to %d are accepted",
+			iterations, keyDerivationIterations, maxKeyDerivationIterations))
+		return
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(payload) < saltSize {
+		err = errDecrypting
+		return
+	}
+
+	salt := payload[:saltSize]
+	aead, err := ep.cipher(iterations, salt)
+	if err != nil {
+		return
+	}
+
+	if iterations == keyDerivationIterations {
+		ep.keys.mutex.Lock()
+		ep.keys.salt = salt
+		ep.keys.mutex.Unlock()
+	}
+
+	payload = payload[saltSize:]
+	if len(payload) < aead.NonceSize() {
+		err = errDecrypting
+		return
+	}
+
+	plain, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], nil)
+	if err != nil {
+		err = errDecrypting
+		return
+	}
+
+	decrypted = string(plain)
+	return
+}
+
+func (ep EncryptedPersistor) saltForSave() (salt []byte, err error) {
+	ep.keys.mutex.Lock()
+	defer ep.keys.mutex.Unlock()
+
+	if ep.keys.salt == nil {
+		salt = make([]byte, saltSize)
+		_, err = io.ReadFull(rand.Reader, salt)
+		if err != nil {
+			return
+		}
+		ep.keys.salt = salt
+	}
+	salt = ep.keys.salt
+	return
+}
+
+// cipher derives the key for the given parameters, once per persistor.
+func (ep EncryptedPersistor) cipher(iterations int, salt []byte) (aead cipher.AEAD, err error) {
+	ep.keys.mutex.Lock()
+	defer ep.keys.mutex.Unlock()
+
+	id := fmt.Sprintf("%d:%x", iterations, salt)
+	aead, found := ep.keys.ciphers[id]
+	if found {
+		return
+	}
+
+	key, err := pbkdf2.Key(sha256.New, string(ep.passphrase), salt, iterations, keySize)
+	if err != nil {
+		return
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+
+	ep.keys.ciphers[id] = aead
+	return
+}
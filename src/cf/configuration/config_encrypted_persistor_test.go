@@ -0,0 +1,175 @@
+package configuration_test
+
+import (
+	. "cf/configuration"
+	"fileutils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ = Describe("EncryptedPersistor", func() {
+	savedConfigData := func() (data *Data) {
+		data = NewData()
+		data.Target = "https://api.example.com"
+		data.AccessToken = "bearer the-access-token"
+		data.RefreshToken = "the-refresh-token"
+		data.CreateProfile("ci")
+		data.SwitchProfile("ci")
+		data.ClientId = "my-client"
+		data.ClientSecret = "the-client-secret"
+		data.SwitchProfile(DEFAULT_PROFILE)
+		return
+	}
+
+	It("encrypts the tokens of every profile and decrypts them on load", func() {
+		withFakeHome(func(configPath string) {
+			persistor := NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("my passphrase"))
+			_, err := persistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			configData := savedConfigData()
+			err = persistor.Save(configData)
+			Expect(err).NotTo(HaveOccurred())
+
+			fileContents, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fileContents)).To(ContainSubstring("https://api.example.com"))
+			Expect(string(fileContents)).To(ContainSubstring("enc:v2:600000:"))
+			Expect(string(fileContents)).NotTo(ContainSubstring("the-access-token"))
+			Expect(string(fileContents)).NotTo(ContainSubstring("the-refresh-token"))
+			Expect(string(fileContents)).NotTo(ContainSubstring("the-client-secret"))
+
+			Expect(configData.AccessToken).To(Equal("bearer the-access-token"))
+
+			loadedData, err := persistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loadedData).To(Equal(configData))
+		})
+	})
+
+	It("fails to load with the wrong key", func() {
+		withFakeHome(func(configPath string) {
+			persistor := NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("my passphrase"))
+			_, err := persistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = persistor.Save(savedConfigData())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("wrong passphrase")).Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("CF_CONFIG_KEY"))
+		})
+	})
+
+	It("refuses to derive the key with too few or too many iterations", func() {
+		withFakeHome(func(configPath string) {
+			persistor := NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("my passphrase"))
+			_, err := persistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = persistor.Save(savedConfigData())
+			Expect(err).NotTo(HaveOccurred())
+
+			fileContents, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, iterations := range []string{"1", "999999999999"} {
+				edited := strings.Replace(string(fileContents), "enc:v2:600000:", "enc:v2:"+iterations+":", -1)
+				err = ioutil.WriteFile(configPath, []byte(edited), 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("my passphrase")).Load()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("key derivation iterations"))
+			}
+		})
+	})
+
+	It("reads plaintext configs and encrypts them on the next save", func() {
+		withFakeHome(func(configPath string) {
+			diskPersistor := NewDiskPersistor(configPath)
+			_, err := diskPersistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = diskPersistor.Save(savedConfigData())
+			Expect(err).NotTo(HaveOccurred())
+
+			persistor := NewEncryptedPersistor(NewDiskPersistor(configPath), []byte("my passphrase"))
+			loadedData, err := persistor.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loadedData.AccessToken).To(Equal("bearer the-access-token"))
+
+			err = persistor.Save(loadedData)
+			Expect(err).NotTo(HaveOccurred())
+
+			fileContents, err := ioutil.ReadFile(configPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fileContents)).NotTo(ContainSubstring("the-access-token"))
+		})
+	})
+
+	Describe("ConfigKeyFromEnv", func() {
+		AfterEach(func() {
+			os.Unsetenv(CF_CONFIG_KEY)
+			os.Unsetenv(CF_CONFIG_KEY_FILE)
+		})
+
+		It("returns nothing when neither CF_CONFIG_KEY nor CF_CONFIG_KEY_FILE is set", func() {
+			key, err := ConfigKeyFromEnv()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(BeNil())
+		})
+
+		It("uses the value as the passphrase", func() {
+			os.Setenv(CF_CONFIG_KEY, "my passphrase")
+			key, err := ConfigKeyFromEnv()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(key)).To(Equal("my passphrase"))
+		})
+
+		It("reads the passphrase from a key file", func() {
+			fileutils.TempDir("config-key", func(dir string, err error) {
+				keyFile := filepath.Join(dir, "config.key")
+				err = ioutil.WriteFile(keyFile, []byte("key from file\n"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv(CF_CONFIG_KEY_FILE, keyFile)
+				key, err := ConfigKeyFromEnv()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(key)).To(Equal("key from file"))
+			})
+		})
+
+		It("uses CF_CONFIG_KEY as the passphrase even when it names an existing file", func() {
+			fileutils.TempDir("config-key", func(dir string, err error) {
+				keyFile := filepath.Join(dir, "config.key")
+				err = ioutil.WriteFile(keyFile, []byte("key from file\n"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv(CF_CONFIG_KEY, keyFile)
+				key, err := ConfigKeyFromEnv()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(key)).To(Equal(keyFile))
+			})
+		})
+
+		It("fails when the key file cannot be read", func() {
+			os.Setenv(CF_CONFIG_KEY_FILE, "/does/not/exist/config.key")
+			_, err := ConfigKeyFromEnv()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("/does/not/exist/config.key"))
+		})
+
+		It("fails when both CF_CONFIG_KEY and CF_CONFIG_KEY_FILE are set", func() {
+			os.Setenv(CF_CONFIG_KEY, "my passphrase")
+			os.Setenv(CF_CONFIG_KEY_FILE, "config.key")
+			_, err := ConfigKeyFromEnv()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
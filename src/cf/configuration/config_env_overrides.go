@@ -0,0 +1,103 @@
+package configuration
+
+import (
+	"cf/models"
+	"os"
+)
+
+const (
+	CF_API      = "CF_API"
+	CF_USERNAME = "CF_USERNAME"
+	CF_PASSWORD = "CF_PASSWORD"
+	CF_ORG      = "CF_ORG"
+	CF_SPACE    = "CF_SPACE"
+)
+
+// EnvOverrides are settings taken from the environment instead of the config
+// file, so commands can run without 'cf api', 'cf login' and 'cf target'. The
+// session they lead to is kept in memory only.
+type EnvOverrides struct {
+	ApiEndpoint  string
+	Username     string
+	Password     string
+	ClientId     string
+	ClientSecret string
+	OrgName      string
+	SpaceName    string
+}
+
+func EnvOverridesFromEnv() EnvOverrides {
+	return EnvOverrides{
+		ApiEndpoint:  os.Getenv(CF_API),
+		Username:     os.Getenv(CF_USERNAME),
+		Password:     os.Getenv(CF_PASSWORD),
+		ClientId:     os.Getenv(CF_CLIENT_ID),
+		ClientSecret: os.Getenv(CF_CLIENT_SECRET),
+		OrgName:      os.Getenv(CF_ORG),
+		SpaceName:    os.Getenv(CF_SPACE),
+	}
+}
+
+func (overrides EnvOverrides) IsSet() bool {
+	return overrides != EnvOverrides{}
+}
+
+func (overrides EnvOverrides) HasUserCredentials() bool {
+	return overrides.Username != "" && overrides.Password != ""
+}
+
+func (overrides EnvOverrides) HasClientCredentials() bool {
+	return overrides.ClientId != "" && overrides.ClientSecret != ""
+}
+
+// apply lays the overrides over the profile in use. A different API endpoint
+// starts from an empty profile, and credentials replace the saved session so
+// the command authenticates as the given user or client.
+func (overrides EnvOverrides) apply(data *Data) {
+	if overrides.ApiEndpoint != "" && overrides.ApiEndpoint != data.Target {
+		data.Profile = Profile{Target: overrides.ApiEndpoint}
+	}
+
+	if overrides.HasUserCredentials() || overrides.HasClientCredentials() {
+		data.AccessToken = ""
+		data.RefreshToken = ""
+		data.ClientId = ""
+		data.ClientSecret = ""
+	}
+
+	if overrides.OrgName != "" && overrides.OrgName != data.OrganizationFields.Name {
+		data.OrganizationFields = models.OrganizationFields{Name: overrides.OrgName}
+		data.SpaceFields = models.SpaceFields{}
+	}
+
+	if overrides.SpaceName != "" && overrides.SpaceName != data.SpaceFields.Name {
+		data.SpaceFields = models.SpaceFields{Name: overrides.SpaceName}
+	}
+}
+
+// restore puts the saved values back into the fields of a profile that the
+// overrides set, before it is saved. A profile targeting an overridden API
+// endpoint belongs to that endpoint and is not saved at all. The tokens are
+// restored too when the session was started with env credentials.
+func (overrides EnvOverrides) restore(profile *Profile, saved Profile, envSession bool) {
+	if overrides.ApiEndpoint != "" && profile.Target != saved.Target {
+		*profile = saved
+		return
+	}
+
+	if envSession {
+		profile.AccessToken = saved.AccessToken
+		profile.RefreshToken = saved.RefreshToken
+		profile.ClientId = saved.ClientId
+		profile.ClientSecret = saved.ClientSecret
+	}
+
+	if overrides.OrgName != "" {
+		profile.OrganizationFields = saved.OrganizationFields
+		profile.SpaceFields = saved.SpaceFields
+	}
+
+	if overrides.SpaceName != "" {
+		profile.SpaceFields = saved.SpaceFields
+	}
+}
@@ -0,0 +1,19 @@
+//go:build aix || solaris
+
+package configuration
+
+import (
+	"os"
+	"syscall"
+)
+
+// AIX, Solaris and illumos have no flock, so these take an fcntl lock on the
+// whole file instead. fcntl locks are held per process, which still keeps
+// other cf processes out.
+func lockFile(file *os.File) error {
+	return syscall.FcntlFlock(file.Fd(), syscall.F_SETLKW, &syscall.Flock_t{Type: syscall.F_WRLCK})
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.FcntlFlock(file.Fd(), syscall.F_SETLK, &syscall.Flock_t{Type: syscall.F_UNLCK})
+}
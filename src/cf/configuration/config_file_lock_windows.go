@@ -0,0 +1,32 @@
+//go:build windows
+
+package configuration
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// see LockFileEx documentation for bit flags
+// http://msdn.microsoft.com/en-us/library/windows/desktop/aa365203(v=vs.85).aspx
+const LOCKFILE_EXCLUSIVE_LOCK = 0x00000002
+
+func lockFile(file *os.File) error {
+	return callKernel32("LockFileEx", uintptr(file.Fd()), LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, uintptr(unsafe.Pointer(new(syscall.Overlapped))))
+}
+
+func unlockFile(file *os.File) error {
+	return callKernel32("UnlockFileEx", uintptr(file.Fd()), 0, 1, 0, uintptr(unsafe.Pointer(new(syscall.Overlapped))))
+}
+
+func callKernel32(name string, args ...uintptr) (err error) {
+	dll := syscall.MustLoadDLL("kernel32")
+	proc := dll.MustFindProc(name)
+	r, _, err := proc.Call(args...)
+
+	if r == 0 {
+		return err
+	}
+	return nil
+}
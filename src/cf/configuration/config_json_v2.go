@@ -15,6 +15,9 @@ type configJsonV2 struct {
 	RefreshToken          string
 	OrganizationFields    models.OrganizationFields
 	SpaceFields           models.SpaceFields
+	SSLDisabled           bool
+	CACertFile            string
+	Proxy                 string
 }
 
 func JsonMarshalV2(config *Data) (output []byte, err error) {
@@ -28,6 +31,9 @@ func JsonMarshalV2(config *Data) (output []byte, err error) {
 		RefreshToken:          config.RefreshToken,
 		OrganizationFields:    config.OrganizationFields,
 		SpaceFields:           config.SpaceFields,
+		SSLDisabled:           config.SSLDisabled,
+		CACertFile:            config.CACertFile,
+		Proxy:                 config.Proxy,
 	})
 }
 
@@ -51,6 +57,9 @@ func JsonUnmarshalV2(input []byte, config *Data) (err error) {
 	config.OrganizationFields = configJson.OrganizationFields
 	config.LoggregatorEndPoint = configJson.LoggregatorEndpoint
 	config.AuthorizationEndpoint = configJson.AuthorizationEndpoint
+	config.SSLDisabled = configJson.SSLDisabled
+	config.CACertFile = configJson.CACertFile
+	config.Proxy = configJson.Proxy
 
 	return
 }
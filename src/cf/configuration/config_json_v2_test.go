@@ -29,7 +29,10 @@ var _ = Describe("Parsing V2 config files", func() {
 				},
 				"SpaceFields": {
 					"Name": "the-space"
-				}
+				},
+				"SSLDisabled": true,
+				"CACertFile": "/path/to/ca.pem",
+				"Proxy": "socks5://proxy.example.com:1080"
 			}`)
 
 		It("returns a populated config object", func() {
@@ -38,14 +41,22 @@ var _ = Describe("Parsing V2 config files", func() {
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(configData).To(Equal(&Data{
-				Target:                "api.example.com",
-				ApiVersion:            "2",
-				AuthorizationEndpoint: "auth.example.com",
-				LoggregatorEndPoint:   "logs.example.com",
-				AccessToken:           "the-access-token",
-				RefreshToken:          "the-refresh-token",
-				OrganizationFields:    models.OrganizationFields{Name: "the-org"},
-				SpaceFields:           models.SpaceFields{Name: "the-space"},
+				Profile: Profile{
+					Target:                "api.example.com",
+					ApiVersion:            "2",
+					AuthorizationEndpoint: "auth.example.com",
+					LoggregatorEndPoint:   "logs.example.com",
+					AccessToken:           "the-access-token",
+					RefreshToken:          "the-refresh-token",
+					OrganizationFields:    models.OrganizationFields{Name: "the-org"},
+					SpaceFields:           models.SpaceFields{Name: "the-space"},
+					SSLDisabled:           true,
+					CACertFile:            "/path/to/ca.pem",
+				},
+				ActiveProfile:  DEFAULT_PROFILE,
+				DefaultProfile: DEFAULT_PROFILE,
+				Profiles:       map[string]Profile{},
+				Proxy:          "socks5://proxy.example.com:1080",
 			}))
 		})
 	})
@@ -0,0 +1,57 @@
+package configuration
+
+import (
+	"encoding/json"
+)
+
+type configJsonV3 struct {
+	ConfigVersion  int
+	CurrentProfile string
+	Profiles       map[string]Profile
+	Proxy          string
+}
+
+func JsonMarshalV3(config *Data) (output []byte, err error) {
+	profiles := map[string]Profile{}
+	for name, profile := range config.Profiles {
+		profiles[name] = profile
+	}
+	profiles[config.ActiveProfile] = config.Profile
+
+	return json.Marshal(configJsonV3{
+		ConfigVersion:  3,
+		CurrentProfile: config.DefaultProfile,
+		Profiles:       profiles,
+		Proxy:          config.Proxy,
+	})
+}
+
+func JsonUnmarshalV3(input []byte, config *Data) (err error) {
+	configJson := new(configJsonV3)
+
+	err = json.Unmarshal(input, configJson)
+	if err != nil {
+		return
+	}
+
+	if configJson.ConfigVersion != 3 {
+		return
+	}
+
+	config.DefaultProfile = configJson.CurrentProfile
+	if config.DefaultProfile == "" {
+		config.DefaultProfile = DEFAULT_PROFILE
+	}
+
+	config.Profiles = map[string]Profile{}
+	for name, profile := range configJson.Profiles {
+		config.Profiles[name] = profile
+	}
+
+	config.ActiveProfile = config.DefaultProfile
+	config.Profile = config.Profiles[config.ActiveProfile]
+	delete(config.Profiles, config.ActiveProfile)
+
+	config.Proxy = configJson.Proxy
+	return
+}
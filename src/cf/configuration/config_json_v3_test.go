@@ -0,0 +1,75 @@
+package configuration_test
+
+import (
+	. "cf/configuration"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("V3 config files", func() {
+	var json = []byte(`
+		{
+			"ConfigVersion": 3,
+			"CurrentProfile": "prod",
+			"Profiles": {
+				"prod": {
+					"Target": "api.prod.example.com",
+					"LoggregatorEndpoint": "logs.prod.example.com",
+					"AccessToken": "the-prod-token",
+					"OrganizationFields": {
+						"Name": "the-org"
+					}
+				},
+				"staging": {
+					"Target": "api.staging.example.com",
+					"AccessToken": "the-staging-token"
+				}
+			},
+			"Proxy": "socks5://proxy.example.com:1080"
+		}`)
+
+	It("uses the current profile and keeps the others", func() {
+		configData := NewData()
+		err := JsonUnmarshalV3(json, configData)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configData).To(Equal(&Data{
+			Profile: Profile{
+				Target:              "api.prod.example.com",
+				LoggregatorEndPoint: "logs.prod.example.com",
+				AccessToken:         "the-prod-token",
+				OrganizationFields:  models.OrganizationFields{Name: "the-org"},
+			},
+			ActiveProfile:  "prod",
+			DefaultProfile: "prod",
+			Profiles: map[string]Profile{
+				"staging": Profile{
+					Target:      "api.staging.example.com",
+					AccessToken: "the-staging-token",
+				},
+			},
+			Proxy: "socks5://proxy.example.com:1080",
+		}))
+	})
+
+	It("writes the profile in use under its name", func() {
+		configData := NewData()
+		err := JsonUnmarshalV3(json, configData)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = configData.SwitchProfile("staging")
+		Expect(err).NotTo(HaveOccurred())
+		configData.Target = "api.new-staging.example.com"
+
+		output, err := JsonMarshalV3(configData)
+		Expect(err).NotTo(HaveOccurred())
+
+		reloaded := NewData()
+		err = JsonUnmarshalV3(output, reloaded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded.ActiveProfile).To(Equal("prod"))
+		Expect(reloaded.Target).To(Equal("api.prod.example.com"))
+		Expect(reloaded.Profiles["staging"].Target).To(Equal("api.new-staging.example.com"))
+	})
+})
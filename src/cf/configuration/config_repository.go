@@ -2,19 +2,34 @@ package configuration
 
 import (
 	"cf/models"
+	"os"
 	"sync"
 )
 
 type configRepository struct {
-	data      *Data
-	mutex     *sync.RWMutex
-	initOnce  *sync.Once
-	persistor Persistor
-	onError   func(error)
-}
-
+	data       *Data
+	mutex      *sync.RWMutex
+	initOnce   *sync.Once
+	persistor  Persistor
+	saved      *Data
+	overrides  EnvOverrides
+	envSession bool
+	onError    func(error)
+}
+
+// NewRepositoryFromFilepath encrypts the tokens in the file when a key is set
+// with CF_CONFIG_KEY or CF_CONFIG_KEY_FILE.
 func NewRepositoryFromFilepath(filepath string, errorHandler func(error)) Repository {
-	return NewRepositoryFromPersistor(NewDiskPersistor(filepath), errorHandler)
+	var persistor Persistor = NewDiskPersistor(filepath)
+
+	key, err := ConfigKeyFromEnv()
+	if err != nil {
+		errorHandler(err)
+	} else if key != nil {
+		persistor = NewEncryptedPersistor(persistor, key)
+	}
+
+	return NewRepositoryFromPersistor(persistor, errorHandler)
 }
 
 func NewRepositoryFromPersistor(persistor Persistor, errorHandler func(error)) Repository {
@@ -30,11 +45,21 @@ type Reader interface {
 	ApiEndpoint() string
 	ApiVersion() string
 	AuthorizationEndpoint() string
+	TokenEndpoint() string
 	LoggregatorEndpoint() string
 	AccessToken() string
 	RefreshToken() string
+	ClientId() string
+	ClientSecret() string
 	OrganizationFields() models.OrganizationFields
 	SpaceFields() models.SpaceFields
+	IsSSLDisabled() bool
+	CACertFile() string
+	Proxy() string
+	EnvOverrides() EnvOverrides
+	ProfileName() string
+	ProfileNames() []string
+	ProfileByName(string) (Profile, bool)
 
 	HasSpace() bool
 	HasOrganization() bool
@@ -50,11 +75,19 @@ type ReadWriter interface {
 	SetApiEndpoint(string)
 	SetApiVersion(string)
 	SetAuthorizationEndpoint(string)
+	SetTokenEndpoint(string)
 	SetLoggregatorEndpoint(string)
 	SetAccessToken(string)
 	SetRefreshToken(string)
+	SetClientCredentials(string, string)
 	SetOrganizationFields(models.OrganizationFields)
 	SetSpaceFields(models.SpaceFields)
+	SetSSLDisabled(bool)
+	SetCACertFile(string)
+	SetProxy(string)
+	UseEnvSession()
+	CreateProfile(string) error
+	UseProfile(string) error
 }
 
 type Repository interface {
@@ -65,36 +98,102 @@ type Repository interface {
 // ACCESS CONTROL
 
 func (c *configRepository) init() {
-	c.initOnce.Do(func() {
-		var err error
-		c.data, err = c.persistor.Load()
+	c.initOnce.Do(c.load)
+}
+
+func (c *configRepository) load() {
+	c.data = c.loadSaved()
+	c.saved = c.data.copy()
+
+	c.overrides = EnvOverridesFromEnv()
+	c.overrides.apply(c.data)
+}
+
+// loadSaved reads the config file as it is, without the env overrides.
+func (c *configRepository) loadSaved() (data *Data) {
+	data, err := c.persistor.Load()
+	if err != nil {
+		c.onError(err)
+	}
+
+	// CF_PROFILE picks the profile for this command only, the default
+	// profile stored in the config file stays unchanged
+	profile := os.Getenv(CF_PROFILE)
+	if profile != "" {
+		err = data.SwitchProfile(profile)
 		if err != nil {
 			c.onError(err)
 		}
-	})
+	}
+	return
 }
 
+// read uses the config loaded when the command started, and does not look at
+// the file again. Changes saved by other cf processes, such as a token they
+// refreshed, are picked up by the next write, under the persistor lock.
 func (c *configRepository) read(cb func()) {
 	c.mutex.RLock()
-	defer c.mutex.RUnlock()
 	c.init()
+	defer c.mutex.RUnlock()
 
 	cb()
 }
 
+// write holds the persistor lock from reloading the config to saving it, so
+// changes made by concurrent cf processes are not lost. While env overrides
+// are set the config in memory is kept and only the saved values of the
+// overridden fields are reloaded, so they never end up in the file.
 func (c *configRepository) write(cb func()) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.init()
 
+	err := c.persistor.Lock()
+	if err != nil {
+		c.onError(err)
+	} else {
+		defer c.persistor.Unlock()
+	}
+
+	if c.persistor.Changed() {
+		if c.overrides.IsSet() {
+			c.saved = c.loadSaved()
+		} else {
+			c.load()
+		}
+	}
+
 	cb()
 
-	err := c.persistor.Save(c.data)
+	err = c.persistor.Save(c.dataToSave())
 	if err != nil {
 		c.onError(err)
 	}
 }
 
+// dataToSave puts back the saved values of the fields set by env overrides,
+// and of the tokens of a session started with env credentials, into the
+// profile the overrides were applied to.
+func (c *configRepository) dataToSave() (data *Data) {
+	data = c.data.copy()
+	if !c.overrides.IsSet() {
+		return
+	}
+
+	name := c.saved.ActiveProfile
+	if data.ActiveProfile == name {
+		c.overrides.restore(&data.Profile, c.saved.Profile, c.envSession)
+		return
+	}
+
+	profile, found := data.Profiles[name]
+	if found {
+		c.overrides.restore(&profile, c.saved.Profile, c.envSession)
+		data.Profiles[name] = profile
+	}
+	return
+}
+
 // CLOSERS
 
 func (c *configRepository) Close() {
@@ -119,6 +218,13 @@ func (c *configRepository) AuthorizationEndpoint() (authEndpoint string) {
 	return
 }
 
+func (c *configRepository) TokenEndpoint() (tokenEndpoint string) {
+	c.read(func() {
+		tokenEndpoint = c.data.TokenEndpoint
+	})
+	return
+}
+
 func (c *configRepository) LoggregatorEndpoint() (logEndpoint string) {
 	c.read(func() {
 		logEndpoint = c.data.LoggregatorEndPoint
@@ -147,6 +253,20 @@ func (c *configRepository) RefreshToken() (refreshToken string) {
 	return
 }
 
+func (c *configRepository) ClientId() (clientId string) {
+	c.read(func() {
+		clientId = c.data.ClientId
+	})
+	return
+}
+
+func (c *configRepository) ClientSecret() (clientSecret string) {
+	c.read(func() {
+		clientSecret = c.data.ClientSecret
+	})
+	return
+}
+
 func (c *configRepository) OrganizationFields() (org models.OrganizationFields) {
 	c.read(func() {
 		org = c.data.OrganizationFields
@@ -161,6 +281,59 @@ func (c *configRepository) SpaceFields() (space models.SpaceFields) {
 	return
 }
 
+func (c *configRepository) IsSSLDisabled() (isSSLDisabled bool) {
+	c.read(func() {
+		isSSLDisabled = c.data.SSLDisabled
+	})
+	return
+}
+
+func (c *configRepository) CACertFile() (path string) {
+	c.read(func() {
+		path = c.data.CACertFile
+	})
+	return
+}
+
+func (c *configRepository) Proxy() (proxy string) {
+	c.read(func() {
+		proxy = c.data.Proxy
+	})
+	return
+}
+
+func (c *configRepository) EnvOverrides() (overrides EnvOverrides) {
+	c.read(func() {
+		overrides = c.overrides
+	})
+	return
+}
+
+func (c *configRepository) ProfileName() (name string) {
+	c.read(func() {
+		name = c.data.ActiveProfile
+	})
+	return
+}
+
+func (c *configRepository) ProfileNames() (names []string) {
+	c.read(func() {
+		names = c.data.ProfileNames()
+	})
+	return
+}
+
+func (c *configRepository) ProfileByName(name string) (profile Profile, found bool) {
+	c.read(func() {
+		if name == c.data.ActiveProfile {
+			profile, found = c.data.Profile, true
+			return
+		}
+		profile, found = c.data.Profiles[name]
+	})
+	return
+}
+
 func (c *configRepository) UserEmail() (email string) {
 	c.read(func() {
 		email = NewTokenInfo(c.data.AccessToken).Email
@@ -175,9 +348,15 @@ func (c *configRepository) UserGuid() (guid string) {
 	return
 }
 
+// Username is the client id for tokens obtained with client credentials,
+// which do not belong to a user.
 func (c *configRepository) Username() (name string) {
 	c.read(func() {
-		name = NewTokenInfo(c.data.AccessToken).Username
+		info := NewTokenInfo(c.data.AccessToken)
+		name = info.Username
+		if name == "" {
+			name = info.ClientId
+		}
 	})
 	return
 }
@@ -207,13 +386,27 @@ func (c *configRepository) HasSpace() (hasSpace bool) {
 
 func (c *configRepository) ClearSession() {
 	c.write(func() {
-		c.data.AccessToken = ""
-		c.data.RefreshToken = ""
-		c.data.OrganizationFields = models.OrganizationFields{}
-		c.data.SpaceFields = models.SpaceFields{}
+		c.data.clearSession()
+		c.saved.clearSession()
+
+		err := c.persistor.Wipe()
+		if err != nil {
+			c.onError(err)
+		}
 	})
 }
 
+// UseEnvSession keeps the tokens of the session started with the credentials
+// from CF_USERNAME and CF_PASSWORD, or CF_CLIENT_ID and CF_CLIENT_SECRET, out
+// of the config file.
+func (c *configRepository) UseEnvSession() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.init()
+
+	c.envSession = true
+}
+
 func (c *configRepository) SetApiEndpoint(endpoint string) {
 	c.write(func() {
 		c.data.Target = endpoint
@@ -232,6 +425,12 @@ func (c *configRepository) SetAuthorizationEndpoint(endpoint string) {
 	})
 }
 
+func (c *configRepository) SetTokenEndpoint(endpoint string) {
+	c.write(func() {
+		c.data.TokenEndpoint = endpoint
+	})
+}
+
 func (c *configRepository) SetLoggregatorEndpoint(endpoint string) {
 	c.write(func() {
 		c.data.LoggregatorEndPoint = endpoint
@@ -250,6 +449,13 @@ func (c *configRepository) SetRefreshToken(token string) {
 	})
 }
 
+func (c *configRepository) SetClientCredentials(clientId, clientSecret string) {
+	c.write(func() {
+		c.data.ClientId = clientId
+		c.data.ClientSecret = clientSecret
+	})
+}
+
 func (c *configRepository) SetOrganizationFields(org models.OrganizationFields) {
 	c.write(func() {
 		c.data.OrganizationFields = org
@@ -261,3 +467,38 @@ func (c *configRepository) SetSpaceFields(space models.SpaceFields) {
 		c.data.SpaceFields = space
 	})
 }
+
+func (c *configRepository) SetSSLDisabled(disabled bool) {
+	c.write(func() {
+		c.data.SSLDisabled = disabled
+	})
+}
+
+func (c *configRepository) SetCACertFile(path string) {
+	c.write(func() {
+		c.data.CACertFile = path
+	})
+}
+
+func (c *configRepository) SetProxy(proxy string) {
+	c.write(func() {
+		c.data.Proxy = proxy
+	})
+}
+
+func (c *configRepository) CreateProfile(name string) (err error) {
+	c.write(func() {
+		err = c.data.CreateProfile(name)
+	})
+	return
+}
+
+func (c *configRepository) UseProfile(name string) (err error) {
+	c.write(func() {
+		err = c.data.SwitchProfile(name)
+		if err == nil {
+			c.data.DefaultProfile = name
+		}
+	})
+	return
+}
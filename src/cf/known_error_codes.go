@@ -1,13 +1,19 @@
 package cf
 
+// Cloud Controller error codes handled by commands. They are compared against
+// net.HttpError.ErrorCode (also exposed as ApiResponse.ErrorCode).
 const (
+	INVALID_AUTH_TOKEN          = "1000"
 	BAD_QUERY_PARAM             = "10005"
 	USER_EXISTS                 = "20002"
 	USER_NOT_FOUND              = "20003"
 	ORG_EXISTS                  = "30002"
 	SPACE_EXISTS                = "40002"
 	SERVICE_INSTANCE_NAME_TAKEN = "60002"
+	APP_ALREADY_BOUND           = "90003"
+	DOMAIN_NAME_TAKEN           = "130003"
 	APP_NOT_STAGED              = "170002"
+	ROUTE_HOST_TAKEN            = "210003"
 	APP_STOPPED                 = "220001"
 	BUILDPACK_EXISTS            = "290001"
 )
@@ -11,8 +11,11 @@ import (
 	"strconv"
 )
 
+// Manifest holds the apps of a manifest. Warnings lists the keys found in
+// the manifest files that cf does not know.
 type Manifest struct {
 	Applications []models.AppParams
+	Warnings     ManifestErrors
 }
 
 func NewEmptyManifest() (m *Manifest) {
@@ -31,6 +34,15 @@ func NewManifest(basePath string, data generic.Map) (m *Manifest, errs ManifestE
 	return
 }
 
+// Data is the manifest in the form it takes in a manifest file.
+func (m *Manifest) Data() map[string]interface{} {
+	apps := []interface{}{}
+	for _, app := range m.Applications {
+		apps = append(apps, appParamsToMap(app))
+	}
+	return map[string]interface{}{"applications": apps}
+}
+
 func walkManifestLookingForProperties(data generic.Map) (errs ManifestErrors) {
 	generic.Each(data, func(key, value interface{}) {
 		errs = append(errs, walkMapLookingForProperties(value)...)
@@ -102,7 +114,10 @@ func mapToAppParams(basePath string, yamlMap generic.Map) (appParams models.AppP
 	appParams.BuildpackUrl = stringVal(yamlMap, "buildpack", &errs)
 	appParams.DiskQuota = bytesVal(yamlMap, "disk_quota", &errs)
 	appParams.Domain = stringVal(yamlMap, "domain", &errs)
+	appParams.Domains = sliceVal(yamlMap, "domains", &errs)
 	appParams.Host = stringVal(yamlMap, "host", &errs)
+	appParams.Hosts = sliceVal(yamlMap, "hosts", &errs)
+	appParams.Routes = sliceVal(yamlMap, "routes", &errs)
 	appParams.Name = stringVal(yamlMap, "name", &errs)
 	appParams.Path = stringVal(yamlMap, "path", &errs)
 	appParams.StackName = stringVal(yamlMap, "stack", &errs)
@@ -111,7 +126,7 @@ func mapToAppParams(basePath string, yamlMap generic.Map) (appParams models.AppP
 	appParams.InstanceCount = intVal(yamlMap, "instances", &errs)
 	appParams.HealthCheckTimeout = intVal(yamlMap, "timeout", &errs)
 	appParams.NoRoute = boolVal(yamlMap, "no-route", &errs)
-	appParams.Services = sliceOrEmptyVal(yamlMap, "services", &errs)
+	appParams.Services, appParams.ServiceInstances = servicesVal(yamlMap, &errs)
 	appParams.EnvironmentVars = envVarOrEmptyMap(yamlMap, &errs)
 
 	if appParams.Path != nil {
@@ -127,6 +142,90 @@ func mapToAppParams(basePath string, yamlMap generic.Map) (appParams models.AppP
 	return
 }
 
+func appParamsToMap(appParams models.AppParams) (yamlMap map[string]interface{}) {
+	yamlMap = map[string]interface{}{}
+
+	if appParams.Name != nil {
+		yamlMap["name"] = *appParams.Name
+	}
+	if appParams.BuildpackUrl != nil {
+		yamlMap["buildpack"] = *appParams.BuildpackUrl
+	}
+	if appParams.Command != nil {
+		yamlMap["command"] = *appParams.Command
+	}
+	if appParams.DiskQuota != nil {
+		yamlMap["disk_quota"] = fmt.Sprintf("%dM", *appParams.DiskQuota)
+	}
+	if appParams.Memory != nil {
+		yamlMap["memory"] = fmt.Sprintf("%dM", *appParams.Memory)
+	}
+	if appParams.InstanceCount != nil {
+		yamlMap["instances"] = *appParams.InstanceCount
+	}
+	if appParams.HealthCheckTimeout != nil {
+		yamlMap["timeout"] = *appParams.HealthCheckTimeout
+	}
+	if appParams.StackName != nil {
+		yamlMap["stack"] = *appParams.StackName
+	}
+	if appParams.Path != nil {
+		yamlMap["path"] = *appParams.Path
+	}
+	if appParams.Host != nil {
+		yamlMap["host"] = *appParams.Host
+	}
+	if appParams.Domain != nil {
+		yamlMap["domain"] = *appParams.Domain
+	}
+	if appParams.Hosts != nil {
+		yamlMap["hosts"] = *appParams.Hosts
+	}
+	if appParams.Domains != nil {
+		yamlMap["domains"] = *appParams.Domains
+	}
+	if appParams.Routes != nil {
+		yamlMap["routes"] = *appParams.Routes
+	}
+	if appParams.NoRoute != nil && *appParams.NoRoute {
+		yamlMap["no-route"] = true
+	}
+	if appParams.Services != nil && len(*appParams.Services) > 0 {
+		yamlMap["services"] = servicesToList(appParams)
+	}
+	if appParams.EnvironmentVars != nil && len(*appParams.EnvironmentVars) > 0 {
+		yamlMap["env"] = *appParams.EnvironmentVars
+	}
+	return
+}
+
+func servicesToList(appParams models.AppParams) (services []interface{}) {
+	for _, name := range *appParams.Services {
+		params, found := appParams.FindServiceInstanceParams(name)
+		if !found {
+			services = append(services, name)
+			continue
+		}
+
+		serviceMap := map[string]interface{}{"name": params.Name}
+		if params.Label != "" {
+			serviceMap["label"] = params.Label
+			serviceMap["plan"] = params.Plan
+		}
+		if params.Provider != "" {
+			serviceMap["provider"] = params.Provider
+		}
+		if params.Credentials != nil {
+			serviceMap["credentials"] = params.Credentials
+		}
+		if params.SysLogDrainUrl != "" {
+			serviceMap["syslog_drain_url"] = params.SysLogDrainUrl
+		}
+		services = append(services, serviceMap)
+	}
+	return
+}
+
 func checkForNulls(yamlMap generic.Map) (errs ManifestErrors) {
 	generic.Each(yamlMap, func(key interface{}, value interface{}) {
 		if key == "command" {
@@ -174,7 +273,12 @@ func bytesVal(yamlMap generic.Map, key string, errs *ManifestErrors) *uint64 {
 	if yamlVal == nil {
 		return nil
 	}
-	value, err := formatters.ToMegabytes(yamlVal.(string))
+	stringVal, ok := yamlVal.(string)
+	if !ok {
+		*errs = append(*errs, errors.New(fmt.Sprintf("%s must be a string value, such as 256M or 1G", key)))
+		return nil
+	}
+	value, err := formatters.ToMegabytes(stringVal)
 	if err != nil {
 		*errs = append(*errs, errors.New(fmt.Sprintf("Unexpected value for %s :\n%s", key, err.Error())))
 		return nil
@@ -222,9 +326,9 @@ func boolVal(yamlMap generic.Map, key string, errs *ManifestErrors) *bool {
 	}
 }
 
-func sliceOrEmptyVal(yamlMap generic.Map, key string, errs *ManifestErrors) *[]string {
+func sliceVal(yamlMap generic.Map, key string, errs *ManifestErrors) *[]string {
 	if !yamlMap.Has(key) {
-		return new([]string)
+		return nil
 	}
 
 	var (
@@ -256,6 +360,109 @@ func sliceOrEmptyVal(yamlMap generic.Map, key string, errs *ManifestErrors) *[]s
 	return &stringSlice
 }
 
+var serviceKeys = []string{"name", "label", "provider", "plan", "credentials", "syslog_drain_url"}
+
+// servicesVal reads the services of an app. Each one is either the name of a
+// service instance, or a map that also says how to create the instance.
+func servicesVal(yamlMap generic.Map, errs *ManifestErrors) (names *[]string, instances *[]models.ServiceInstanceParams) {
+	names = new([]string)
+	if !yamlMap.Has("services") {
+		return
+	}
+
+	entries, ok := yamlMap.Get("services").([]interface{})
+	if !ok {
+		*errs = append(*errs, errors.New("Expected services to be a list of service names or services."))
+		names = nil
+		return
+	}
+
+	for _, entry := range entries {
+		if name, ok := entry.(string); ok {
+			*names = append(*names, name)
+			continue
+		}
+
+		if !generic.IsMappable(entry) {
+			*errs = append(*errs, errors.New("Expected services to be a list of service names or services."))
+			continue
+		}
+
+		params, err := serviceInstanceParams(generic.NewMap(entry))
+		if err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+
+		*names = append(*names, params.Name)
+		if params.Label == "" && params.Credentials == nil && params.SysLogDrainUrl == "" {
+			continue
+		}
+
+		if instances == nil {
+			instances = new([]models.ServiceInstanceParams)
+		}
+		*instances = append(*instances, params)
+	}
+	return
+}
+
+func serviceInstanceParams(serviceMap generic.Map) (params models.ServiceInstanceParams, err error) {
+	for _, key := range serviceMap.Keys() {
+		if !containsString(serviceKeys, fmt.Sprintf("%v", key)) {
+			err = errors.New(fmt.Sprintf("Unknown key '%v' in service", key))
+			return
+		}
+	}
+
+	var ok bool
+	stringFields := map[string]*string{
+		"name":             &params.Name,
+		"label":            &params.Label,
+		"provider":         &params.Provider,
+		"plan":             &params.Plan,
+		"syslog_drain_url": &params.SysLogDrainUrl,
+	}
+	for key, field := range stringFields {
+		if !serviceMap.Has(key) {
+			continue
+		}
+		*field, ok = serviceMap.Get(key).(string)
+		if !ok {
+			err = errors.New(fmt.Sprintf("Expected %s of service to be a string.", key))
+			return
+		}
+	}
+
+	if params.Name == "" {
+		err = errors.New("Expected service to have a name.")
+		return
+	}
+
+	if serviceMap.Has("credentials") {
+		credentials := serviceMap.Get("credentials")
+		if !generic.IsMappable(credentials) {
+			err = errors.New(fmt.Sprintf("Expected credentials of service %s to be a set of key => value.", params.Name))
+			return
+		}
+
+		params.Credentials = map[string]string{}
+		generic.Each(generic.NewMap(credentials), func(key, value interface{}) {
+			params.Credentials[fmt.Sprintf("%v", key)] = fmt.Sprintf("%v", value)
+		})
+	}
+
+	switch {
+	case params.Label != "" && params.Plan == "", params.Label == "" && params.Plan != "":
+		err = errors.New(fmt.Sprintf("Service %s needs both a label and a plan to be created.", params.Name))
+	case params.Label != "" && (params.Credentials != nil || params.SysLogDrainUrl != ""):
+		err = errors.New(fmt.Sprintf("Service %s cannot have both a plan and user provided credentials.", params.Name))
+	case params.Provider != "" && params.Label == "":
+		err = errors.New(fmt.Sprintf("Service %s needs a label and a plan to go with its provider.", params.Name))
+	}
+	return
+}
+
 func envVarOrEmptyMap(yamlMap generic.Map, errs *ManifestErrors) *map[string]string {
 	key := "env"
 	switch envVars := yamlMap.Get(key).(type) {
@@ -274,7 +481,7 @@ func envVarOrEmptyMap(yamlMap generic.Map, errs *ManifestErrors) *map[string]str
 
 		result := make(map[string]string, envVars.Count())
 		generic.Each(envVars, func(key, value interface{}) {
-			result[key.(string)] = value.(string)
+			result[fmt.Sprintf("%v", key)] = fmt.Sprintf("%v", value)
 		})
 		return &result
 	default:
@@ -283,11 +490,24 @@ func envVarOrEmptyMap(yamlMap generic.Map, errs *ManifestErrors) *map[string]str
 	}
 }
 
+// validateEnvVars accepts numbers and booleans as well as strings, since a
+// variable such as ((port)) may come from a vars file as a number.
 func validateEnvVars(input generic.Map) (errs ManifestErrors) {
 	generic.Each(input, func(key, value interface{}) {
-		if value == nil {
-			errs = append(errs, errors.New(fmt.Sprintf("env var '%s' should not be null", key)))
+		err := validateEnvVar(key, value)
+		if err != nil {
+			errs = append(errs, err)
 		}
 	})
 	return
 }
+
+func validateEnvVar(key, value interface{}) error {
+	switch value.(type) {
+	case nil:
+		return errors.New(fmt.Sprintf("env var '%s' should not be null", key))
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}, generic.Map:
+		return errors.New(fmt.Sprintf("env var '%s' should be a single value", key))
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package manifest
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"generic"
 	"github.com/cloudfoundry/gamble"
 	"io"
@@ -11,7 +13,8 @@ import (
 )
 
 type ManifestRepository interface {
-	ReadManifest(string) (manifest *Manifest, path string, errors ManifestErrors)
+	ReadManifest(string, Variables) (manifest *Manifest, path string, errors ManifestErrors)
+	WriteManifest(manifest *Manifest, path string) (err error)
 }
 
 type ManifestDiskRepository struct{}
@@ -20,7 +23,7 @@ func NewManifestDiskRepository() (repo ManifestRepository) {
 	return ManifestDiskRepository{}
 }
 
-func (repo ManifestDiskRepository) ReadManifest(inputPath string) (m *Manifest, manifestPath string, errs ManifestErrors) {
+func (repo ManifestDiskRepository) ReadManifest(inputPath string, vars Variables) (m *Manifest, manifestPath string, errs ManifestErrors) {
 	m = NewEmptyManifest()
 
 	basePath, fileName, err := repo.manifestPath(inputPath)
@@ -31,31 +34,44 @@ func (repo ManifestDiskRepository) ReadManifest(inputPath string) (m *Manifest,
 
 	manifestPath = filepath.Join(basePath, fileName)
 
-	mapp, err := repo.readAllYAMLFiles(manifestPath)
-	if err != nil {
-		errs = append(errs, err)
+	mapp, warnings, errs := repo.readAllYAMLFiles(manifestPath, vars)
+	m.Warnings = warnings
+	if !errs.Empty() {
 		return
 	}
 
-	m, errs = NewManifest(basePath, mapp)
+	parsed, errs := NewManifest(basePath, mapp)
 	if !errs.Empty() {
 		return
 	}
 
+	m.Applications = parsed.Applications
 	return
 }
 
-func (repo ManifestDiskRepository) readAllYAMLFiles(path string) (mergedMap generic.Map, err error) {
-	file, err := os.Open(filepath.Clean(path))
+// readAllYAMLFiles reads a manifest and the manifests it inherits from. Each
+// file is interpolated and validated on its own, so errors and warnings name
+// the file they are found in.
+func (repo ManifestDiskRepository) readAllYAMLFiles(path string, vars Variables) (mergedMap generic.Map, warnings, errs ManifestErrors) {
+	yamlBytes, err := ioutil.ReadFile(filepath.Clean(path))
 	if err != nil {
+		errs = append(errs, err)
 		return
 	}
-	defer file.Close()
 
-	mapp, err := parseManifest(file)
+	mapp, err := parseManifest(bytes.NewReader(yamlBytes))
 	if err != nil {
+		errs = append(errs, errors.New(fmt.Sprintf("%s: %s", path, err)))
+		return
+	}
+
+	interpolated, errs := interpolate(mapp, vars, path)
+	if !errs.Empty() {
 		return
 	}
+	mapp = interpolated.(generic.Map)
+
+	errs, warnings = validateManifestFile(path, mapp, newManifestLines(yamlBytes))
 
 	if !mapp.Has("inherit") {
 		mergedMap = mapp
@@ -64,7 +80,6 @@ func (repo ManifestDiskRepository) readAllYAMLFiles(path string) (mergedMap gene
 
 	inheritedPath, ok := mapp.Get("inherit").(string)
 	if !ok {
-		err = errors.New("invalid inherit path in manifest")
 		return
 	}
 
@@ -72,8 +87,10 @@ func (repo ManifestDiskRepository) readAllYAMLFiles(path string) (mergedMap gene
 		inheritedPath = filepath.Join(filepath.Dir(path), inheritedPath)
 	}
 
-	inheritedMap, err := repo.readAllYAMLFiles(inheritedPath)
-	if err != nil {
+	inheritedMap, inheritedWarnings, inheritedErrs := repo.readAllYAMLFiles(inheritedPath, vars)
+	warnings = append(warnings, inheritedWarnings...)
+	errs = append(errs, inheritedErrs...)
+	if !errs.Empty() {
 		return
 	}
 
@@ -81,6 +98,22 @@ func (repo ManifestDiskRepository) readAllYAMLFiles(path string) (mergedMap gene
 	return
 }
 
+// WriteManifest writes a manifest that NewManifest reads back into the same
+// apps, leaving out the settings the apps do not have.
+func (repo ManifestDiskRepository) WriteManifest(m *Manifest, path string) (err error) {
+	yaml, err := gamble.Render(m.Data())
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error rendering manifest:\n%s", err))
+		return
+	}
+
+	err = ioutil.WriteFile(filepath.Clean(path), []byte("---\n"+yaml), 0644)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error writing manifest file %s:\n%s", path, err))
+	}
+	return
+}
+
 func parseManifest(file io.Reader) (yamlMap generic.Map, err error) {
 	yamlBytes, err := ioutil.ReadAll(file)
 	if err != nil {
@@ -2,8 +2,11 @@ package manifest_test
 
 import (
 	. "cf/manifest"
+	"cf/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"runtime"
 )
@@ -17,7 +20,7 @@ var _ = Describe("ManifestDiskRepository", func() {
 
 	Describe("given a directory containing a file called 'manifest.yml", func() {
 		It("reads that file", func() {
-			m, path, errs := repo.ReadManifest("../../fixtures/manifests")
+			m, path, errs := repo.ReadManifest("../../fixtures/manifests", NewVariables())
 
 			Expect(errs).To(BeEmpty())
 			Expect(path).To(Equal(filepath.Clean("../../fixtures/manifests/manifest.yml")))
@@ -27,7 +30,7 @@ var _ = Describe("ManifestDiskRepository", func() {
 
 	Describe("given a directory that doesn't contain a file called 'manifest.yml", func() {
 		It("returns an error", func() {
-			_, path, errs := repo.ReadManifest("../../fixtures")
+			_, path, errs := repo.ReadManifest("../../fixtures", NewVariables())
 
 			Expect(errs).NotTo(BeEmpty())
 			Expect(path).To(BeEmpty())
@@ -36,7 +39,7 @@ var _ = Describe("ManifestDiskRepository", func() {
 
 	Describe("given a path to a file", func() {
 		It("reads the file at that path", func() {
-			m, path, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml")
+			m, path, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml", NewVariables())
 
 			Expect(errs).To(BeEmpty())
 			Expect(path).To(Equal(filepath.Clean("../../fixtures/manifests/different-manifest.yml")))
@@ -44,7 +47,7 @@ var _ = Describe("ManifestDiskRepository", func() {
 		})
 
 		It("passes the base directory to the manifest file", func() {
-			m, _, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml")
+			m, _, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml", NewVariables())
 
 			Expect(errs).To(BeEmpty())
 			Expect(len(m.Applications)).To(Equal(1))
@@ -60,31 +63,31 @@ var _ = Describe("ManifestDiskRepository", func() {
 
 	Describe("given a path to a file that doesn't exist", func() {
 		It("returns an error", func() {
-			_, _, errs := repo.ReadManifest("some/path/that/doesnt/exist/manifest.yml")
+			_, _, errs := repo.ReadManifest("some/path/that/doesnt/exist/manifest.yml", NewVariables())
 			Expect(errs).NotTo(BeEmpty())
 		})
 
 		It("returns empty string for the manifest path", func() {
-			_, path, _ := repo.ReadManifest("some/path/that/doesnt/exist/manifest.yml")
+			_, path, _ := repo.ReadManifest("some/path/that/doesnt/exist/manifest.yml", NewVariables())
 			Expect(path).To(Equal(""))
 		})
 	})
 
 	Describe("when the manifest is not valid", func() {
 		It("returns an error", func() {
-			_, _, errs := repo.ReadManifest("../../fixtures/manifests/empty-manifest.yml")
+			_, _, errs := repo.ReadManifest("../../fixtures/manifests/empty-manifest.yml", NewVariables())
 			Expect(errs).NotTo(BeEmpty())
 		})
 
 		It("returns the path to the manifest", func() {
 			inputPath := filepath.Clean("../../fixtures/manifests/empty-manifest.yml")
-			_, path, _ := repo.ReadManifest(inputPath)
+			_, path, _ := repo.ReadManifest(inputPath, NewVariables())
 			Expect(path).To(Equal(inputPath))
 		})
 	})
 
 	It("converts nested maps to generic maps", func() {
-		m, _, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml")
+		m, _, errs := repo.ReadManifest("../../fixtures/manifests/different-manifest.yml", NewVariables())
 
 		Expect(errs).To(BeEmpty())
 		Expect(*m.Applications[0].EnvironmentVars).To(Equal(map[string]string{
@@ -93,7 +96,7 @@ var _ = Describe("ManifestDiskRepository", func() {
 	})
 
 	It("merges manifests with their 'inherited' manifests", func() {
-		m, _, errs := repo.ReadManifest("../../fixtures/manifests/inherited-manifest.yml")
+		m, _, errs := repo.ReadManifest("../../fixtures/manifests/inherited-manifest.yml", NewVariables())
 		Expect(errs).To(BeEmpty())
 		Expect(*m.Applications[0].Name).To(Equal("base-app"))
 		Expect(*m.Applications[0].Services).To(Equal([]string{"base-service"}))
@@ -111,4 +114,179 @@ var _ = Describe("ManifestDiskRepository", func() {
 		services := *m.Applications[1].Services
 		Expect(services).To(Equal([]string{"base-service", "foo-service"}))
 	})
+
+	It("reports the file, line and app of every invalid setting", func() {
+		m, _, errs := repo.ReadManifest("../../fixtures/manifests/invalid-manifest.yml", NewVariables())
+
+		manifestPath := filepath.Clean("../../fixtures/manifests/invalid-manifest.yml")
+		basePath := filepath.Clean("../../fixtures/manifests/invalid-base-manifest.yml")
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Error()).To(ContainSubstring(manifestPath + ":6: app my-app: Unexpected value for memory"))
+		Expect(errs[1].Error()).To(Equal(basePath + ":3: Expected no-route to be a boolean."))
+
+		Expect(m.Warnings).To(HaveLen(1))
+		Expect(m.Warnings[0].Error()).To(Equal(manifestPath + ":5: app my-app: Unknown key 'instance', did you mean 'instances'?"))
+	})
+
+	It("reports the line of every invalid env var, accepting numbers and booleans", func() {
+		m, _, errs := repo.ReadManifest("../../fixtures/manifests/invalid-env-manifest.yml", NewVariables())
+
+		manifestPath := filepath.Clean("../../fixtures/manifests/invalid-env-manifest.yml")
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(Equal(manifestPath + ":6: app my-app: env var 'HOSTS' should be a single value"))
+		Expect(m.Warnings).To(BeEmpty())
+	})
+
+	Describe("interpolating variables", func() {
+		var vars Variables
+
+		BeforeEach(func() {
+			vars = NewVariables()
+			err := vars.AddFromFile("../../fixtures/manifests/vars.yml")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("CF_VAR_database_url")
+		})
+
+		It("fills in variables from vars files, flags and the environment", func() {
+			os.Setenv("CF_VAR_database_url", "postgres://db.example.com")
+			vars.AddFromEnv()
+			err := vars.AddFromFlag("space=production")
+			Expect(err).NotTo(HaveOccurred())
+
+			m, _, errs := repo.ReadManifest("../../fixtures/manifests/vars-manifest.yml", vars)
+			Expect(errs).To(BeEmpty())
+
+			app := m.Applications[0]
+			Expect(*app.Name).To(Equal("my-app"))
+			Expect(*app.InstanceCount).To(Equal(3))
+			Expect(*app.Host).To(Equal("my-app-production"))
+			Expect(*app.EnvironmentVars).To(Equal(map[string]string{
+				"DATABASE_URL": "postgres://db.example.com",
+				"PORT":         "8080",
+			}))
+		})
+
+		It("names the variables that are not set and the file using them", func() {
+			_, _, errs := repo.ReadManifest("../../fixtures/manifests/vars-manifest.yml", vars)
+			Expect(errs).NotTo(BeEmpty())
+			Expect(errs.Error()).To(ContainSubstring("Unresolved variable ((database_url)) in manifest"))
+			Expect(errs.Error()).To(ContainSubstring("vars-manifest.yml"))
+		})
+
+		It("rejects flags that are not name=value", func() {
+			err := vars.AddFromFlag("database_url")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected name=value"))
+		})
+	})
+
+	Describe("writing a manifest", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "manifest")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes a manifest that reads back into the same apps", func() {
+			name, command, buildpack, stack := "my-app", "bundle exec rackup", "ruby_buildpack", "cflinuxfs"
+			host, domain := "my-host", "example.com"
+			memory, diskQuota := uint64(256), uint64(1024)
+			instances := 3
+			services := []string{"my-db", "my-cache"}
+			env := map[string]string{"RAILS_ENV": "production"}
+
+			m := NewEmptyManifest()
+			m.Applications = []models.AppParams{{
+				Name:            &name,
+				Command:         &command,
+				BuildpackUrl:    &buildpack,
+				StackName:       &stack,
+				Host:            &host,
+				Domain:          &domain,
+				Memory:          &memory,
+				DiskQuota:       &diskQuota,
+				InstanceCount:   &instances,
+				Services:        &services,
+				EnvironmentVars: &env,
+			}}
+
+			path := filepath.Join(dir, "manifest.yml")
+			err := repo.WriteManifest(m, path)
+			Expect(err).NotTo(HaveOccurred())
+
+			readManifest, _, errs := repo.ReadManifest(path, NewVariables())
+			Expect(errs).To(BeEmpty())
+			Expect(readManifest.Applications).To(Equal(m.Applications))
+		})
+
+		It("writes lists of hosts, domains and routes", func() {
+			name := "my-app"
+
+			m := NewEmptyManifest()
+			m.Applications = []models.AppParams{{
+				Name:    &name,
+				Hosts:   &[]string{"my-app", "www"},
+				Domains: &[]string{"example.com"},
+				Routes:  &[]string{"api.example.org"},
+			}}
+
+			path := filepath.Join(dir, "manifest.yml")
+			err := repo.WriteManifest(m, path)
+			Expect(err).NotTo(HaveOccurred())
+
+			readManifest, _, errs := repo.ReadManifest(path, NewVariables())
+			Expect(errs).To(BeEmpty())
+			Expect(*readManifest.Applications[0].Hosts).To(Equal([]string{"my-app", "www"}))
+			Expect(*readManifest.Applications[0].Domains).To(Equal([]string{"example.com"}))
+			Expect(*readManifest.Applications[0].Routes).To(Equal([]string{"api.example.org"}))
+		})
+
+		It("writes the services the app declares", func() {
+			name := "my-app"
+
+			m := NewEmptyManifest()
+			m.Applications = []models.AppParams{{
+				Name:     &name,
+				Services: &[]string{"existing-service", "my-db", "my-creds"},
+				ServiceInstances: &[]models.ServiceInstanceParams{
+					{Name: "my-db", Label: "mysql", Plan: "small"},
+					{Name: "my-creds", Credentials: map[string]string{"user": "admin"}},
+				},
+			}}
+
+			path := filepath.Join(dir, "manifest.yml")
+			err := repo.WriteManifest(m, path)
+			Expect(err).NotTo(HaveOccurred())
+
+			readManifest, _, errs := repo.ReadManifest(path, NewVariables())
+			Expect(errs).To(BeEmpty())
+			Expect(*readManifest.Applications[0].Services).To(Equal(*m.Applications[0].Services))
+			Expect(*readManifest.Applications[0].ServiceInstances).To(Equal(*m.Applications[0].ServiceInstances))
+		})
+
+		It("writes apps without routes as workers", func() {
+			name, noRoute := "my-worker", true
+
+			m := NewEmptyManifest()
+			m.Applications = []models.AppParams{{Name: &name, NoRoute: &noRoute}}
+
+			path := filepath.Join(dir, "manifest.yml")
+			err := repo.WriteManifest(m, path)
+			Expect(err).NotTo(HaveOccurred())
+
+			readManifest, _, errs := repo.ReadManifest(path, NewVariables())
+			Expect(errs).To(BeEmpty())
+			Expect(*readManifest.Applications[0].Name).To(Equal("my-worker"))
+			Expect(*readManifest.Applications[0].NoRoute).To(BeTrue())
+		})
+	})
 })
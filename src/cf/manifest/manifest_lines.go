@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var yamlKeyRegex = regexp.MustCompile(`^["']?([^"':#][^"':]*)["']?\s*:(\s|$)`)
+
+// manifestLines maps each key of a manifest file to the line it is on. Keys
+// are dotted paths, such as memory or applications.0.memory for the memory of
+// the first app. The YAML parser does not keep track of lines, so the file is
+// scanned by indentation, which is enough for the block style manifests use.
+type manifestLines map[string]int
+
+type yamlFrame struct {
+	indent    int
+	path      string
+	itemCount int
+}
+
+func newManifestLines(yamlBytes []byte) (lines manifestLines) {
+	lines = manifestLines{}
+	frames := []*yamlFrame{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(yamlBytes))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "#") || strings.HasPrefix(content, "---") {
+			continue
+		}
+		indent := len(line) - len(content)
+
+		if content == "-" || strings.HasPrefix(content, "- ") {
+			for len(frames) > 0 && frames[len(frames)-1].indent > indent {
+				frames = frames[:len(frames)-1]
+			}
+
+			itemPath := strconv.Itoa(0)
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1]
+				itemPath = joinKeyPath(parent.path, strconv.Itoa(parent.itemCount))
+				parent.itemCount++
+			}
+			lines[itemPath] = lineNumber
+			frames = append(frames, &yamlFrame{indent: indent + 1, path: itemPath})
+
+			content = strings.TrimLeft(strings.TrimPrefix(content, "-"), " ")
+			indent = len(line) - len(content)
+		}
+
+		match := yamlKeyRegex.FindStringSubmatch(content)
+		if match == nil {
+			continue
+		}
+
+		for len(frames) > 0 && frames[len(frames)-1].indent >= indent {
+			frames = frames[:len(frames)-1]
+		}
+
+		path := strings.TrimSpace(match[1])
+		if len(frames) > 0 {
+			path = joinKeyPath(frames[len(frames)-1].path, path)
+		}
+		lines[path] = lineNumber
+		frames = append(frames, &yamlFrame{indent: indent, path: path})
+	}
+	return
+}
+
+// lineOf returns the line of a key, or of the closest enclosing key found.
+func (lines manifestLines) lineOf(path string) int {
+	for path != "" {
+		if line, found := lines[path]; found {
+			return line
+		}
+
+		lastDot := strings.LastIndex(path, ".")
+		if lastDot < 0 {
+			break
+		}
+		path = path[:lastDot]
+	}
+	return 0
+}
+
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -2,6 +2,7 @@ package manifest_test
 
 import (
 	"cf/manifest"
+	"cf/models"
 	"generic"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -102,6 +103,44 @@ var _ = Describe("Testing with ginkgo", func() {
 		Expect(errs.Error()).To(ContainSubstring("env var 'bar' should not be null"))
 	})
 
+	It("turns numbers and booleans in env into strings", func() {
+		m, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{
+					"name": "my-app",
+					"env": map[string]interface{}{
+						"PORT":    8080,
+						"RATIO":   0.5,
+						"VERBOSE": true,
+					},
+				},
+			},
+		}))
+
+		Expect(errs).To(BeEmpty())
+		Expect(*m.Applications[0].EnvironmentVars).To(Equal(map[string]string{
+			"PORT":    "8080",
+			"RATIO":   "0.5",
+			"VERBOSE": "true",
+		}))
+	})
+
+	It("rejects env vars that are lists or maps", func() {
+		_, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{
+					"name": "my-app",
+					"env": map[string]interface{}{
+						"HOSTS": []interface{}{"a", "b"},
+					},
+				},
+			},
+		}))
+
+		Expect(errs).NotTo(BeEmpty())
+		Expect(errs.Error()).To(ContainSubstring("env var 'HOSTS' should be a single value"))
+	})
+
 	It("returns an empty map when no env was present in the manifest", func() {
 		m, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
 			"applications": []interface{}{
@@ -137,6 +176,105 @@ var _ = Describe("Testing with ginkgo", func() {
 		}
 	})
 
+	It("parses lists of hosts, domains and routes", func() {
+		m, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{
+					"name":    "my-app",
+					"hosts":   []interface{}{"my-app", "www"},
+					"domains": []interface{}{"example.com", "example.org"},
+					"routes":  []interface{}{"my-app.cf-app.com", "example.net"},
+				},
+			},
+		}))
+
+		Expect(errs).To(BeEmpty())
+		Expect(*m.Applications[0].Hosts).To(Equal([]string{"my-app", "www"}))
+		Expect(*m.Applications[0].Domains).To(Equal([]string{"example.com", "example.org"}))
+		Expect(*m.Applications[0].Routes).To(Equal([]string{"my-app.cf-app.com", "example.net"}))
+	})
+
+	It("does not set hosts, domains or routes when the manifest has none", func() {
+		m, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{"name": "my-app"},
+			},
+		}))
+
+		Expect(errs).To(BeEmpty())
+		Expect(m.Applications[0].Hosts).To(BeNil())
+		Expect(m.Applications[0].Domains).To(BeNil())
+		Expect(m.Applications[0].Routes).To(BeNil())
+	})
+
+	It("returns an error when routes is not a list of strings", func() {
+		_, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{"routes": "my-app.example.com"},
+			},
+		}))
+
+		Expect(errs).NotTo(BeEmpty())
+		Expect(errs.Error()).To(ContainSubstring("Expected routes to be a list of strings."))
+	})
+
+	It("parses services declared with a plan or with credentials", func() {
+		m, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{
+					"name": "my-app",
+					"services": []interface{}{
+						"existing-service",
+						map[string]interface{}{
+							"name":     "my-db",
+							"label":    "mysql",
+							"provider": "core",
+							"plan":     "small",
+						},
+						map[string]interface{}{
+							"name":             "my-creds",
+							"credentials":      map[string]interface{}{"user": "admin", "port": 5432},
+							"syslog_drain_url": "syslog://logs.example.com",
+						},
+					},
+				},
+			},
+		}))
+
+		Expect(errs).To(BeEmpty())
+		app := m.Applications[0]
+		Expect(*app.Services).To(Equal([]string{"existing-service", "my-db", "my-creds"}))
+		Expect(*app.ServiceInstances).To(Equal([]models.ServiceInstanceParams{
+			{Name: "my-db", Label: "mysql", Provider: "core", Plan: "small"},
+			{
+				Name:           "my-creds",
+				Credentials:    map[string]string{"user": "admin", "port": "5432"},
+				SysLogDrainUrl: "syslog://logs.example.com",
+			},
+		}))
+	})
+
+	It("returns errors for services that cannot be created", func() {
+		_, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
+			"applications": []interface{}{
+				map[string]interface{}{
+					"services": []interface{}{
+						map[string]interface{}{"label": "mysql", "plan": "small"},
+						map[string]interface{}{"name": "no-plan", "label": "mysql"},
+						map[string]interface{}{"name": "both", "label": "mysql", "plan": "small", "credentials": map[string]interface{}{"user": "admin"}},
+						map[string]interface{}{"name": "typo", "lable": "mysql"},
+					},
+				},
+			},
+		}))
+
+		Expect(errs).NotTo(BeEmpty())
+		Expect(errs.Error()).To(ContainSubstring("Expected service to have a name."))
+		Expect(errs.Error()).To(ContainSubstring("Service no-plan needs both a label and a plan to be created."))
+		Expect(errs.Error()).To(ContainSubstring("Service both cannot have both a plan and user provided credentials."))
+		Expect(errs.Error()).To(ContainSubstring("Unknown key 'lable' in service"))
+	})
+
 	It("TestParsingManifestWithNulls", func() {
 		_, errs := manifest.NewManifest("/some/path", generic.NewMap(map[string]interface{}{
 			"applications": []interface{}{
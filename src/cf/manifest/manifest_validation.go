@@ -0,0 +1,216 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"generic"
+	"sort"
+	"strings"
+)
+
+// appKeys are the app settings a manifest may have, for each app or at the
+// top level as defaults for all apps.
+var appKeys = []string{
+	"buildpack",
+	"command",
+	"disk_quota",
+	"domain",
+	"domains",
+	"env",
+	"host",
+	"hosts",
+	"instances",
+	"memory",
+	"name",
+	"no-route",
+	"path",
+	"routes",
+	"services",
+	"stack",
+	"timeout",
+}
+
+var topLevelKeys = []string{
+	"applications",
+	"inherit",
+}
+
+// validateManifestFile checks the keys and values of one manifest file before
+// it is merged with the files it inherits from, so every error points at the
+// file, line and app it comes from. Unknown keys are only warnings, since push
+// has always ignored them.
+func validateManifestFile(path string, data generic.Map, lines manifestLines) (errs, warnings ManifestErrors) {
+	for _, key := range keysInFileOrder(data, "", lines) {
+		keyPath := fmt.Sprintf("%v", key)
+		value := data.Get(key)
+
+		switch keyPath {
+		case "applications":
+			appErrs, appWarnings := validateApplications(path, value, lines)
+			errs = append(errs, appErrs...)
+			warnings = append(warnings, appWarnings...)
+		case "inherit":
+			if _, ok := value.(string); !ok {
+				errs = append(errs, newLineError(path, lines.lineOf(keyPath), "", errors.New("inherit must be a path to a manifest")))
+			}
+		default:
+			keyErrs, keyWarnings := validateAppKey(path, "", keyPath, value, lines)
+			errs = append(errs, keyErrs...)
+			warnings = append(warnings, keyWarnings...)
+		}
+	}
+	return
+}
+
+func validateApplications(path string, value interface{}, lines manifestLines) (errs, warnings ManifestErrors) {
+	apps, ok := value.([]interface{})
+	if !ok {
+		errs = append(errs, newLineError(path, lines.lineOf("applications"), "", errors.New("Expected applications to be a list")))
+		return
+	}
+
+	for index, appData := range apps {
+		appPath := fmt.Sprintf("applications.%d", index)
+		if !generic.IsMappable(appData) {
+			errs = append(errs, newLineError(path, lines.lineOf(appPath), "", errors.New("Expected application to be a dictionary")))
+			continue
+		}
+
+		appMap := generic.NewMap(appData)
+		appName, _ := appMap.Get("name").(string)
+		for _, key := range keysInFileOrder(appMap, appPath, lines) {
+			keyPath := joinKeyPath(appPath, fmt.Sprintf("%v", key))
+			keyErrs, keyWarnings := validateAppKey(path, appName, keyPath, appMap.Get(key), lines)
+			errs = append(errs, keyErrs...)
+			warnings = append(warnings, keyWarnings...)
+		}
+	}
+	return
+}
+
+// validateAppKey runs a single setting through mapToAppParams, which holds the
+// rules for the values of every app setting. Env vars are checked one by one
+// first, so each error points at the line of its own var.
+func validateAppKey(path, appName, keyPath string, value interface{}, lines manifestLines) (errs, warnings ManifestErrors) {
+	key := keyPath[strings.LastIndex(keyPath, ".")+1:]
+	line := lines.lineOf(keyPath)
+
+	if !containsString(appKeys, key) {
+		message := fmt.Sprintf("Unknown key '%s'", key)
+		if suggestion := closestKey(key); suggestion != "" {
+			message = fmt.Sprintf("%s, did you mean '%s'?", message, suggestion)
+		}
+		warnings = append(warnings, newLineError(path, line, appName, errors.New(message)))
+		return
+	}
+
+	if key == "env" && generic.IsMappable(value) {
+		envVars := generic.NewMap(value)
+		for _, name := range keysInFileOrder(envVars, keyPath, lines) {
+			err := validateEnvVar(name, envVars.Get(name))
+			if err != nil {
+				varLine := lines.lineOf(joinKeyPath(keyPath, fmt.Sprintf("%v", name)))
+				errs = append(errs, newLineError(path, varLine, appName, err))
+			}
+		}
+		return
+	}
+
+	_, keyErrs := mapToAppParams("", generic.NewMap(map[string]interface{}{key: value}))
+	for _, err := range keyErrs {
+		errs = append(errs, newLineError(path, line, appName, err))
+	}
+	return
+}
+
+func newLineError(path string, line int, appName string, err error) error {
+	location := path
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d", path, line)
+	}
+	if appName != "" {
+		return errors.New(fmt.Sprintf("%s: app %s: %s", location, appName, err))
+	}
+	return errors.New(fmt.Sprintf("%s: %s", location, err))
+}
+
+func keysInFileOrder(data generic.Map, path string, lines manifestLines) (keys []interface{}) {
+	keys = data.Keys()
+	sort.Sort(keysByLine{keys, path, lines})
+	return
+}
+
+type keysByLine struct {
+	keys  []interface{}
+	path  string
+	lines manifestLines
+}
+
+func (sorter keysByLine) Len() int {
+	return len(sorter.keys)
+}
+
+func (sorter keysByLine) Swap(i, j int) {
+	sorter.keys[i], sorter.keys[j] = sorter.keys[j], sorter.keys[i]
+}
+
+func (sorter keysByLine) Less(i, j int) bool {
+	lineI := sorter.lines.lineOf(joinKeyPath(sorter.path, fmt.Sprintf("%v", sorter.keys[i])))
+	lineJ := sorter.lines.lineOf(joinKeyPath(sorter.path, fmt.Sprintf("%v", sorter.keys[j])))
+	if lineI != lineJ {
+		return lineI < lineJ
+	}
+	return fmt.Sprintf("%v", sorter.keys[i]) < fmt.Sprintf("%v", sorter.keys[j])
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey suggests the known key a typo was most likely meant to be.
+func closestKey(key string) (closest string) {
+	bestDistance := 3
+	for _, candidate := range append(appKeys, topLevelKeys...) {
+		distance := editDistance(key, candidate)
+		if distance < bestDistance {
+			closest, bestDistance = candidate, distance
+		}
+	}
+	return
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) (min int) {
+	min = values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return
+}
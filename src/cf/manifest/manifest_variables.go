@@ -0,0 +1,141 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"generic"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const CF_VAR_PREFIX = "CF_VAR_"
+
+var variableRegex = regexp.MustCompile(`\(\(([-\w.]+)\)\)`)
+
+// Variables fill in the ((name)) placeholders of a manifest, so one manifest
+// can serve several environments.
+type Variables map[string]interface{}
+
+func NewVariables() Variables {
+	return Variables{}
+}
+
+// AddFromEnv adds a variable for each CF_VAR_ env var, named after the rest of
+// the env var name; CF_VAR_host sets ((host)).
+func (vars Variables) AddFromEnv() {
+	for _, envVar := range os.Environ() {
+		if !strings.HasPrefix(envVar, CF_VAR_PREFIX) {
+			continue
+		}
+
+		nameAndValue := strings.SplitN(strings.TrimPrefix(envVar, CF_VAR_PREFIX), "=", 2)
+		if nameAndValue[0] != "" && len(nameAndValue) == 2 {
+			vars[nameAndValue[0]] = nameAndValue[1]
+		}
+	}
+}
+
+// AddFromFile adds the top level keys of a YAML vars file.
+func (vars Variables) AddFromFile(path string) (err error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error reading vars file %s\n%s", path, err))
+		return
+	}
+	defer file.Close()
+
+	varsMap, err := parseManifest(file)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error reading vars file %s\n%s", path, err))
+		return
+	}
+
+	generic.Each(varsMap, func(key, value interface{}) {
+		vars[fmt.Sprintf("%v", key)] = value
+	})
+	return
+}
+
+// AddFromFlag adds a variable given as name=value.
+func (vars Variables) AddFromFlag(flag string) (err error) {
+	nameAndValue := strings.SplitN(flag, "=", 2)
+	if len(nameAndValue) != 2 || nameAndValue[0] == "" {
+		err = errors.New(fmt.Sprintf("Invalid variable '%s', expected name=value", flag))
+		return
+	}
+
+	vars[nameAndValue[0]] = nameAndValue[1]
+	return
+}
+
+// interpolate replaces the placeholders in the values of a manifest file. A
+// value made of a single placeholder takes the variable as it is, so vars
+// files can fill in numbers, lists and maps too.
+func interpolate(value interface{}, vars Variables, path string) (result interface{}, errs ManifestErrors) {
+	unresolved := map[string]bool{}
+	result = interpolateValue(value, vars, path, unresolved, &errs)
+	return
+}
+
+func interpolateValue(value interface{}, vars Variables, path string, unresolved map[string]bool, errs *ManifestErrors) interface{} {
+	switch value := value.(type) {
+	case string:
+		return interpolateString(value, vars, path, unresolved, errs)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for index, item := range value {
+			result[index] = interpolateValue(item, vars, path, unresolved, errs)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = interpolateValue(item, vars, path, unresolved, errs)
+		}
+		return result
+	case map[interface{}]interface{}, generic.Map:
+		result := generic.NewMap()
+		generic.Each(generic.NewMap(value), func(key, item interface{}) {
+			result.Set(key, interpolateValue(item, vars, path, unresolved, errs))
+		})
+		return result
+	}
+	return value
+}
+
+func interpolateString(value string, vars Variables, path string, unresolved map[string]bool, errs *ManifestErrors) interface{} {
+	match := variableRegex.FindStringSubmatch(value)
+	if match != nil && match[0] == value {
+		variable, found := lookUpVariable(match[1], vars, path, unresolved, errs)
+		if !found {
+			return value
+		}
+		return variable
+	}
+
+	return variableRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+		name := variableRegex.FindStringSubmatch(placeholder)[1]
+		variable, found := lookUpVariable(name, vars, path, unresolved, errs)
+		if !found {
+			return placeholder
+		}
+
+		switch variable.(type) {
+		case []interface{}, map[string]interface{}, map[interface{}]interface{}, generic.Map:
+			*errs = append(*errs, errors.New(fmt.Sprintf("Variable ((%s)) in manifest %s must be a single value to be used inside '%s'", name, path, value)))
+			return placeholder
+		}
+		return fmt.Sprintf("%v", variable)
+	})
+}
+
+func lookUpVariable(name string, vars Variables, path string, unresolved map[string]bool, errs *ManifestErrors) (variable interface{}, found bool) {
+	variable, found = vars[name]
+	if !found && !unresolved[name] {
+		unresolved[name] = true
+		*errs = append(*errs, errors.New(fmt.Sprintf("Unresolved variable ((%s)) in manifest %s", name, path)))
+	}
+	return
+}
@@ -33,6 +33,7 @@ func (model Application) ToParams() (params AppParams) {
 type AppSummary struct {
 	ApplicationFields
 	RouteSummaries []RouteSummary
+	Services       []ServiceInstance
 }
 
 type ApplicationFields struct {
@@ -54,17 +55,21 @@ type AppParams struct {
 	Command            *string
 	DiskQuota          *uint64
 	Domain             *string
+	Domains            *[]string
 	EnvironmentVars    *map[string]string
 	Guid               *string
 	HealthCheckTimeout *int
 	Host               *string
+	Hosts              *[]string
 	InstanceCount      *int
 	Memory             *uint64
 	Name               *string
 	NoRoute            *bool
 	Path               *string
+	Routes             *[]string
 	RunningInstances   *int
 	Services           *[]string
+	ServiceInstances   *[]ServiceInstanceParams
 	SpaceGuid          *string
 	StackGuid          *string
 	StackName          *string
@@ -84,6 +89,9 @@ func (app *AppParams) Merge(other *AppParams) {
 	if other.Domain != nil {
 		app.Domain = other.Domain
 	}
+	if other.Domains != nil {
+		app.Domains = other.Domains
+	}
 	if other.EnvironmentVars != nil {
 		app.EnvironmentVars = other.EnvironmentVars
 	}
@@ -96,6 +104,9 @@ func (app *AppParams) Merge(other *AppParams) {
 	if other.Host != nil {
 		app.Host = other.Host
 	}
+	if other.Hosts != nil {
+		app.Hosts = other.Hosts
+	}
 	if other.InstanceCount != nil {
 		app.InstanceCount = other.InstanceCount
 	}
@@ -111,12 +122,18 @@ func (app *AppParams) Merge(other *AppParams) {
 	if other.Path != nil {
 		app.Path = other.Path
 	}
+	if other.Routes != nil {
+		app.Routes = other.Routes
+	}
 	if other.RunningInstances != nil {
 		app.RunningInstances = other.RunningInstances
 	}
 	if other.Services != nil {
 		app.Services = other.Services
 	}
+	if other.ServiceInstances != nil {
+		app.ServiceInstances = other.ServiceInstances
+	}
 	if other.SpaceGuid != nil {
 		app.SpaceGuid = other.SpaceGuid
 	}
@@ -131,6 +148,21 @@ func (app *AppParams) Merge(other *AppParams) {
 	}
 }
 
+// FindServiceInstanceParams finds how to create the service instance with the
+// given name, if the app says so.
+func (app *AppParams) FindServiceInstanceParams(name string) (params ServiceInstanceParams, found bool) {
+	if app.ServiceInstances == nil {
+		return
+	}
+
+	for _, instanceParams := range *app.ServiceInstances {
+		if instanceParams.Name == name {
+			return instanceParams, true
+		}
+	}
+	return
+}
+
 func (app *AppParams) Equals(otherParams *AppParams) bool {
 	return reflect.DeepEqual(*app, *otherParams)
 }
@@ -18,3 +18,20 @@ type ServiceInstance struct {
 func (inst ServiceInstance) IsUserProvided() bool {
 	return inst.ServicePlan.Guid == ""
 }
+
+// ServiceInstanceParams describe a service instance an app is bound to, so the
+// instance can be created when it does not exist yet. Instances with a label
+// and a plan are created from a service offering; the others are user
+// provided.
+type ServiceInstanceParams struct {
+	Name           string
+	Label          string
+	Provider       string
+	Plan           string
+	Credentials    map[string]string
+	SysLogDrainUrl string
+}
+
+func (params ServiceInstanceParams) IsUserProvided() bool {
+	return params.Label == ""
+}
@@ -1,6 +1,7 @@
 package net
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -12,31 +13,59 @@ type ApiResponse struct {
 	ErrorHeader string
 	ErrorBody   string
 
-	isError        bool
-	isHttpResponse bool
-	isNotFound     bool
+	err              error
+	isError          bool
+	isHttpResponse   bool
+	isNotFound       bool
+	isInvalidSSLCert bool
 }
 
-func NewApiResponse(message string, errorCode string, statusCode int) (apiResponse ApiResponse) {
-	return ApiResponse{
-		Message:        message,
-		ErrorCode:      errorCode,
-		StatusCode:     statusCode,
-		isError:        true,
-		isHttpResponse: true,
+// NewApiResponseWithTypedError builds the response for one of the gateway
+// errors, filling in the status and error code of any HttpError it wraps.
+func NewApiResponseWithTypedError(err error) (apiResponse ApiResponse) {
+	apiResponse = ApiResponse{
+		Message: err.Error(),
+		err:     err,
+		isError: true,
 	}
-}
 
-func NewApiResponseWithHttpError(message string, errorCode string, statusCode int, errorHeader string, errorBody string) (apiResponse ApiResponse) {
-	return ApiResponse{
-		Message:        message,
-		ErrorCode:      errorCode,
-		StatusCode:     statusCode,
-		isError:        true,
-		isHttpResponse: true,
-		ErrorHeader:    errorHeader,
-		ErrorBody:      errorBody,
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		apiResponse.ErrorCode = httpErr.ErrorCode
+		apiResponse.StatusCode = httpErr.StatusCode
+		apiResponse.ErrorHeader = httpErr.Header
+		apiResponse.ErrorBody = httpErr.Body
+		apiResponse.isHttpResponse = true
 	}
+
+	var jobErr *AsyncJobError
+	if errors.As(err, &jobErr) {
+		apiResponse.ErrorCode = jobErr.ErrorCode
+	}
+
+	var notFoundErr *NotFoundError
+	if errors.As(err, &notFoundErr) && httpErr == nil {
+		apiResponse.isError = false
+		apiResponse.isNotFound = true
+	}
+
+	var sslErr *InvalidSSLCertError
+	apiResponse.isInvalidSSLCert = errors.As(err, &sslErr)
+	return
+}
+
+func NewApiResponse(message string, errorCode string, statusCode int) (apiResponse ApiResponse) {
+	apiResponse = NewApiResponseWithTypedError(newHttpError(&HttpError{
+		StatusCode:  statusCode,
+		ErrorCode:   errorCode,
+		Description: message,
+	}))
+	apiResponse.Message = message
+	return
+}
+
+func NewApiResponseWithHttpError(httpErr *HttpError) (apiResponse ApiResponse) {
+	return NewApiResponseWithTypedError(newHttpError(httpErr))
 }
 
 func NewApiResponseWithStatusCode(statusCode int) (apiResponse ApiResponse) {
@@ -47,30 +76,34 @@ func NewApiResponseWithStatusCode(statusCode int) (apiResponse ApiResponse) {
 }
 
 func NewApiResponseWithMessage(message string, a ...interface{}) (apiResponse ApiResponse) {
-	return ApiResponse{
-		Message: fmt.Sprintf(message, a...),
-		isError: true,
-	}
+	return NewApiResponseWithTypedError(&RequestError{Message: fmt.Sprintf(message, a...)})
 }
 
 func NewApiResponseWithError(message string, err error) (apiResponse ApiResponse) {
-	return ApiResponse{
-		Message: fmt.Sprintf("%s: %s", message, err.Error()),
-		isError: true,
-	}
+	return NewApiResponseWithTypedError(&RequestError{Message: message, Cause: err})
 }
 
 func NewNotFoundApiResponse(message string, a ...interface{}) (apiResponse ApiResponse) {
-	return ApiResponse{
-		Message:    fmt.Sprintf(message, a...),
-		isNotFound: true,
-	}
+	return NewApiResponseWithTypedError(&NotFoundError{Message: fmt.Sprintf(message, a...)})
+}
+
+func NewInvalidSSLCertApiResponse(host string) (apiResponse ApiResponse) {
+	return NewApiResponseWithTypedError(&InvalidSSLCertError{Host: host})
+}
+
+func NewNetworkErrorApiResponse(host string, err error) (apiResponse ApiResponse) {
+	return NewApiResponseWithTypedError(&NetworkError{Host: host, Timeout: isTimeoutError(err), Cause: err})
 }
 
 func NewSuccessfulApiResponse() (apiResponse ApiResponse) {
 	return ApiResponse{}
 }
 
+// Err returns the typed error behind an unsuccessful response, or nil.
+func (apiResponse ApiResponse) Err() error {
+	return apiResponse.err
+}
+
 func (apiResponse ApiResponse) IsError() bool {
 	return apiResponse.isError
 }
@@ -83,6 +116,10 @@ func (apiResponse ApiResponse) IsNotFound() bool {
 	return apiResponse.isNotFound || (apiResponse.isHttpResponse && apiResponse.StatusCode == 404)
 }
 
+func (apiResponse ApiResponse) IsInvalidSSLCert() bool {
+	return apiResponse.isInvalidSSLCert
+}
+
 func (apiResponse ApiResponse) IsSuccessful() bool {
 	return !apiResponse.IsNotSuccessful()
 }
@@ -0,0 +1,289 @@
+package net
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	CF_RECORD = "CF_RECORD"
+	CF_REPLAY = "CF_REPLAY"
+
+	MULTIPART_CONTENT_PLACEHOLDER = "[MULTIPART/FORM-DATA CONTENT HIDDEN]"
+)
+
+type CassetteRequest struct {
+	Method  string
+	Url     string
+	Path    string
+	Headers map[string][]string
+	Body    string
+}
+
+type CassetteResponse struct {
+	StatusCode int
+	Headers    map[string][]string
+	Body       string
+}
+
+type CassetteInteraction struct {
+	Request  CassetteRequest
+	Response CassetteResponse
+}
+
+// Cassette is a recorded conversation between the CLI and the API, stored as
+// JSON with the same private data hidden as in CF_TRACE output.
+type Cassette struct {
+	Interactions []CassetteInteraction
+
+	path   string
+	played []bool
+	mutex  sync.Mutex
+}
+
+var recordingCassettes = map[string]*Cassette{}
+var replayingCassettes = map[string]*Cassette{}
+var cassettesMutex sync.Mutex
+
+// cassetteTransportFromEnv wraps the given transport so that it records to, or
+// replays from, the cassette named by CF_RECORD or CF_REPLAY. Every gateway in
+// the process shares the same cassette for a given file.
+func cassetteTransportFromEnv(transport http.RoundTripper) (http.RoundTripper, error) {
+	if path := os.Getenv(CF_REPLAY); path != "" {
+		cassette, err := loadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		return &replayingTransport{cassette: cassette}, nil
+	}
+
+	if path := os.Getenv(CF_RECORD); path != "" {
+		return &recordingTransport{transport: transport, cassette: recordingCassette(path)}, nil
+	}
+
+	return transport, nil
+}
+
+func loadCassette(path string) (cassette *Cassette, err error) {
+	cassettesMutex.Lock()
+	defer cassettesMutex.Unlock()
+
+	cassette, found := replayingCassettes[path]
+	if found {
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error reading cassette %s: %s", path, err))
+		return
+	}
+
+	cassette = &Cassette{path: path}
+	err = json.Unmarshal(data, cassette)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error parsing cassette %s: %s", path, err))
+		return
+	}
+
+	cassette.played = make([]bool, len(cassette.Interactions))
+	replayingCassettes[path] = cassette
+	return
+}
+
+func recordingCassette(path string) (cassette *Cassette) {
+	cassettesMutex.Lock()
+	defer cassettesMutex.Unlock()
+
+	cassette, found := recordingCassettes[path]
+	if !found {
+		cassette = &Cassette{path: path, Interactions: []CassetteInteraction{}}
+		recordingCassettes[path] = cassette
+	}
+	return
+}
+
+func (cassette *Cassette) record(interaction CassetteInteraction) (err error) {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	cassette.Interactions = append(cassette.Interactions, interaction)
+
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return
+	}
+
+	// the whole file is rewritten every time so that a command exiting
+	// early still leaves a valid cassette behind
+	return ioutil.WriteFile(cassette.path, data, 0600)
+}
+
+// find returns the first interaction matching the request which has not been
+// played back yet. Once all matches have been used, the last one is repeated,
+// which keeps polling loops going.
+func (cassette *Cassette) find(request CassetteRequest) (interaction CassetteInteraction, found bool) {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	lastMatch := -1
+	for index, candidate := range cassette.Interactions {
+		if !candidate.Request.matches(request) {
+			continue
+		}
+
+		lastMatch = index
+		if !cassette.played[index] {
+			cassette.played[index] = true
+			return candidate, true
+		}
+	}
+
+	if lastMatch >= 0 {
+		return cassette.Interactions[lastMatch], true
+	}
+	return
+}
+
+func (recorded CassetteRequest) matches(request CassetteRequest) bool {
+	return recorded.Method == request.Method &&
+		recorded.Path == request.Path &&
+		strings.TrimSpace(recorded.Body) == strings.TrimSpace(request.Body)
+}
+
+type recordingTransport struct {
+	transport http.RoundTripper
+	cassette  *Cassette
+}
+
+func (recorder *recordingTransport) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	cassetteRequest, err := newCassetteRequest(request)
+	if err != nil {
+		return
+	}
+
+	response, err = recorder.transport.RoundTrip(request)
+	if err != nil {
+		return
+	}
+
+	body, err := readResponseBody(response)
+	if err != nil {
+		return
+	}
+
+	err = recorder.cassette.record(CassetteInteraction{
+		Request: cassetteRequest,
+		Response: CassetteResponse{
+			StatusCode: response.StatusCode,
+			Headers:    sanitizeHeaders(response.Header),
+			Body:       body,
+		},
+	})
+	if err != nil {
+		err = errors.New(fmt.Sprintf("Error recording cassette %s: %s", recorder.cassette.path, err))
+	}
+	return
+}
+
+type replayingTransport struct {
+	cassette *Cassette
+}
+
+func (replayer *replayingTransport) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	cassetteRequest, err := newCassetteRequest(request)
+	if err != nil {
+		return
+	}
+
+	interaction, found := replayer.cassette.find(cassetteRequest)
+	if !found {
+		err = errors.New(fmt.Sprintf("No response recorded in cassette %s for %s %s", replayer.cassette.path, request.Method, cassetteRequest.Path))
+		return
+	}
+
+	body := interaction.Response.Body
+	response = &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(interaction.Response.Headers),
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+	response.Header.Del("Content-Length")
+	return
+}
+
+// newCassetteRequest captures the sanitized request. Multipart uploads are not
+// stored, their bodies are app bits or buildpacks and may be huge.
+func newCassetteRequest(request *http.Request) (cassetteRequest CassetteRequest, err error) {
+	cassetteRequest = CassetteRequest{
+		Method:  request.Method,
+		Url:     request.URL.String(),
+		Path:    request.URL.RequestURI(),
+		Headers: sanitizeHeaders(request.Header),
+	}
+
+	cassetteRequest.Body, err = readRequestBody(request)
+	return
+}
+
+// readRequestBody returns the sanitized body and leaves the request readable.
+func readRequestBody(request *http.Request) (body string, err error) {
+	if request.Body == nil {
+		return
+	}
+
+	if strings.Contains(request.Header.Get("Content-Type"), "multipart/form-data") {
+		body = MULTIPART_CONTENT_PLACEHOLDER
+		return
+	}
+
+	bodyBytes, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+	body = Sanitize(string(bodyBytes))
+	return
+}
+
+// readResponseBody returns the sanitized body and leaves the response readable.
+func readResponseBody(response *http.Response) (body string, err error) {
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+	body = Sanitize(string(bodyBytes))
+	return
+}
+
+func sanitizeHeaders(headers http.Header) (sanitized map[string][]string) {
+	sanitized = map[string][]string{}
+	for name, values := range headers {
+		if http.CanonicalHeaderKey(name) == "Authorization" {
+			values = []string{PRIVATE_DATA_PLACEHOLDER}
+		}
+		sanitized[name] = values
+	}
+	return
+}
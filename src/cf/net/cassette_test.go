@@ -0,0 +1,135 @@
+package net_test
+
+import (
+	. "cf/net"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	testconfig "testhelpers/configuration"
+)
+
+var _ = Describe("recording and replaying cassettes", func() {
+	var (
+		tmpDir       string
+		cassettePath string
+		apiServer    *httptest.Server
+		requestCount int
+	)
+
+	newGateway := func() Gateway {
+		gateway := NewCloudControllerGateway(testconfig.NewRepository())
+		gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+		return gateway
+	}
+
+	performRequest := func(gateway Gateway, method, path, body string) (string, ApiResponse) {
+		var request *Request
+		if body == "" {
+			request, _ = gateway.NewRequest(method, apiServer.URL+path, "BEARER my-access-token", nil)
+		} else {
+			request, _ = gateway.NewRequest(method, apiServer.URL+path, "BEARER my-access-token", strings.NewReader(body))
+		}
+		response, _, apiResponse := gateway.PerformRequestForTextResponse(request)
+		return response, apiResponse
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "cassettes")
+		Expect(err).NotTo(HaveOccurred())
+		cassettePath = filepath.Join(tmpDir, "cassette.json")
+
+		requestCount = 0
+		apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requestCount++
+			body, _ := ioutil.ReadAll(request.Body)
+			fmt.Fprintf(writer, `{"path":"%s","body":"%s","access_token":"secret-token"}`, request.URL.Path, strings.TrimSpace(string(body)))
+		}))
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+		os.Unsetenv(CF_RECORD)
+		os.Unsetenv(CF_REPLAY)
+		os.RemoveAll(tmpDir)
+	})
+
+	It("records every request and response to a sanitized cassette", func() {
+		os.Setenv(CF_RECORD, cassettePath)
+		gateway := newGateway()
+
+		response, apiResponse := performRequest(gateway, "GET", "/v2/apps", "")
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		Expect(response).To(ContainSubstring("secret-token"))
+
+		_, apiResponse = performRequest(gateway, "PUT", "/v2/apps/my-app-guid", `{"name":"my-app"}`)
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+
+		data, err := ioutil.ReadFile(cassettePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("my-access-token"))
+		Expect(string(data)).NotTo(ContainSubstring("secret-token"))
+
+		cassette := Cassette{}
+		err = json.Unmarshal(data, &cassette)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(len(cassette.Interactions)).To(Equal(2))
+		Expect(cassette.Interactions[0].Request.Method).To(Equal("GET"))
+		Expect(cassette.Interactions[0].Request.Path).To(Equal("/v2/apps"))
+		Expect(cassette.Interactions[0].Request.Headers["Authorization"]).To(Equal([]string{PRIVATE_DATA_PLACEHOLDER}))
+		Expect(cassette.Interactions[0].Response.StatusCode).To(Equal(http.StatusOK))
+		Expect(cassette.Interactions[1].Request.Method).To(Equal("PUT"))
+		Expect(cassette.Interactions[1].Request.Body).To(Equal(`{"name":"my-app"}`))
+	})
+
+	It("replays responses from a cassette without touching the network", func() {
+		os.Setenv(CF_RECORD, cassettePath)
+		recordingGateway := newGateway()
+		performRequest(recordingGateway, "PUT", "/v2/apps/app-1", `{"name":"app-1"}`)
+		performRequest(recordingGateway, "PUT", "/v2/apps/app-1", `{"name":"app-2"}`)
+		os.Unsetenv(CF_RECORD)
+
+		requestCount = 0
+		os.Setenv(CF_REPLAY, cassettePath)
+		replayingGateway := newGateway()
+
+		response, apiResponse := performRequest(replayingGateway, "PUT", "/v2/apps/app-1", `{"name":"app-2"}`)
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		Expect(response).To(ContainSubstring(`{"name":"app-2"}`))
+
+		response, apiResponse = performRequest(replayingGateway, "PUT", "/v2/apps/app-1", `{"name":"app-1"}`)
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		Expect(response).To(ContainSubstring(`{"name":"app-1"}`))
+
+		Expect(requestCount).To(Equal(0))
+	})
+
+	It("returns an error when no recorded response matches the request", func() {
+		os.Setenv(CF_RECORD, cassettePath)
+		performRequest(newGateway(), "GET", "/v2/apps", "")
+		os.Unsetenv(CF_RECORD)
+
+		os.Setenv(CF_REPLAY, cassettePath)
+		_, apiResponse := performRequest(newGateway(), "GET", "/v2/spaces", "")
+
+		Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+		Expect(apiResponse.Message).To(ContainSubstring("No response recorded in cassette"))
+		Expect(apiResponse.Message).To(ContainSubstring("GET /v2/spaces"))
+	})
+
+	It("returns an error when the cassette cannot be read", func() {
+		os.Setenv(CF_REPLAY, filepath.Join(tmpDir, "missing.json"))
+		_, apiResponse := performRequest(newGateway(), "GET", "/v2/apps", "")
+
+		Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+		Expect(apiResponse.Message).To(ContainSubstring("Error reading cassette"))
+	})
+})
@@ -1,6 +1,8 @@
 package net
 
 import (
+	"cf"
+	"cf/configuration"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -8,9 +10,7 @@ import (
 	"strconv"
 )
 
-func NewCloudControllerGateway() Gateway {
-	invalidTokenCode := "1000"
-
+func NewCloudControllerGateway(config configuration.Reader) Gateway {
 	type ccErrorResponse struct {
 		Code        int
 		Description string
@@ -26,7 +26,7 @@ func NewCloudControllerGateway() Gateway {
 		json.Unmarshal(jsonBytes, &ccResp)
 
 		code := strconv.Itoa(ccResp.Code)
-		if code == invalidTokenCode {
+		if code == cf.INVALID_AUTH_TOKEN {
 			code = INVALID_TOKEN_CODE
 		}
 
@@ -38,7 +38,7 @@ func NewCloudControllerGateway() Gateway {
 		}
 	}
 
-	gateway := newGateway(errorHandler)
+	gateway := newGateway(errorHandler, config)
 	gateway.PollingEnabled = true
 	return gateway
 }
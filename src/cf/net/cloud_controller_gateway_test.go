@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 	"net/http"
 	"net/http/httptest"
+	testconfig "testhelpers/configuration"
 )
 
 var failingCloudControllerRequest = func(writer http.ResponseWriter, request *http.Request) {
@@ -23,10 +24,11 @@ var invalidTokenCloudControllerRequest = func(writer http.ResponseWriter, reques
 
 var _ = Describe("Testing with ginkgo", func() {
 	It("TestCloudControllerGatewayErrorHandling", func() {
-		gateway := NewCloudControllerGateway()
+		gateway := NewCloudControllerGateway(testconfig.NewRepository())
 
 		ts := httptest.NewTLSServer(http.HandlerFunc(failingCloudControllerRequest))
 		defer ts.Close()
+		gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		request, apiResponse := gateway.NewRequest("GET", ts.URL, "TOKEN", nil)
 		Expect(apiResponse.IsNotSuccessful()).To(BeFalse())
@@ -39,10 +41,11 @@ var _ = Describe("Testing with ginkgo", func() {
 	})
 	It("TestCloudControllerGatewayInvalidTokenHandling", func() {
 
-		gateway := NewCloudControllerGateway()
+		gateway := NewCloudControllerGateway(testconfig.NewRepository())
 
 		ts := httptest.NewTLSServer(http.HandlerFunc(invalidTokenCloudControllerRequest))
 		defer ts.Close()
+		gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		request, apiResponse := gateway.NewRequest("GET", ts.URL, "TOKEN", nil)
 		Expect(apiResponse.IsNotSuccessful()).To(BeFalse())
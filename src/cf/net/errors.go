@@ -0,0 +1,145 @@
+package net
+
+import (
+	"cf"
+	"errors"
+	"fmt"
+)
+
+// The gateway errors below are what ApiResponse.Err() returns, so callers can
+// switch on the kind of failure instead of comparing status codes and parsing
+// messages. Errors wrapping another one expose it through Unwrap, which lets
+// errors.As reach e.g. the HttpError behind an AuthError.
+
+// HttpError is any non-2xx response. ErrorCode and Description come from the
+// error body of the Cloud Controller or UAA.
+type HttpError struct {
+	StatusCode  int
+	ErrorCode   string
+	Description string
+	Header      string
+	Body        string
+}
+
+func (err *HttpError) Error() string {
+	return fmt.Sprintf("Server error, status code: %d, error code: %s, message: %s", err.StatusCode, err.ErrorCode, err.Description)
+}
+
+// NetworkError means no response was received at all.
+type NetworkError struct {
+	Host    string
+	Timeout bool
+	Cause   error
+}
+
+func (err *NetworkError) Error() string {
+	if err.Timeout {
+		return fmt.Sprintf("Timed out waiting for a response from %s: %s\nTIP: Use the %s, %s or %s env vars to change the timeouts (in seconds)",
+			err.Host, err.Cause.Error(), CF_HTTP_TIMEOUT, CF_HTTP_CONNECT_TIMEOUT, CF_HTTP_TLS_TIMEOUT)
+	}
+	return fmt.Sprintf("Error performing request: %s", err.Cause.Error())
+}
+
+func (err *NetworkError) Unwrap() error {
+	return err.Cause
+}
+
+// InvalidSSLCertError is a network error caused by a certificate that could
+// not be verified.
+type InvalidSSLCertError struct {
+	Host string
+}
+
+func (err *InvalidSSLCertError) Error() string {
+	return fmt.Sprintf("Invalid SSL Cert for %s\nTIP: Use '%s api --skip-ssl-validation' to continue with an insecure API endpoint", err.Host, cf.Name())
+}
+
+// AuthError means the credentials or access token were rejected.
+type AuthError struct {
+	Message string
+	Cause   error
+}
+
+func (err *AuthError) Error() string {
+	return err.Message
+}
+
+func (err *AuthError) Unwrap() error {
+	return err.Cause
+}
+
+// IsAuthError tells whether err is an AuthError or wraps one.
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// NotFoundError means the resource asked for does not exist, either because
+// the server answered 404 or because a lookup came back empty.
+type NotFoundError struct {
+	Message string
+	Cause   error
+}
+
+func (err *NotFoundError) Error() string {
+	return err.Message
+}
+
+func (err *NotFoundError) Unwrap() error {
+	return err.Cause
+}
+
+// AsyncJobError means an asynchronous Cloud Controller job failed or did not
+// finish in time. ErrorCode, ErrorName and Description come from the job's
+// error_details, where ErrorName is its error_code, such as
+// CF-AppBitsUploadInvalid.
+type AsyncJobError struct {
+	JobUrl      string
+	ErrorCode   string
+	ErrorName   string
+	Description string
+	TimedOut    bool
+}
+
+func (err *AsyncJobError) Error() string {
+	if err.TimedOut {
+		return fmt.Sprintf("Error: timed out waiting for async job '%s' to finish", err.JobUrl)
+	}
+	if err.Description == "" {
+		return fmt.Sprintf("Error: async job '%s' failed", err.JobUrl)
+	}
+	if err.ErrorName == "" {
+		return fmt.Sprintf("Job failed, error code: %s, message: %s", err.ErrorCode, err.Description)
+	}
+	return fmt.Sprintf("Job failed, error code: %s (%s), message: %s", err.ErrorCode, err.ErrorName, err.Description)
+}
+
+// RequestError covers everything else that can go wrong around a request,
+// such as building it or decoding the response.
+type RequestError struct {
+	Message string
+	Cause   error
+}
+
+func (err *RequestError) Error() string {
+	if err.Cause == nil {
+		return err.Message
+	}
+	return fmt.Sprintf("%s: %s", err.Message, err.Cause.Error())
+}
+
+func (err *RequestError) Unwrap() error {
+	return err.Cause
+}
+
+// newHttpError picks the kind of error for a failed response: rejected tokens
+// and 401s are auth errors, 404s are not-found errors.
+func newHttpError(httpErr *HttpError) error {
+	switch {
+	case httpErr.StatusCode == 401 || httpErr.ErrorCode == INVALID_TOKEN_CODE:
+		return &AuthError{Message: httpErr.Error(), Cause: httpErr}
+	case httpErr.StatusCode == 404:
+		return &NotFoundError{Message: httpErr.Error(), Cause: httpErr}
+	}
+	return httpErr
+}
@@ -0,0 +1,124 @@
+package net_test
+
+import (
+	"cf"
+	. "cf/net"
+	"errors"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	testconfig "testhelpers/configuration"
+)
+
+var _ = Describe("gateway errors", func() {
+	var (
+		apiServer *httptest.Server
+		gateway   Gateway
+	)
+
+	BeforeEach(func() {
+		apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			switch request.URL.Path {
+			case "/v2/organizations":
+				writer.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(writer, `{"code":30002,"description":"The organization name is taken: my-org"}`)
+			case "/v2/apps/my-app-guid":
+				writer.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(writer, `{"code":100004,"description":"The app name could not be found: my-app-guid"}`)
+			case "/v2/spaces":
+				writer.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(writer, `{"code":10002,"description":"Authentication error"}`)
+			}
+		}))
+
+		gateway = NewCloudControllerGateway(testconfig.NewRepository())
+		gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	perform := func(path string) ApiResponse {
+		request, _ := gateway.NewRequest("GET", apiServer.URL+path, "BEARER my-access-token", nil)
+		return gateway.PerformRequest(request)
+	}
+
+	It("returns an HttpError carrying the CC error code and description", func() {
+		apiResponse := perform("/v2/organizations")
+
+		httpErr, ok := apiResponse.Err().(*HttpError)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(httpErr.ErrorCode).To(Equal(cf.ORG_EXISTS))
+		Expect(httpErr.Description).To(Equal("The organization name is taken: my-org"))
+		Expect(httpErr.Body).To(ContainSubstring("30002"))
+
+		Expect(apiResponse.Message).To(Equal(httpErr.Error()))
+		Expect(apiResponse.ErrorCode).To(Equal(cf.ORG_EXISTS))
+	})
+
+	It("returns a NotFoundError wrapping the HttpError for a 404", func() {
+		apiResponse := perform("/v2/apps/my-app-guid")
+
+		Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&NotFoundError{}))
+		Expect(apiResponse.IsNotFound()).To(BeTrue())
+
+		var httpErr *HttpError
+		Expect(errors.As(apiResponse.Err(), &httpErr)).To(BeTrue())
+		Expect(httpErr.ErrorCode).To(Equal("100004"))
+	})
+
+	It("returns an AuthError wrapping the HttpError for a 401", func() {
+		apiResponse := perform("/v2/spaces")
+
+		Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&AuthError{}))
+		Expect(apiResponse.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		var httpErr *HttpError
+		Expect(errors.As(apiResponse.Err(), &httpErr)).To(BeTrue())
+		Expect(httpErr.ErrorCode).To(Equal("10002"))
+	})
+
+	It("returns a NetworkError when the server cannot be reached", func() {
+		gateway.MaxRetries = 0
+		apiServer.Close()
+
+		apiResponse := perform("/v2/organizations")
+
+		networkErr, ok := apiResponse.Err().(*NetworkError)
+		Expect(ok).To(BeTrue())
+		Expect(networkErr.Timeout).To(BeFalse())
+		Expect(errors.Unwrap(networkErr)).NotTo(BeNil())
+		Expect(apiResponse.Message).To(ContainSubstring("Error performing request"))
+	})
+
+	It("keeps the cause of other errors", func() {
+		cause := errors.New("unexpected EOF")
+		apiResponse := NewApiResponseWithError("Error parsing JSON", cause)
+
+		Expect(apiResponse.Message).To(Equal("Error parsing JSON: unexpected EOF"))
+		Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&RequestError{}))
+		Expect(errors.Is(apiResponse.Err(), cause)).To(BeTrue())
+	})
+
+	It("finds auth errors wrapped in other errors", func() {
+		authErr := &AuthError{Message: "Password is incorrect, please try again."}
+
+		Expect(IsAuthError(authErr)).To(BeTrue())
+		Expect(IsAuthError(fmt.Errorf("logging in: %w", authErr))).To(BeTrue())
+		Expect(IsAuthError(&HttpError{StatusCode: http.StatusBadRequest})).To(BeFalse())
+		Expect(IsAuthError(nil)).To(BeFalse())
+	})
+
+	It("treats lookups that came back empty as not found rather than as errors", func() {
+		apiResponse := NewNotFoundApiResponse("%s %s not found", "App", "my-app")
+
+		Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&NotFoundError{}))
+		Expect(apiResponse.IsNotFound()).To(BeTrue())
+		Expect(apiResponse.IsError()).To(BeFalse())
+		Expect(apiResponse.Message).To(Equal("App my-app not found"))
+	})
+})
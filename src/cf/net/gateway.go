@@ -2,6 +2,9 @@ package net
 
 import (
 	"cf"
+	"cf/configuration"
+	"cf/terminal"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,7 +12,9 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,12 +24,30 @@ const (
 	JOB_FAILED               = "failed"
 	DEFAULT_POLLING_THROTTLE = 5 * time.Second
 	ASYNC_REQUEST_TIMEOUT    = 20 * time.Second
+	CF_ASYNC_TIMEOUT         = "CF_ASYNC_TIMEOUT"
+
+	DEFAULT_PAGE_FETCH_CONCURRENCY = 4
+
+	// tokens expiring within this window are refreshed before a request is
+	// sent, so that long uploads and job polling do not fail half way
+	DEFAULT_TOKEN_REFRESH_SKEW = 1 * time.Minute
 )
 
 type JobEntity struct {
-	Status string
+	Status       string
+	ErrorDetails JobErrorDetails `json:"error_details"`
 }
 
+type JobErrorDetails struct {
+	Code        int    `json:"code"`
+	ErrorCode   string `json:"error_code"`
+	Description string `json:"description"`
+}
+
+// JobProgressCallback is called every time a running async job is polled, and
+// once more with done set when polling stops.
+type JobProgressCallback func(elapsed time.Duration, done bool)
+
 type JobResponse struct {
 	Entity JobEntity
 }
@@ -50,6 +73,20 @@ type tokenRefresher interface {
 	RefreshAuthToken() (string, ApiResponse)
 }
 
+// reauthenticator is implemented by token refreshers that can ask the user to
+// log in again once the refresh token itself has been rejected.
+type reauthenticator interface {
+	Reauthenticate(ui terminal.UI) (string, ApiResponse)
+}
+
+// tokenRefresh lets requests sent concurrently with the same expired token,
+// such as the pages of a listing, share a single refresh and its new token.
+type tokenRefresh struct {
+	mutex      sync.Mutex
+	staleToken string
+	newToken   string
+}
+
 type Request struct {
 	HttpReq      *http.Request
 	SeekableBody io.ReadSeeker
@@ -58,13 +95,52 @@ type Request struct {
 type Gateway struct {
 	authenticator   tokenRefresher
 	errHandler      errorHandler
+	config          configuration.Reader
+	trustedCerts    []tls.Certificate
 	PollingEnabled  bool
 	PollingThrottle time.Duration
+	AsyncTimeout    time.Duration
+
+	TokenRefreshSkew time.Duration
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+
+	MaxRedirects         int
+	PageFetchConcurrency int
+	RateLimitMaxWait     time.Duration
+	RateLimitMaxRetries  int
+
+	ConnectTimeout      time.Duration
+	TLSHandshakeTimeout time.Duration
+	RequestTimeout      time.Duration
+
+	ui             terminal.UI
+	reauthenticate bool
+	jobProgress    JobProgressCallback
+	timings        *TimingsRecorder
+	transports     *transportCache
+	tokenRefresh   *tokenRefresh
 }
 
-func newGateway(errHandler errorHandler) (gateway Gateway) {
+func newGateway(errHandler errorHandler, config configuration.Reader) (gateway Gateway) {
 	gateway.errHandler = errHandler
+	gateway.config = config
 	gateway.PollingThrottle = DEFAULT_POLLING_THROTTLE
+	gateway.AsyncTimeout = timeoutFromEnv(CF_ASYNC_TIMEOUT, ASYNC_REQUEST_TIMEOUT)
+	gateway.TokenRefreshSkew = DEFAULT_TOKEN_REFRESH_SKEW
+	gateway.MaxRetries = maxRetriesFromEnv()
+	gateway.RetryBackoff = DEFAULT_RETRY_BACKOFF
+	gateway.RetryMaxBackoff = retryMaxBackoffFromEnv()
+	gateway.ConnectTimeout = timeoutFromEnv(CF_HTTP_CONNECT_TIMEOUT, DEFAULT_HTTP_CONNECT_TIMEOUT)
+	gateway.TLSHandshakeTimeout = timeoutFromEnv(CF_HTTP_TLS_TIMEOUT, DEFAULT_HTTP_TLS_TIMEOUT)
+	gateway.RequestTimeout = timeoutFromEnv(CF_HTTP_TIMEOUT, DEFAULT_HTTP_TIMEOUT)
+	gateway.MaxRedirects = maxRedirectsFromEnv()
+	gateway.PageFetchConcurrency = DEFAULT_PAGE_FETCH_CONCURRENCY
+	gateway.RateLimitMaxWait = timeoutFromEnv(CF_RATE_LIMIT_MAX_WAIT, DEFAULT_RATE_LIMIT_MAX_WAIT)
+	gateway.RateLimitMaxRetries = DEFAULT_RATE_LIMIT_MAX_RETRIES
+	gateway.transports = newTransportCache()
+	gateway.tokenRefresh = new(tokenRefresh)
 	return
 }
 
@@ -72,6 +148,29 @@ func (gateway *Gateway) SetTokenRefresher(auth tokenRefresher) {
 	gateway.authenticator = auth
 }
 
+func (gateway *Gateway) SetUI(ui terminal.UI) {
+	gateway.ui = ui
+}
+
+// EnableReauthentication lets interactive sessions prompt for the password
+// and retry the request when the session can no longer be refreshed.
+func (gateway *Gateway) EnableReauthentication() {
+	gateway.reauthenticate = true
+}
+
+func (gateway *Gateway) SetJobProgressCallback(callback JobProgressCallback) {
+	gateway.jobProgress = callback
+}
+
+func (gateway *Gateway) SetTimingsRecorder(recorder *TimingsRecorder) {
+	gateway.timings = recorder
+}
+
+func (gateway *Gateway) SetTrustedCerts(certificates []tls.Certificate) {
+	gateway.trustedCerts = certificates
+	gateway.transports = newTransportCache()
+}
+
 func (gateway Gateway) GetResource(url, accessToken string, resource interface{}) (apiResponse ApiResponse) {
 	request, apiResponse := gateway.NewRequest("GET", url, accessToken, nil)
 	if apiResponse.IsNotSuccessful() {
@@ -110,29 +209,111 @@ func (gateway Gateway) ListPaginatedResources(
 	cb func(interface{}) bool) (apiResponse ApiResponse) {
 
 	for path != "" {
-		pagination := NewPaginatedResources(resource)
-		apiResponse = gateway.GetResource(fmt.Sprintf("%s%s", target, path), accessToken, &pagination)
+		var pagination PaginatedResources
+		var resources []interface{}
+		pagination, resources, apiResponse = gateway.getPage(target, accessToken, path, resource)
 		if apiResponse.IsNotSuccessful() {
 			return
 		}
 
-		resources, err := pagination.Resources()
-		if err != nil {
-			return NewApiResponseWithError("Error parsing JSON", err)
-		}
-
 		for _, resource := range resources {
 			if !cb(resource) {
 				return
 			}
 		}
 
+		if gateway.PageFetchConcurrency > 1 {
+			pageURLs := pagination.RemainingPageURLs()
+			if len(pageURLs) > 1 {
+				return gateway.listPagesConcurrently(target, accessToken, pageURLs, resource, cb)
+			}
+		}
+
 		path = pagination.NextURL
 	}
 
 	return
 }
 
+type pageResult struct {
+	resources   []interface{}
+	apiResponse ApiResponse
+}
+
+// listPagesConcurrently fetches the pages with a bounded pool of workers but
+// hands their resources to the callback strictly in page order. Pages that
+// have not been requested yet are skipped once the callback returns false.
+func (gateway Gateway) listPagesConcurrently(
+	target string,
+	accessToken string,
+	pageURLs []string,
+	resource interface{},
+	cb func(interface{}) bool) (apiResponse ApiResponse) {
+
+	results := make([]chan pageResult, len(pageURLs))
+	for index := range results {
+		results[index] = make(chan pageResult, 1)
+	}
+
+	done := make(chan bool)
+	defer close(done)
+
+	pages := make(chan int)
+	go func() {
+		defer close(pages)
+		for index := range pageURLs {
+			select {
+			case pages <- index:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	workers := gateway.PageFetchConcurrency
+	if workers > len(pageURLs) {
+		workers = len(pageURLs)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range pages {
+				_, resources, apiResponse := gateway.getPage(target, accessToken, pageURLs[index], resource)
+				results[index] <- pageResult{resources: resources, apiResponse: apiResponse}
+			}
+		}()
+	}
+
+	for index := range pageURLs {
+		result := <-results[index]
+		if result.apiResponse.IsNotSuccessful() {
+			return result.apiResponse
+		}
+
+		for _, resource := range result.resources {
+			if !cb(resource) {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func (gateway Gateway) getPage(target, accessToken, path string, resource interface{}) (pagination PaginatedResources, resources []interface{}, apiResponse ApiResponse) {
+	pagination = NewPaginatedResources(resource)
+	apiResponse = gateway.GetResource(fmt.Sprintf("%s%s", target, path), accessToken, &pagination)
+	if apiResponse.IsNotSuccessful() {
+		return
+	}
+
+	resources, err := pagination.Resources()
+	if err != nil {
+		apiResponse = NewApiResponseWithError("Error parsing JSON", err)
+	}
+	return
+}
+
 func (gateway Gateway) createUpdateOrDeleteResource(verb, url, accessToken string, body io.ReadSeeker, resource interface{}) (apiResponse ApiResponse) {
 	request, apiResponse := gateway.NewRequest(verb, url, accessToken, body)
 	if apiResponse.IsNotSuccessful() {
@@ -144,7 +325,7 @@ func (gateway Gateway) createUpdateOrDeleteResource(verb, url, accessToken strin
 	}
 
 	if gateway.PollingEnabled {
-		_, apiResponse = gateway.PerformPollingRequestForJSONResponse(request, resource, ASYNC_REQUEST_TIMEOUT)
+		_, apiResponse = gateway.PerformPollingRequestForJSONResponse(request, resource, gateway.AsyncTimeout)
 		return
 	} else {
 		_, apiResponse = gateway.PerformRequestForJSONResponse(request, resource)
@@ -186,8 +367,18 @@ func (gateway Gateway) NewRequest(method, path, accessToken string, body io.Read
 	return
 }
 
+func (request *Request) rewindBody() {
+	if request.SeekableBody != nil {
+		request.SeekableBody.Seek(0, 0)
+		request.HttpReq.Body = ioutil.NopCloser(request.SeekableBody)
+	}
+}
+
 func (gateway Gateway) PerformRequest(request *Request) (apiResponse ApiResponse) {
-	_, apiResponse = gateway.doRequestHandlingAuth(request)
+	rawResponse, apiResponse := gateway.doRequestHandlingAuth(request)
+	if rawResponse != nil {
+		rawResponse.Body.Close()
+	}
 	return
 }
 
@@ -201,6 +392,8 @@ func (gateway Gateway) PerformRequestForResponseBytes(request *Request) (bytes [
 		return
 	}
 
+	defer rawResponse.Body.Close()
+
 	bytes, err := ioutil.ReadAll(rawResponse.Body)
 	if err != nil {
 		apiResponse = NewApiResponseWithError("Error reading response", err)
@@ -278,9 +471,15 @@ func (gateway Gateway) PerformPollingRequestForJSONResponse(request *Request, re
 
 func (gateway Gateway) waitForJob(jobUrl, accessToken string, timeout time.Duration) (apiResponse ApiResponse) {
 	startTime := time.Now()
+	if gateway.jobProgress != nil {
+		defer func() {
+			gateway.jobProgress(time.Since(startTime), true)
+		}()
+	}
+
 	for true {
 		if time.Since(startTime) > timeout {
-			apiResponse = NewApiResponseWithMessage("Error: timed out waiting for async job '%s' to finish", jobUrl)
+			apiResponse = NewApiResponseWithTypedError(&AsyncJobError{JobUrl: jobUrl, TimedOut: true})
 			return
 		}
 
@@ -297,10 +496,22 @@ func (gateway Gateway) waitForJob(jobUrl, accessToken string, timeout time.Durat
 		case JOB_FINISHED:
 			return
 		case JOB_FAILED:
-			apiResponse = NewApiResponse("Internal Server Error", "", 500)
+			jobErr := &AsyncJobError{
+				JobUrl:      jobUrl,
+				ErrorName:   response.Entity.ErrorDetails.ErrorCode,
+				Description: response.Entity.ErrorDetails.Description,
+			}
+			if response.Entity.ErrorDetails.Code != 0 {
+				jobErr.ErrorCode = strconv.Itoa(response.Entity.ErrorDetails.Code)
+			}
+			apiResponse = NewApiResponseWithTypedError(jobErr)
 			return
 		}
 
+		if gateway.jobProgress != nil {
+			gateway.jobProgress(time.Since(startTime), false)
+		}
+
 		accessToken = request.HttpReq.Header.Get("Authorization")
 
 		time.Sleep(gateway.PollingThrottle)
@@ -315,6 +526,17 @@ func (gateway Gateway) doRequestHandlingAuth(request *Request) (rawResponse *htt
 		httpReq.Body = ioutil.NopCloser(request.SeekableBody)
 	}
 
+	// refresh a token that is about to expire up front, rather than sending
+	// the body twice after the server rejects it
+	if gateway.authenticator != nil && configuration.NewTokenInfo(httpReq.Header.Get("Authorization")).ExpiresWithin(gateway.TokenRefreshSkew) {
+		var newToken string
+		newToken, apiResponse = gateway.refreshAuthTokenOnce(httpReq.Header.Get("Authorization"))
+		if apiResponse.IsNotSuccessful() {
+			return
+		}
+		httpReq.Header.Set("Authorization", newToken)
+	}
+
 	// perform request
 	rawResponse, apiResponse = gateway.doRequestAndHandlerError(request)
 	if apiResponse.IsSuccessful() || gateway.authenticator == nil {
@@ -326,41 +548,167 @@ func (gateway Gateway) doRequestHandlingAuth(request *Request) (rawResponse *htt
 	}
 
 	// refresh the auth token
-	newToken, apiResponse := gateway.authenticator.RefreshAuthToken()
+	newToken, apiResponse := gateway.refreshAuthTokenOnce(httpReq.Header.Get("Authorization"))
 	if apiResponse.IsNotSuccessful() {
 		return
 	}
 
 	// reset the auth token and request body
 	httpReq.Header.Set("Authorization", newToken)
-	if request.SeekableBody != nil {
-		request.SeekableBody.Seek(0, 0)
-		httpReq.Body = ioutil.NopCloser(request.SeekableBody)
-	}
+	request.rewindBody()
 
 	// make the request again
 	rawResponse, apiResponse = gateway.doRequestAndHandlerError(request)
 	return
 }
 
+// refreshAuthTokenOnce refreshes the stale token, unless another request has
+// refreshed it already or is refreshing it, in which case its new token is
+// returned.
+func (gateway Gateway) refreshAuthTokenOnce(staleToken string) (newToken string, apiResponse ApiResponse) {
+	if gateway.tokenRefresh == nil {
+		return gateway.refreshAuthToken()
+	}
+
+	gateway.tokenRefresh.mutex.Lock()
+	defer gateway.tokenRefresh.mutex.Unlock()
+
+	if gateway.tokenRefresh.newToken != "" && gateway.tokenRefresh.staleToken == staleToken {
+		newToken = gateway.tokenRefresh.newToken
+		return
+	}
+
+	newToken, apiResponse = gateway.refreshAuthToken()
+	if apiResponse.IsSuccessful() {
+		gateway.tokenRefresh.staleToken = staleToken
+		gateway.tokenRefresh.newToken = newToken
+	}
+	return
+}
+
+// refreshAuthToken returns an AuthError when the session has ended, unless the
+// user logs in again when reauthentication is enabled.
+func (gateway Gateway) refreshAuthToken() (newToken string, apiResponse ApiResponse) {
+	newToken, apiResponse = gateway.authenticator.RefreshAuthToken()
+	if apiResponse.IsSuccessful() || !gateway.reauthenticate || gateway.ui == nil {
+		return
+	}
+
+	if !IsAuthError(apiResponse.Err()) {
+		return
+	}
+
+	reauth, ok := gateway.authenticator.(reauthenticator)
+	if !ok {
+		return
+	}
+
+	return reauth.Reauthenticate(gateway.ui)
+}
+
 func (gateway Gateway) doRequestAndHandlerError(request *Request) (rawResponse *http.Response, apiResponse ApiResponse) {
-	rawResponse, err := doRequest(request.HttpReq)
+	httpClient, apiResponse := gateway.newHttpClient()
+	if apiResponse.IsNotSuccessful() {
+		return
+	}
+
+	var err error
+	var retries, rateLimitRetries int
+	var rateLimitWait time.Duration
+	for {
+		startTime := time.Now()
+		rawResponse, err = doRequest(request.HttpReq, httpClient)
+		rawResponse = gateway.timings.track(request.HttpReq, rawResponse, err, startTime)
+
+		var wait time.Duration
+		if isRateLimited(request.HttpReq, rawResponse) {
+			if rateLimitRetries >= gateway.RateLimitMaxRetries {
+				break
+			}
+
+			// a Retry-After that is missing, zero or in the past falls back
+			// to the backoff, so a rate limited request is not hammered
+			wait, _ = retryAfter(rawResponse)
+			if wait <= 0 {
+				wait = gateway.retryBackoff(rateLimitRetries)
+			}
+
+			if rateLimitWait+wait > gateway.RateLimitMaxWait {
+				break
+			}
+
+			rateLimitRetries++
+			rateLimitWait += wait
+			gateway.sayRateLimited(wait)
+			traceRetry(request.HttpReq, rateLimitRetries, gateway.RateLimitMaxRetries+1, retryReason(rawResponse, err), wait)
+		} else {
+			if retries >= gateway.MaxRetries || !isRetryable(request.HttpReq, rawResponse, err) {
+				break
+			}
+
+			wait = gateway.retryBackoff(retries)
+			retries++
+			traceRetry(request.HttpReq, retries, gateway.MaxRetries+1, retryReason(rawResponse, err), wait)
+		}
+
+		if rawResponse != nil {
+			rawResponse.Body.Close()
+		}
+
+		time.Sleep(wait)
+		request.rewindBody()
+	}
+
 	if err != nil {
-		apiResponse = NewApiResponseWithError("Error performing request", err)
+		if isCertificateError(err) {
+			apiResponse = NewInvalidSSLCertApiResponse(request.HttpReq.URL.Host)
+			return
+		}
+		apiResponse = NewNetworkErrorApiResponse(request.HttpReq.URL.Host, err)
 		return
 	}
 
 	if rawResponse.StatusCode > 299 {
 		errorResponse := gateway.errHandler(rawResponse)
-		message := fmt.Sprintf(
-			"Server error, status code: %d, error code: %s, message: %s",
-			rawResponse.StatusCode,
-			errorResponse.Code,
-			errorResponse.Description,
-		)
-		apiResponse = NewApiResponseWithHttpError(message, errorResponse.Code, rawResponse.StatusCode, errorResponse.ResponseHeader, errorResponse.ResponseBody)
+		apiResponse = NewApiResponseWithHttpError(&HttpError{
+			StatusCode:  rawResponse.StatusCode,
+			ErrorCode:   errorResponse.Code,
+			Description: errorResponse.Description,
+			Header:      errorResponse.ResponseHeader,
+			Body:        errorResponse.ResponseBody,
+		})
 	} else {
 		apiResponse = NewApiResponseWithStatusCode(rawResponse.StatusCode)
 	}
 	return
 }
+
+func (gateway Gateway) newHttpClient() (httpClient *http.Client, apiResponse ApiResponse) {
+	transports := gateway.transports
+	if transports == nil {
+		transports = newTransportCache()
+	}
+
+	settings := transportSettings{
+		sslDisabled:         gateway.config.IsSSLDisabled(),
+		caCertFile:          gateway.config.CACertFile(),
+		connectTimeout:      gateway.ConnectTimeout,
+		tlsHandshakeTimeout: gateway.TLSHandshakeTimeout,
+	}
+
+	transport, err := transports.transportFor(settings, gateway.config, gateway.trustedCerts)
+	if err != nil {
+		apiResponse = NewApiResponseWithError("Error configuring TLS", err)
+		return
+	}
+
+	roundTripper, err := cassetteTransportFromEnv(transport)
+	if err != nil {
+		apiResponse = NewApiResponseWithError("Error setting up record/replay", err)
+		return
+	}
+
+	redirectPolicy := NewRedirectPolicy(gateway.MaxRedirects, gateway.config)
+	httpClient = newHttpClient(roundTripper, redirectPolicy, gateway.RequestTimeout)
+	return
+}
@@ -1,10 +1,15 @@
 package net_test
 
 import (
+	"bytes"
 	"cf"
 	"cf/api"
 	"cf/configuration"
 	. "cf/net"
+	"cf/terminal"
+	"cf/trace"
+	"crypto/tls"
+	"encoding/pem"
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,9 +18,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testnet "testhelpers/net"
+	testterm "testhelpers/terminal"
 	"time"
 )
 
@@ -73,6 +82,7 @@ func testRefreshToken(gateway Gateway, endpoint http.HandlerFunc) (config config
 
 	config, auth := createAuthenticationRepository(apiServer, authServer)
 	gateway.SetTokenRefresher(auth)
+	gateway.SetTrustedCerts(apiServer.TLS.Certificates)
 
 	request, apiResponse := gateway.NewRequest("POST", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), strings.NewReader("expected body"))
 	Expect(apiResponse.IsNotSuccessful()).To(BeFalse())
@@ -88,7 +98,8 @@ func createAuthenticationRepository(apiServer *httptest.Server, authServer *http
 	config.SetAccessToken("bearer initial-access-token")
 	config.SetRefreshToken("initial-refresh-token")
 
-	authGateway := NewUAAGateway()
+	authGateway := NewUAAGateway(config)
+	authGateway.SetTrustedCerts(authServer.TLS.Certificates)
 	authenticator := api.NewUAAAuthenticationRepository(authGateway, config)
 
 	return config, authenticator
@@ -101,8 +112,9 @@ var _ = Describe("Testing with ginkgo", func() {
 	var authRepo api.AuthenticationRepository
 
 	BeforeEach(func() {
-		ccGateway = NewCloudControllerGateway()
-		uaaGateway = NewUAAGateway()
+		config = testconfig.NewRepository()
+		ccGateway = NewCloudControllerGateway(config)
+		uaaGateway = NewUAAGateway(config)
 	})
 
 	It("TestNewRequest", func() {
@@ -127,6 +139,11 @@ var _ = Describe("Testing with ginkgo", func() {
 				case "/v2/foo":
 					fmt.Fprintln(writer, `{ "metadata": { "url": "/v2/jobs/the-job-guid" } }`)
 				case "/v2/jobs/the-job-guid":
+					if jobStatus == "failed" {
+						fmt.Fprint(writer, `{ "entity": { "status": "failed", "error_details": {
+							"code": 170001, "error_code": "CF-StagingError", "description": "Staging error: no buildpack detected" } } }`)
+						return
+					}
 					fmt.Fprintf(writer, `{ "entity": { "status": "%s" } }`, jobStatus)
 				default:
 					writer.WriteHeader(http.StatusInternalServerError)
@@ -138,6 +155,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 			config, authRepo = createAuthenticationRepository(apiServer, authServer)
 			ccGateway.SetTokenRefresher(authRepo)
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
 			ccGateway.PollingThrottle = 3 * time.Millisecond
 		})
 
@@ -162,6 +180,57 @@ var _ = Describe("Testing with ginkgo", func() {
 			_, apiResponse := ccGateway.PerformPollingRequestForJSONResponse(request, new(struct{}), 10*time.Millisecond)
 			Expect(apiResponse.IsSuccessful()).To(BeFalse())
 			Expect(apiResponse.Message).To(ContainSubstring("timed out"))
+
+			jobErr, ok := apiResponse.Err().(*AsyncJobError)
+			Expect(ok).To(BeTrue())
+			Expect(jobErr.TimedOut).To(BeTrue())
+			Expect(jobErr.JobUrl).To(Equal(config.ApiEndpoint() + "/v2/jobs/the-job-guid"))
+		})
+
+		It("returns the error details reported by the failed job", func() {
+			jobStatus = "failed"
+
+			request, _ := ccGateway.NewRequest("GET", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), nil)
+			_, apiResponse := ccGateway.PerformPollingRequestForJSONResponse(request, new(struct{}), 100*time.Millisecond)
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.ErrorCode).To(Equal("170001"))
+			Expect(apiResponse.Message).To(Equal("Job failed, error code: 170001 (CF-StagingError), message: Staging error: no buildpack detected"))
+
+			jobErr, ok := apiResponse.Err().(*AsyncJobError)
+			Expect(ok).To(BeTrue())
+			Expect(jobErr.TimedOut).To(BeFalse())
+			Expect(jobErr.ErrorName).To(Equal("CF-StagingError"))
+			Expect(jobErr.Description).To(Equal("Staging error: no buildpack detected"))
+		})
+
+		It("uses the gateway's async timeout for deletes", func() {
+			ccGateway.AsyncTimeout = 10 * time.Millisecond
+
+			apiResponse := ccGateway.DeleteResource(config.ApiEndpoint()+"/v2/foo", config.AccessToken())
+
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&AsyncJobError{}))
+			Expect(apiResponse.Message).To(ContainSubstring("timed out"))
+		})
+
+		It("reports progress while polling and once more when done", func() {
+			var progress []bool
+			ccGateway.SetJobProgressCallback(func(elapsed time.Duration, done bool) {
+				progress = append(progress, done)
+			})
+
+			go func() {
+				time.Sleep(25 * time.Millisecond)
+				jobStatus = "finished"
+			}()
+
+			request, _ := ccGateway.NewRequest("GET", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), nil)
+			_, apiResponse := ccGateway.PerformPollingRequestForJSONResponse(request, new(struct{}), 2*time.Second)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(len(progress)).To(BeNumerically(">", 1))
+			Expect(progress[len(progress)-1]).To(BeTrue())
+			Expect(progress[0]).To(BeFalse())
 		})
 	})
 
@@ -190,6 +259,7 @@ var _ = Describe("Testing with ginkgo", func() {
 
 			config, auth := createAuthenticationRepository(apiServer, authServer)
 			ccGateway.SetTokenRefresher(auth)
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
 			request, apiResponse = ccGateway.NewRequest("POST", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), fileToUpload)
 		})
 
@@ -214,6 +284,628 @@ var _ = Describe("Testing with ginkgo", func() {
 		})
 	})
 
+	Describe("retrying transient failures", func() {
+		var apiServer *httptest.Server
+		var requestCount int
+		var failuresBeforeSuccess int
+		var requestBodies []string
+
+		BeforeEach(func() {
+			requestCount = 0
+			failuresBeforeSuccess = 2
+			requestBodies = []string{}
+
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requestCount++
+				body, _ := ioutil.ReadAll(request.Body)
+				requestBodies = append(requestBodies, string(body))
+
+				if requestCount <= failuresBeforeSuccess {
+					writer.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprintln(writer, `{}`)
+			}))
+
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			ccGateway.RetryBackoff = time.Millisecond
+			ccGateway.RetryMaxBackoff = 2 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("retries idempotent requests until they succeed", func() {
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(3))
+		})
+
+		It("rewinds the request body between attempts", func() {
+			request, _ := ccGateway.NewRequest("PUT", apiServer.URL+"/v2/foo", "", strings.NewReader("expected body"))
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestBodies).To(Equal([]string{"expected body", "expected body", "expected body"}))
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			ccGateway.MaxRetries = 1
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeFalse())
+			Expect(apiResponse.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(requestCount).To(Equal(2))
+		})
+
+		It("does not retry requests that are not idempotent", func() {
+			request, _ := ccGateway.NewRequest("POST", apiServer.URL+"/v2/foo", "", strings.NewReader("expected body"))
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeFalse())
+			Expect(requestCount).To(Equal(1))
+		})
+
+		It("does not retry client errors", func() {
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiServer.Config.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requestCount++
+				writer.WriteHeader(http.StatusBadRequest)
+			})
+
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeFalse())
+			Expect(requestCount).To(Equal(1))
+		})
+
+		It("retries requests whose connection is closed before the response", func() {
+			apiServer.Config.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requestCount++
+				if requestCount == 1 {
+					conn, _, _ := writer.(http.Hijacker).Hijack()
+					conn.Close()
+					return
+				}
+				fmt.Fprintln(writer, `{}`)
+			})
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(2))
+		})
+
+		It("does not retry requests that fail to connect", func() {
+			stdOut := bytes.NewBuffer([]byte{})
+			trace.SetStdout(stdOut)
+			trace.EnableTrace()
+			defer func() {
+				trace.DisableTrace()
+				trace.SetStdout(os.Stdout)
+			}()
+
+			apiServer.Close()
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeFalse())
+			Expect(apiResponse.Message).To(ContainSubstring("Error performing request"))
+			Expect(stdOut.String()).NotTo(ContainSubstring("RETRYING:"))
+		})
+	})
+
+	Describe("rate limiting", func() {
+		var apiServer *httptest.Server
+		var requestCount int
+		var rateLimitedRequests int
+		var retryAfter string
+		var status int
+		var ui *testterm.FakeUI
+
+		BeforeEach(func() {
+			requestCount = 0
+			rateLimitedRequests = 1
+			retryAfter = "0"
+			status = http.StatusTooManyRequests
+
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requestCount++
+				if requestCount <= rateLimitedRequests {
+					writer.Header().Set("Retry-After", retryAfter)
+					writer.WriteHeader(status)
+					return
+				}
+				fmt.Fprintln(writer, `{}`)
+			}))
+
+			ui = &testterm.FakeUI{}
+			ccGateway.SetUI(ui)
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			ccGateway.MaxRetries = 2
+			ccGateway.RetryBackoff = time.Millisecond
+			ccGateway.RetryMaxBackoff = 10 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("waits as long as Retry-After says and tries again", func() {
+			retryAfter = "1"
+
+			request, _ := ccGateway.NewRequest("PUT", apiServer.URL+"/v2/foo", "", strings.NewReader("expected body"))
+			startTime := time.Now()
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(2))
+			Expect(time.Since(startTime)).To(BeNumerically(">=", time.Second))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"rate limited, retrying in 1s"},
+			})
+		})
+
+		It("honors Retry-After on a 503", func() {
+			status = http.StatusServiceUnavailable
+			rateLimitedRequests = 2
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(3))
+			Expect(len(ui.Outputs)).To(Equal(2))
+		})
+
+		It("understands Retry-After given as an HTTP date", func() {
+			retryAfter = time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(2))
+		})
+
+		It("gives up once the total wait would exceed the cap", func() {
+			retryAfter = "12"
+			ccGateway.RateLimitMaxWait = 10 * time.Second
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(requestCount).To(Equal(1))
+			Expect(ui.Outputs).To(BeEmpty())
+		})
+
+		It("backs off on Retry-After: 0 and gives up after the max rate limit retries", func() {
+			rateLimitedRequests = 10
+			ccGateway.RateLimitMaxRetries = 2
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(requestCount).To(Equal(3))
+			Expect(len(ui.Outputs)).To(Equal(2))
+		})
+
+		It("sends requests that are not idempotent again after a 429", func() {
+			request, _ := ccGateway.NewRequest("POST", apiServer.URL+"/v2/foo", "", strings.NewReader("expected body"))
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(2))
+		})
+
+		It("does not replay requests that are not idempotent after a 503", func() {
+			status = http.StatusServiceUnavailable
+
+			request, _ := ccGateway.NewRequest("POST", apiServer.URL+"/v2/foo", "", strings.NewReader("expected body"))
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(requestCount).To(Equal(1))
+		})
+
+		It("waits for Retry-After even when retries of failed requests are disabled", func() {
+			ccGateway.MaxRetries = 0
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(requestCount).To(Equal(2))
+		})
+	})
+
+	Describe("connection handling", func() {
+		var apiServer *httptest.Server
+		var connections map[string]bool
+		var responseDelay time.Duration
+
+		BeforeEach(func() {
+			connections = map[string]bool{}
+			responseDelay = 0
+
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				connections[request.RemoteAddr] = true
+				time.Sleep(responseDelay)
+				fmt.Fprintln(writer, `{}`)
+			}))
+
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			ccGateway.MaxRetries = 0
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("reuses connections between requests", func() {
+			for i := 0; i < 3; i++ {
+				request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+				_, apiResponse := ccGateway.PerformRequestForJSONResponse(request, &struct{}{})
+				Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			}
+
+			Expect(len(connections)).To(Equal(1))
+		})
+
+		It("returns a clear error when the request times out", func() {
+			responseDelay = 200 * time.Millisecond
+			ccGateway.RequestTimeout = 50 * time.Millisecond
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.Message).To(ContainSubstring("Timed out waiting for a response from " + apiServer.Listener.Addr().String()))
+			Expect(apiResponse.Message).To(ContainSubstring(CF_HTTP_TIMEOUT))
+
+			networkErr, ok := apiResponse.Err().(*NetworkError)
+			Expect(ok).To(BeTrue())
+			Expect(networkErr.Timeout).To(BeTrue())
+			Expect(networkErr.Cause).NotTo(BeNil())
+		})
+	})
+
+	Describe("listing paginated resources", func() {
+		type pagedResource struct {
+			Name string
+		}
+
+		var apiServer *httptest.Server
+		var requestedPages []string
+		var requestedPagesMutex sync.Mutex
+		var failingPage string
+
+		BeforeEach(func() {
+			requestedPages = []string{}
+			failingPage = ""
+
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				page := request.URL.Query().Get("page")
+				if page == "" {
+					page = "1"
+				}
+
+				requestedPagesMutex.Lock()
+				requestedPages = append(requestedPages, page)
+				requestedPagesMutex.Unlock()
+
+				if page == failingPage {
+					writer.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintln(writer, `{"code": 10001, "description": "bad page"}`)
+					return
+				}
+
+				// later pages answer faster, to make sure the callback order
+				// doesn't depend on the order the responses come back in
+				pageNumber, _ := strconv.Atoi(page)
+				time.Sleep(time.Duration(6-pageNumber) * 5 * time.Millisecond)
+
+				nextUrl := ""
+				if pageNumber < 5 {
+					nextUrl = fmt.Sprintf("/v2/things?q=name%%3Afoo&page=%d&results-per-page=2", pageNumber+1)
+				}
+				fmt.Fprintf(writer, `{
+					"total_pages": 5,
+					"next_url": "%s",
+					"resources": [{"name": "thing-%s-a"}, {"name": "thing-%s-b"}]
+				}`, nextUrl, page, page)
+			}))
+
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			ccGateway.PageFetchConcurrency = 3
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("fetches the remaining pages concurrently and calls back in page order", func() {
+			names := []string{}
+			apiResponse := ccGateway.ListPaginatedResources(apiServer.URL, "", "/v2/things?q=name%3Afoo&results-per-page=2", pagedResource{}, func(resource interface{}) bool {
+				names = append(names, resource.(pagedResource).Name)
+				return true
+			})
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(names).To(Equal([]string{
+				"thing-1-a", "thing-1-b",
+				"thing-2-a", "thing-2-b",
+				"thing-3-a", "thing-3-b",
+				"thing-4-a", "thing-4-b",
+				"thing-5-a", "thing-5-b",
+			}))
+			Expect(len(requestedPages)).To(Equal(5))
+		})
+
+		It("stops calling back when the callback returns false", func() {
+			names := []string{}
+			apiResponse := ccGateway.ListPaginatedResources(apiServer.URL, "", "/v2/things?q=name%3Afoo&results-per-page=2", pagedResource{}, func(resource interface{}) bool {
+				names = append(names, resource.(pagedResource).Name)
+				return len(names) < 3
+			})
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(names).To(Equal([]string{"thing-1-a", "thing-1-b", "thing-2-a"}))
+		})
+
+		It("returns the error of the first page that fails after delivering the earlier pages", func() {
+			failingPage = "3"
+
+			names := []string{}
+			apiResponse := ccGateway.ListPaginatedResources(apiServer.URL, "", "/v2/things?q=name%3Afoo&results-per-page=2", pagedResource{}, func(resource interface{}) bool {
+				names = append(names, resource.(pagedResource).Name)
+				return true
+			})
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.ErrorCode).To(Equal("10001"))
+			Expect(names).To(Equal([]string{"thing-1-a", "thing-1-b", "thing-2-a", "thing-2-b"}))
+		})
+
+		It("follows next_url one page at a time when concurrency is disabled", func() {
+			ccGateway.PageFetchConcurrency = 1
+
+			names := []string{}
+			apiResponse := ccGateway.ListPaginatedResources(apiServer.URL, "", "/v2/things?q=name%3Afoo&results-per-page=2", pagedResource{}, func(resource interface{}) bool {
+				names = append(names, resource.(pagedResource).Name)
+				return true
+			})
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(len(names)).To(Equal(10))
+			Expect(requestedPages).To(Equal([]string{"1", "2", "3", "4", "5"}))
+		})
+	})
+
+	Describe("SSL certificate validation", func() {
+		var apiServer *httptest.Server
+
+		BeforeEach(func() {
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				fmt.Fprintln(writer, `{}`)
+			}))
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("fails with an invalid SSL cert error when the server certificate is not trusted", func() {
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsInvalidSSLCert()).To(BeTrue())
+			Expect(apiResponse.Message).To(ContainSubstring("Invalid SSL Cert"))
+			Expect(apiResponse.Message).To(ContainSubstring("--skip-ssl-validation"))
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&InvalidSSLCertError{}))
+		})
+
+		It("succeeds when SSL validation is disabled", func() {
+			config.SetSSLDisabled(true)
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		})
+
+		It("trusts certificates from the configured CA bundle", func() {
+			caFile, err := ioutil.TempFile("", "ca-bundle")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(caFile.Name())
+
+			pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: apiServer.TLS.Certificates[0].Certificate[0]})
+			caFile.Close()
+			config.SetCACertFile(caFile.Name())
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		})
+
+		It("skips trusted certificates without certificate data", func() {
+			ccGateway.SetTrustedCerts([]tls.Certificate{{}, apiServer.TLS.Certificates[0]})
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		})
+
+		It("returns an error when the CA bundle cannot be read", func() {
+			config.SetCACertFile("/path/to/nowhere.pem")
+
+			request, _ := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "", nil)
+			apiResponse := ccGateway.PerformRequest(request)
+
+			Expect(apiResponse.IsNotSuccessful()).To(BeTrue())
+			Expect(apiResponse.Message).To(ContainSubstring("/path/to/nowhere.pem"))
+		})
+	})
+
+	Describe("refreshing tokens before they expire", func() {
+		var (
+			apiServer   *httptest.Server
+			authServer  *httptest.Server
+			authHeaders []string
+			authCalls   int
+			authMutex   sync.Mutex
+		)
+
+		BeforeEach(func() {
+			authHeaders = []string{}
+			authCalls = 0
+
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				authMutex.Lock()
+				authHeaders = append(authHeaders, request.Header.Get("Authorization"))
+				authMutex.Unlock()
+				fmt.Fprintln(writer, `{}`)
+			}))
+			authServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				authMutex.Lock()
+				authCalls++
+				authMutex.Unlock()
+				fmt.Fprintln(writer, `{ "access_token": "new-access-token", "token_type": "bearer", "refresh_token": "new-refresh-token"}`)
+			}))
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+			authServer.Close()
+		})
+
+		setUpTokenExpiringIn := func(expiresIn time.Duration) configuration.ReadWriter {
+			config, auth := createAuthenticationRepository(apiServer, authServer)
+			accessToken, err := testconfig.EncodeAccessToken(configuration.TokenInfo{
+				Username:  "my-user",
+				ExpiresAt: time.Now().Add(expiresIn).Unix(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			config.SetAccessToken(accessToken)
+
+			ccGateway.SetTokenRefresher(auth)
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			return config
+		}
+
+		performRequestWithTokenExpiringIn := func(expiresIn time.Duration) {
+			config := setUpTokenExpiringIn(expiresIn)
+
+			request, _ := ccGateway.NewRequest("PUT", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), strings.NewReader("expected body"))
+			apiResponse := ccGateway.PerformRequest(request)
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		}
+
+		It("refreshes a token expiring within the skew window before sending the request", func() {
+			performRequestWithTokenExpiringIn(10 * time.Second)
+
+			Expect(authCalls).To(Equal(1))
+			Expect(authHeaders).To(Equal([]string{"bearer new-access-token"}))
+		})
+
+		It("refreshes a token that has already expired", func() {
+			performRequestWithTokenExpiringIn(-time.Hour)
+
+			Expect(authCalls).To(Equal(1))
+			Expect(authHeaders).To(Equal([]string{"bearer new-access-token"}))
+		})
+
+		It("refreshes the token once for concurrent requests sent with it", func() {
+			config := setUpTokenExpiringIn(10 * time.Second)
+			accessToken := config.AccessToken()
+
+			wg := sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+
+					request, _ := ccGateway.NewRequest("GET", config.ApiEndpoint()+"/v2/foo", accessToken, nil)
+					apiResponse := ccGateway.PerformRequest(request)
+					Expect(apiResponse.IsSuccessful()).To(BeTrue())
+				}()
+			}
+			wg.Wait()
+
+			Expect(authCalls).To(Equal(1))
+			Expect(authHeaders).To(Equal([]string{
+				"bearer new-access-token", "bearer new-access-token", "bearer new-access-token", "bearer new-access-token",
+			}))
+		})
+
+		It("keeps using a token that is not about to expire", func() {
+			performRequestWithTokenExpiringIn(time.Hour)
+
+			Expect(authCalls).To(Equal(0))
+			Expect(len(authHeaders)).To(Equal(1))
+			Expect(authHeaders[0]).NotTo(Equal("bearer new-access-token"))
+		})
+	})
+
+	Describe("when the session cannot be refreshed", func() {
+		var (
+			apiServer *httptest.Server
+			refresher *fakeReauthenticator
+		)
+
+		BeforeEach(func() {
+			apiServer = httptest.NewTLSServer(refreshTokenApiEndPoint(
+				`{ "code": 1000, "description": "Auth token is invalid" }`,
+				testnet.TestResponse{Status: http.StatusOK, Body: `{}`},
+			))
+			refresher = &fakeReauthenticator{}
+
+			config.SetApiEndpoint(apiServer.URL)
+			config.SetAccessToken("bearer initial-access-token")
+			ccGateway.SetTokenRefresher(refresher)
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			ccGateway.SetUI(&testterm.FakeUI{})
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		performRequest := func() ApiResponse {
+			request, _ := ccGateway.NewRequest("POST", config.ApiEndpoint()+"/v2/foo", config.AccessToken(), strings.NewReader("expected body"))
+			return ccGateway.PerformRequest(request)
+		}
+
+		It("returns the auth error", func() {
+			apiResponse := performRequest()
+
+			Expect(apiResponse.Err()).To(BeAssignableToTypeOf(&AuthError{}))
+			Expect(refresher.reauthenticated).To(BeFalse())
+		})
+
+		It("lets the user log in again and retries the request when enabled", func() {
+			ccGateway.EnableReauthentication()
+			apiResponse := performRequest()
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(refresher.reauthenticated).To(BeTrue())
+		})
+	})
+
 	It("TestRefreshingTheTokenWithUAARequest", func() {
 		endpoint := refreshTokenApiEndPoint(
 			`{ "error": "invalid_token", "error_description": "Auth token is invalid" }`,
@@ -254,3 +946,16 @@ var _ = Describe("Testing with ginkgo", func() {
 		testRefreshTokenWithError(ccGateway, endpoint)
 	})
 })
+
+type fakeReauthenticator struct {
+	reauthenticated bool
+}
+
+func (auth *fakeReauthenticator) RefreshAuthToken() (string, ApiResponse) {
+	return "", NewApiResponseWithTypedError(&AuthError{Message: "Not logged in"})
+}
+
+func (auth *fakeReauthenticator) Reauthenticate(ui terminal.UI) (string, ApiResponse) {
+	auth.reauthenticated = true
+	return "bearer new-access-token", NewSuccessfulApiResponse()
+}
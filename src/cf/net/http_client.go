@@ -1,11 +1,14 @@
 package net
 
 import (
+	"cf/configuration"
 	"cf/terminal"
 	"cf/trace"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"regexp"
@@ -17,29 +20,68 @@ const (
 	PRIVATE_DATA_PLACEHOLDER = "[PRIVATE DATA HIDDEN]"
 )
 
-func newHttpClient() *http.Client {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		Proxy:           http.ProxyFromEnvironment,
-	}
+func newHttpClient(transport http.RoundTripper, redirectPolicy RedirectPolicy, timeout time.Duration) *http.Client {
 	return &http.Client{
-		Transport:     tr,
-		CheckRedirect: PrepareRedirect,
+		Transport:     transport,
+		CheckRedirect: redirectPolicy.CheckRedirect,
+		Timeout:       timeout,
 	}
 }
 
-func PrepareRedirect(req *http.Request, via []*http.Request) error {
-	if len(via) > 1 {
-		return errors.New("stopped after 1 redirect")
+// NewTLSConfig builds the TLS settings shared by the gateways and the
+// loggregator websocket. Certificates are verified against the system roots
+// plus any CA bundle configured with `cf api --ca-cert` and the given trusted
+// certificates, unless SSL validation has been disabled for the target.
+func NewTLSConfig(config configuration.Reader, trustedCerts []tls.Certificate) (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{InsecureSkipVerify: config.IsSSLDisabled()}
+
+	caCertFile := config.CACertFile()
+	if caCertFile == "" && len(trustedCerts) == 0 {
+		return
+	}
+
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		certPool = x509.NewCertPool()
+	}
+
+	if caCertFile != "" {
+		var pemBytes []byte
+		pemBytes, err = ioutil.ReadFile(caCertFile)
+		if err != nil {
+			err = errors.New(fmt.Sprintf("Error reading CA certificates from %s: %s", caCertFile, err))
+			return
+		}
+
+		if !certPool.AppendCertsFromPEM(pemBytes) {
+			err = errors.New(fmt.Sprintf("No PEM encoded CA certificates found in %s", caCertFile))
+			return
+		}
 	}
 
-	prevReq := via[len(via)-1]
+	for _, tlsCert := range trustedCerts {
+		if len(tlsCert.Certificate) == 0 {
+			continue
+		}
 
-	req.Header.Set("Authorization", prevReq.Header.Get("Authorization"))
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(tlsCert.Certificate[0])
+		if err != nil {
+			return
+		}
+		certPool.AddCert(cert)
+	}
 
-	dumpRequest(req)
+	tlsConfig.RootCAs = certPool
+	return
+}
 
-	return nil
+func isCertificateError(err error) bool {
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr)
 }
 
 func Sanitize(input string) (sanitized string) {
@@ -52,6 +94,8 @@ func Sanitize(input string) (sanitized string) {
 	sanitized = re.ReplaceAllString(input, "Authorization: "+PRIVATE_DATA_PLACEHOLDER)
 	re = regexp.MustCompile(`password=[^&]*&`)
 	sanitized = re.ReplaceAllString(sanitized, "password="+PRIVATE_DATA_PLACEHOLDER+"&")
+	re = regexp.MustCompile(`passcode=[^&]*&`)
+	sanitized = re.ReplaceAllString(sanitized, "passcode="+PRIVATE_DATA_PLACEHOLDER+"&")
 
 	sanitized = sanitizeJson("access_token", sanitized)
 	sanitized = sanitizeJson("refresh_token", sanitized)
@@ -60,8 +104,10 @@ func Sanitize(input string) (sanitized string) {
 	return
 }
 
-func doRequest(request *http.Request) (response *http.Response, err error) {
-	httpClient := newHttpClient()
+func doRequest(request *http.Request, httpClient *http.Client) (response *http.Response, err error) {
+	if trace.Format() != trace.TEXT_FORMAT {
+		return doRequestWithStructuredTrace(request, httpClient)
+	}
 
 	dumpRequest(request)
 
@@ -50,6 +50,22 @@ Authorization: [PRIVATE DATA HIDDEN]
 Content-Type: application/x-www-form-urlencoded
 
 grant_type=password&password=[PRIVATE DATA HIDDEN]&scope=&username=mgehard%2Bcli%40pivotallabs.com
+`
+		Expect(Sanitize(request)).To(Equal(expected))
+	})
+	It("removes one-time passcodes", func() {
+		request := `
+POST /oauth/token HTTP/1.1
+Host: login.run.pivotal.io
+
+grant_type=password&passcode=a1b2c3&scope=
+`
+
+		expected := `
+POST /oauth/token HTTP/1.1
+Host: login.run.pivotal.io
+
+grant_type=password&passcode=[PRIVATE DATA HIDDEN]&scope=
 `
 		Expect(Sanitize(request)).To(Equal(expected))
 	})
@@ -145,21 +161,21 @@ Server: Apache-Coyote/1.1
 		Expect(err).NotTo(HaveOccurred())
 		Expect(redirectReq.Header.Get("Authorization")).To(Equal("my-auth-token"))
 	})
-	It("TestPrepareRedirectFailsAfterOneRedirect", func() {
-
-		firstReq, err := http.NewRequest("GET", "/foo", nil)
-		Expect(err).NotTo(HaveOccurred())
-
-		secondReq, err := http.NewRequest("GET", "/manchu", nil)
-		Expect(err).NotTo(HaveOccurred())
+	It("TestPrepareRedirectFailsAfterTooManyRedirects", func() {
+		via := []*http.Request{}
+		for i := 0; i < DEFAULT_MAX_REDIRECTS; i++ {
+			req, err := http.NewRequest("GET", "/foo", nil)
+			Expect(err).NotTo(HaveOccurred())
+			via = append(via, req)
+		}
 
 		redirectReq, err := http.NewRequest("GET", "/bar", nil)
 		Expect(err).NotTo(HaveOccurred())
 
-		via := []*http.Request{firstReq, secondReq}
-
 		err = PrepareRedirect(redirectReq, via)
+		Expect(err).NotTo(HaveOccurred())
 
+		err = PrepareRedirect(redirectReq, append(via, redirectReq))
 		Expect(err).To(HaveOccurred())
 	})
 })
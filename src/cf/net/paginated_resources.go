@@ -2,9 +2,14 @@ package net
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 )
 
+var pageParamRegex = regexp.MustCompile(`([?&])page=(\d+)`)
+
 func NewPaginatedResources(exampleResource interface{}) PaginatedResources {
 	return PaginatedResources{
 		resourceType: reflect.TypeOf(exampleResource),
@@ -13,6 +18,7 @@ func NewPaginatedResources(exampleResource interface{}) PaginatedResources {
 
 type PaginatedResources struct {
 	NextURL        string          `json:"next_url"`
+	TotalPages     int             `json:"total_pages"`
 	ResourcesBytes json.RawMessage `json:"resources"`
 	resourceType   reflect.Type
 }
@@ -28,3 +34,23 @@ func (this PaginatedResources) Resources() ([]interface{}, error) {
 	}
 	return contents, err
 }
+
+// RemainingPageURLs derives the paths of all pages after this one from
+// next_url and total_pages. It returns nil when they can't be worked out, in
+// which case the pages have to be followed one by one.
+func (this PaginatedResources) RemainingPageURLs() (urls []string) {
+	match := pageParamRegex.FindStringSubmatch(this.NextURL)
+	if match == nil {
+		return
+	}
+
+	nextPage, err := strconv.Atoi(match[2])
+	if err != nil || nextPage > this.TotalPages {
+		return
+	}
+
+	for page := nextPage; page <= this.TotalPages; page++ {
+		urls = append(urls, pageParamRegex.ReplaceAllString(this.NextURL, fmt.Sprintf("${1}page=%d", page)))
+	}
+	return
+}
@@ -0,0 +1,214 @@
+package net
+
+import (
+	"bufio"
+	"cf/configuration"
+	"code.google.com/p/go.net/proxy"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	stdnet "net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var proxySchemes = []string{"http", "https", "socks5"}
+
+// ParseProxyURL parses a proxy setting the way curl does: a bare host:port is
+// taken to be an HTTP proxy.
+func ParseProxyURL(value string) (proxyURL *url.URL, err error) {
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+
+	proxyURL, err = url.Parse(value)
+	if err != nil || proxyURL.Host == "" {
+		err = errors.New(fmt.Sprintf("Invalid proxy URL %s", value))
+		return
+	}
+
+	for _, scheme := range proxySchemes {
+		if proxyURL.Scheme == scheme {
+			return
+		}
+	}
+
+	err = errors.New(fmt.Sprintf("Unsupported proxy scheme %s, use one of %s", proxyURL.Scheme, strings.Join(proxySchemes, ", ")))
+	return
+}
+
+// ProxyFor picks the proxy used to reach target, or nil to connect directly.
+// Hosts listed in NO_PROXY are always reached directly; otherwise the proxy
+// set with `cf config --proxy` wins over HTTPS_PROXY / HTTP_PROXY and then
+// ALL_PROXY from the environment. Websocket URLs follow their http
+// counterparts, so the gateways and loggregator agree on the route taken.
+func ProxyFor(config configuration.Reader, target *url.URL) (proxyURL *url.URL, err error) {
+	if bypassProxy(target) {
+		return
+	}
+
+	value := config.Proxy()
+	if value == "" {
+		value = proxyFromEnv(target.Scheme)
+	}
+	if value == "" {
+		return
+	}
+
+	return ParseProxyURL(value)
+}
+
+// DialThroughProxy opens a TCP connection to the host of target, tunnelling
+// through the proxy chosen by ProxyFor. SOCKS5 proxies use the go.net proxy
+// dialer, HTTP proxies are asked to CONNECT.
+func DialThroughProxy(config configuration.Reader, target *url.URL) (conn stdnet.Conn, err error) {
+	dialer := &stdnet.Dialer{Timeout: timeoutFromEnv(CF_HTTP_CONNECT_TIMEOUT, DEFAULT_HTTP_CONNECT_TIMEOUT)}
+	addr := hostWithPort(target)
+
+	proxyURL, err := ProxyFor(config, target)
+	if err != nil {
+		return
+	}
+
+	if proxyURL == nil {
+		return dialer.Dial("tcp", addr)
+	}
+
+	if proxyURL.Scheme == "socks5" {
+		var socksDialer proxy.Dialer
+		socksDialer, err = proxy.FromURL(proxyURL, dialer)
+		if err != nil {
+			return
+		}
+		return socksDialer.Dial("tcp", addr)
+	}
+
+	conn, err = dialer.Dial("tcp", hostWithPort(proxyURL))
+	if err != nil {
+		return
+	}
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+	}
+
+	err = connectThroughHttpProxy(conn, proxyURL, addr)
+	if err != nil {
+		conn.Close()
+		conn = nil
+	}
+	return
+}
+
+func connectThroughHttpProxy(conn stdnet.Conn, proxyURL *url.URL, addr string) (err error) {
+	request := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	err = request.Write(conn)
+	if err != nil {
+		return
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = errors.New(fmt.Sprintf("Proxy %s refused to connect to %s: %s", proxyURL.Host, addr, response.Status))
+	}
+	return
+}
+
+func proxyFromEnv(scheme string) (value string) {
+	names := []string{"ALL_PROXY"}
+	switch scheme {
+	case "https", "wss":
+		names = []string{"HTTPS_PROXY", "ALL_PROXY"}
+	case "http", "ws":
+		names = []string{"HTTP_PROXY", "ALL_PROXY"}
+	}
+
+	for _, name := range names {
+		value = getenvAnyCase(name)
+		if value != "" {
+			return
+		}
+	}
+	return
+}
+
+// bypassProxy reports whether target matches NO_PROXY: "*", a host name
+// (which also covers its subdomains), a host:port pair, an IP or a CIDR range.
+func bypassProxy(target *url.URL) bool {
+	noProxy := getenvAnyCase("NO_PROXY")
+	if noProxy == "" {
+		return false
+	}
+
+	host := strings.ToLower(target.Host)
+	hostname := host
+	if h, _, err := stdnet.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	ip := stdnet.ParseIP(hostname)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case entry == host:
+			return true
+		}
+
+		if _, network, err := stdnet.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entry = strings.TrimPrefix(entry, ".")
+		if hostname == entry || strings.HasSuffix(hostname, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostWithPort(u *url.URL) string {
+	if _, _, err := stdnet.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+
+	switch u.Scheme {
+	case "https", "wss":
+		return u.Host + ":443"
+	case "socks5":
+		return u.Host + ":1080"
+	}
+	return u.Host + ":80"
+}
+
+func getenvAnyCase(name string) (value string) {
+	value = os.Getenv(name)
+	if value == "" {
+		value = os.Getenv(strings.ToLower(name))
+	}
+	return
+}
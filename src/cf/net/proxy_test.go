@@ -0,0 +1,266 @@
+package net_test
+
+import (
+	"cf/configuration"
+	. "cf/net"
+	"encoding/binary"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
+	stdnet "net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	testconfig "testhelpers/configuration"
+)
+
+var proxyEnvVars = []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "all_proxy", "no_proxy"}
+
+var _ = Describe("proxy configuration", func() {
+	var savedEnv map[string]string
+
+	BeforeEach(func() {
+		savedEnv = map[string]string{}
+		for _, name := range proxyEnvVars {
+			savedEnv[name] = os.Getenv(name)
+			os.Unsetenv(name)
+		}
+	})
+
+	AfterEach(func() {
+		for name, value := range savedEnv {
+			os.Setenv(name, value)
+		}
+	})
+
+	proxyFor := func(config configuration.Reader, target string) string {
+		targetUrl, err := url.Parse(target)
+		Expect(err).NotTo(HaveOccurred())
+
+		proxyUrl, err := ProxyFor(config, targetUrl)
+		Expect(err).NotTo(HaveOccurred())
+		if proxyUrl == nil {
+			return ""
+		}
+		return proxyUrl.String()
+	}
+
+	Describe("ProxyFor", func() {
+		It("connects directly when no proxy is set", func() {
+			Expect(proxyFor(testconfig.NewRepository(), "https://api.example.com")).To(Equal(""))
+		})
+
+		It("uses the scheme specific env var before ALL_PROXY", func() {
+			os.Setenv("HTTPS_PROXY", "secure-proxy.example.com:8080")
+			os.Setenv("http_proxy", "http://plain-proxy.example.com:8080")
+			os.Setenv("ALL_PROXY", "socks5://socks-proxy.example.com:1080")
+			config := testconfig.NewRepository()
+
+			Expect(proxyFor(config, "https://api.example.com")).To(Equal("http://secure-proxy.example.com:8080"))
+			Expect(proxyFor(config, "wss://loggregator.example.com:4443")).To(Equal("http://secure-proxy.example.com:8080"))
+			Expect(proxyFor(config, "ws://loggregator.example.com")).To(Equal("http://plain-proxy.example.com:8080"))
+
+			os.Unsetenv("HTTPS_PROXY")
+			Expect(proxyFor(config, "https://api.example.com")).To(Equal("socks5://socks-proxy.example.com:1080"))
+		})
+
+		It("prefers the proxy from the config file over the environment", func() {
+			os.Setenv("HTTPS_PROXY", "http://env-proxy.example.com:8080")
+			config := testconfig.NewRepository()
+			config.SetProxy("socks5://config-proxy.example.com:1080")
+
+			Expect(proxyFor(config, "https://api.example.com")).To(Equal("socks5://config-proxy.example.com:1080"))
+		})
+
+		It("bypasses the proxy for hosts matching NO_PROXY", func() {
+			os.Setenv("NO_PROXY", "internal.example.com, .corp.example.com,localhost:8080,10.0.0.0/8")
+			config := testconfig.NewRepository()
+			config.SetProxy("socks5://proxy.example.com:1080")
+
+			Expect(proxyFor(config, "https://internal.example.com")).To(Equal(""))
+			Expect(proxyFor(config, "https://api.internal.example.com")).To(Equal(""))
+			Expect(proxyFor(config, "wss://loggregator.corp.example.com:4443")).To(Equal(""))
+			Expect(proxyFor(config, "http://localhost:8080")).To(Equal(""))
+			Expect(proxyFor(config, "https://10.1.2.3")).To(Equal(""))
+
+			Expect(proxyFor(config, "http://localhost:9090")).To(Equal("socks5://proxy.example.com:1080"))
+			Expect(proxyFor(config, "https://notinternal.example.com")).To(Equal("socks5://proxy.example.com:1080"))
+			Expect(proxyFor(config, "https://11.1.2.3")).To(Equal("socks5://proxy.example.com:1080"))
+		})
+
+		It("bypasses the proxy for every host when NO_PROXY is *", func() {
+			os.Setenv("NO_PROXY", "*")
+			os.Setenv("ALL_PROXY", "socks5://proxy.example.com:1080")
+
+			Expect(proxyFor(testconfig.NewRepository(), "https://api.example.com")).To(Equal(""))
+		})
+	})
+
+	Describe("ParseProxyURL", func() {
+		It("rejects unsupported schemes", func() {
+			_, err := ParseProxyURL("ftp://proxy.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported proxy scheme ftp"))
+		})
+
+		It("rejects URLs without a host", func() {
+			_, err := ParseProxyURL("socks5://")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("connecting through a proxy", func() {
+		var (
+			apiServer *httptest.Server
+			gateway   Gateway
+		)
+
+		BeforeEach(func() {
+			apiServer = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				fmt.Fprint(writer, `{"name":"my-app"}`)
+			}))
+		})
+
+		AfterEach(func() {
+			apiServer.Close()
+		})
+
+		It("sends gateway requests to an HTTP proxy", func() {
+			var proxiedUrls []string
+			proxyServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				proxiedUrls = append(proxiedUrls, request.URL.String())
+				fmt.Fprint(writer, `{"name":"proxied-app"}`)
+			}))
+			defer proxyServer.Close()
+
+			config := testconfig.NewRepository()
+			config.SetProxy(proxyServer.URL)
+			gateway = NewCloudControllerGateway(config)
+
+			request, _ := gateway.NewRequest("GET", apiServer.URL+"/v2/apps/my-app-guid", "BEARER my-access-token", nil)
+			app := &struct{ Name string }{}
+			_, apiResponse := gateway.PerformRequestForJSONResponse(request, app)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(app.Name).To(Equal("proxied-app"))
+			Expect(proxiedUrls).To(Equal([]string{apiServer.URL + "/v2/apps/my-app-guid"}))
+		})
+
+		It("tunnels gateway requests and raw connections through a SOCKS5 proxy", func() {
+			socksProxy := newSocks5Proxy()
+			defer socksProxy.Close()
+
+			config := testconfig.NewRepository()
+			config.SetProxy("socks5://" + socksProxy.Addr())
+			gateway = NewCloudControllerGateway(config)
+
+			request, _ := gateway.NewRequest("GET", apiServer.URL+"/v2/apps/my-app-guid", "BEARER my-access-token", nil)
+			app := &struct{ Name string }{}
+			_, apiResponse := gateway.PerformRequestForJSONResponse(request, app)
+
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+			Expect(app.Name).To(Equal("my-app"))
+
+			apiUrl, _ := url.Parse(apiServer.URL)
+			conn, err := DialThroughProxy(config, apiUrl)
+			Expect(err).NotTo(HaveOccurred())
+			conn.Close()
+
+			Expect(socksProxy.Destinations()).To(Equal([]string{apiUrl.Host, apiUrl.Host}))
+		})
+	})
+})
+
+// socks5Proxy is a minimal no-auth SOCKS5 server supporting CONNECT.
+type socks5Proxy struct {
+	listener     stdnet.Listener
+	mutex        sync.Mutex
+	destinations []string
+}
+
+func newSocks5Proxy() (proxy *socks5Proxy) {
+	listener, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	proxy = &socks5Proxy{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go proxy.serve(conn)
+		}
+	}()
+	return
+}
+
+func (proxy *socks5Proxy) Addr() string {
+	return proxy.listener.Addr().String()
+}
+
+func (proxy *socks5Proxy) Close() {
+	proxy.listener.Close()
+}
+
+func (proxy *socks5Proxy) Destinations() []string {
+	proxy.mutex.Lock()
+	defer proxy.mutex.Unlock()
+	return proxy.destinations
+}
+
+func (proxy *socks5Proxy) serve(conn stdnet.Conn) {
+	defer conn.Close()
+
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return
+	}
+	io.CopyN(ioutil.Discard, conn, int64(greeting[1]))
+	conn.Write([]byte{5, 0})
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	var host string
+	switch header[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = stdnet.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+
+	portBytes := make([]byte, 2)
+	io.ReadFull(conn, portBytes)
+	destination := stdnet.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+
+	proxy.mutex.Lock()
+	proxy.destinations = append(proxy.destinations, destination)
+	proxy.mutex.Unlock()
+
+	upstream, err := stdnet.Dial("tcp", destination)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
@@ -0,0 +1,121 @@
+package net
+
+import (
+	"cf/configuration"
+	"cf/terminal"
+	"cf/trace"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	CF_MAX_REDIRECTS      = "CF_MAX_REDIRECTS"
+	DEFAULT_MAX_REDIRECTS = 3
+)
+
+// RedirectPolicy limits the number of redirects followed and decides whether
+// the Authorization header may travel along. Credentials are only forwarded
+// to the scheme and host of the original request, or to the API, authorization
+// and token endpoints saved in the config from /v2/info.
+type RedirectPolicy struct {
+	MaxRedirects   int
+	TrustedOrigins []string
+}
+
+func NewRedirectPolicy(maxRedirects int, config configuration.Reader) (policy RedirectPolicy) {
+	policy.MaxRedirects = maxRedirects
+	if config == nil {
+		return
+	}
+
+	endpoints := []string{
+		config.ApiEndpoint(),
+		config.AuthorizationEndpoint(),
+		config.TokenEndpoint(),
+	}
+
+	for _, endpoint := range endpoints {
+		endpointUrl, err := url.Parse(endpoint)
+		if err != nil || endpointUrl.Host == "" {
+			continue
+		}
+		policy.TrustedOrigins = append(policy.TrustedOrigins, origin(endpointUrl))
+	}
+	return
+}
+
+func maxRedirectsFromEnv() int {
+	value := os.Getenv(CF_MAX_REDIRECTS)
+	if value == "" {
+		return DEFAULT_MAX_REDIRECTS
+	}
+
+	redirects, err := strconv.Atoi(value)
+	if err != nil || redirects < 0 {
+		trace.Logger.Printf("Ignoring invalid value for env var %s: %s\n", CF_MAX_REDIRECTS, value)
+		return DEFAULT_MAX_REDIRECTS
+	}
+	return redirects
+}
+
+func PrepareRedirect(req *http.Request, via []*http.Request) error {
+	return RedirectPolicy{MaxRedirects: DEFAULT_MAX_REDIRECTS}.CheckRedirect(req, via)
+}
+
+func (policy RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > policy.MaxRedirects {
+		return errors.New(fmt.Sprintf("stopped after %d redirects", policy.MaxRedirects))
+	}
+
+	prevReq := via[len(via)-1]
+	authorization := prevReq.Header.Get("Authorization")
+
+	if policy.canForwardCredentials(via[0].URL, req.URL) {
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+	} else {
+		req.Header.Del("Authorization")
+		if authorization != "" && trace.Format() == trace.TEXT_FORMAT {
+			trace.Logger.Printf("\n%s Not forwarding the Authorization header to %s\n", terminal.HeaderColor("REDIRECT:"), origin(req.URL))
+		}
+	}
+
+	if trace.Format() == trace.TEXT_FORMAT {
+		dumpRequest(req)
+	}
+
+	return nil
+}
+
+func (policy RedirectPolicy) canForwardCredentials(originalUrl, redirectUrl *url.URL) bool {
+	redirectOrigin := origin(redirectUrl)
+	if redirectOrigin == origin(originalUrl) {
+		return true
+	}
+
+	for _, trustedOrigin := range policy.TrustedOrigins {
+		if redirectOrigin == trustedOrigin {
+			return true
+		}
+	}
+	return false
+}
+
+func origin(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+
+	if scheme == "https" && strings.HasSuffix(host, ":443") {
+		host = strings.TrimSuffix(host, ":443")
+	} else if scheme == "http" && strings.HasSuffix(host, ":80") {
+		host = strings.TrimSuffix(host, ":80")
+	}
+
+	return scheme + "://" + host
+}
@@ -0,0 +1,119 @@
+package net_test
+
+import (
+	"bytes"
+	. "cf/net"
+	"cf/trace"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	testconfig "testhelpers/configuration"
+)
+
+var _ = Describe("RedirectPolicy", func() {
+	var policy RedirectPolicy
+
+	redirect := func(from, to string) *http.Request {
+		originalReq, err := http.NewRequest("GET", from, nil)
+		Expect(err).NotTo(HaveOccurred())
+		originalReq.Header.Set("Authorization", "bearer my-token")
+
+		redirectReq, err := http.NewRequest("GET", to, nil)
+		Expect(err).NotTo(HaveOccurred())
+		redirectReq.Header.Set("Authorization", "bearer my-token")
+
+		err = policy.CheckRedirect(redirectReq, []*http.Request{originalReq})
+		Expect(err).NotTo(HaveOccurred())
+		return redirectReq
+	}
+
+	BeforeEach(func() {
+		config := testconfig.NewRepository()
+		config.SetApiEndpoint("https://api.example.com")
+		config.SetAuthorizationEndpoint("https://login.example.com")
+		config.SetTokenEndpoint("https://uaa.example.com")
+		policy = NewRedirectPolicy(2, config)
+	})
+
+	It("forwards credentials to the same scheme and host", func() {
+		req := redirect("https://api.example.com/v2/foo", "https://api.example.com:443/v2/bar")
+		Expect(req.Header.Get("Authorization")).To(Equal("bearer my-token"))
+	})
+
+	It("forwards credentials to the endpoints advertised in /v2/info", func() {
+		req := redirect("https://api.example.com/v2/foo", "https://login.example.com/oauth/token")
+		Expect(req.Header.Get("Authorization")).To(Equal("bearer my-token"))
+
+		req = redirect("https://api.example.com/v2/foo", "https://uaa.example.com/Users")
+		Expect(req.Header.Get("Authorization")).To(Equal("bearer my-token"))
+	})
+
+	It("does not trust hosts guessed from the authorization endpoint", func() {
+		config := testconfig.NewRepository()
+		config.SetApiEndpoint("https://api.example.com")
+		config.SetAuthorizationEndpoint("https://login.example.com")
+		policy = NewRedirectPolicy(2, config)
+
+		req := redirect("https://api.example.com/v2/foo", "https://uaa.example.com/Users")
+		Expect(req.Header.Get("Authorization")).To(Equal(""))
+	})
+
+	It("strips credentials when redirected to another host", func() {
+		req := redirect("https://api.example.com/v2/foo", "https://blobstore.example.org/bits")
+		Expect(req.Header.Get("Authorization")).To(Equal(""))
+	})
+
+	It("strips credentials when redirected from https to http", func() {
+		req := redirect("https://api.example.com/v2/foo", "http://api.example.com/v2/bar")
+		Expect(req.Header.Get("Authorization")).To(Equal(""))
+	})
+
+	It("warns in the trace output when credentials are stripped", func() {
+		stdOut := bytes.NewBuffer([]byte{})
+		trace.SetStdout(stdOut)
+		trace.EnableTrace()
+		defer func() {
+			trace.DisableTrace()
+			trace.SetStdout(os.Stdout)
+		}()
+
+		redirect("https://api.example.com/v2/foo", "https://blobstore.example.org/bits")
+		Expect(stdOut.String()).To(ContainSubstring("Not forwarding the Authorization header to https://blobstore.example.org"))
+	})
+
+	It("stops after the configured number of redirects", func() {
+		req, _ := http.NewRequest("GET", "https://api.example.com/v2/foo", nil)
+
+		err := policy.CheckRedirect(req, []*http.Request{req, req})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = policy.CheckRedirect(req, []*http.Request{req, req, req})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("stopped after 2 redirects"))
+	})
+
+	It("is used by the gateway when following redirects", func() {
+		var receivedAuthorization string
+		otherServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			receivedAuthorization = request.Header.Get("Authorization")
+			writer.Write([]byte(`{}`))
+		}))
+		defer otherServer.Close()
+
+		apiServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			http.Redirect(writer, request, otherServer.URL+"/elsewhere", http.StatusFound)
+		}))
+		defer apiServer.Close()
+
+		gateway := NewCloudControllerGateway(testconfig.NewRepository())
+		gateway.SetTrustedCerts(append(apiServer.TLS.Certificates, otherServer.TLS.Certificates...))
+
+		request, _ := gateway.NewRequest("GET", apiServer.URL+"/v2/foo", "bearer my-token", nil)
+		apiResponse := gateway.PerformRequest(request)
+
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+		Expect(receivedAuthorization).To(Equal(""))
+	})
+})
@@ -0,0 +1,187 @@
+package net
+
+import (
+	"cf/trace"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	stdnet "net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	CF_MAX_RETRIES            = "CF_MAX_RETRIES"
+	CF_RETRY_MAX_BACKOFF      = "CF_RETRY_MAX_BACKOFF"
+	DEFAULT_MAX_RETRIES       = 3
+	DEFAULT_RETRY_BACKOFF     = 500 * time.Millisecond
+	DEFAULT_RETRY_MAX_BACKOFF = 10 * time.Second
+
+	CF_RATE_LIMIT_MAX_WAIT         = "CF_RATE_LIMIT_MAX_WAIT"
+	DEFAULT_RATE_LIMIT_MAX_WAIT    = 2 * time.Minute
+	DEFAULT_RATE_LIMIT_MAX_RETRIES = 10
+)
+
+var idempotentMethods = map[string]bool{
+	"GET":    true,
+	"HEAD":   true,
+	"PUT":    true,
+	"DELETE": true,
+}
+
+var retryableStatusCodes = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+func maxRetriesFromEnv() int {
+	value := os.Getenv(CF_MAX_RETRIES)
+	if value == "" {
+		return DEFAULT_MAX_RETRIES
+	}
+
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		trace.Logger.Printf("Ignoring invalid value for env var %s: %s\n", CF_MAX_RETRIES, value)
+		return DEFAULT_MAX_RETRIES
+	}
+	return retries
+}
+
+func retryMaxBackoffFromEnv() time.Duration {
+	value := os.Getenv(CF_RETRY_MAX_BACKOFF)
+	if value == "" {
+		return DEFAULT_RETRY_MAX_BACKOFF
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		trace.Logger.Printf("Ignoring invalid value for env var %s: %s\n", CF_RETRY_MAX_BACKOFF, value)
+		return DEFAULT_RETRY_MAX_BACKOFF
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// retryBackoff doubles the base delay for every failed attempt, caps it at the
+// ceiling and then picks a random delay in the upper half of that window so
+// that several clients don't retry in lock step.
+func (gateway Gateway) retryBackoff(attempt int) time.Duration {
+	backoff := gateway.RetryBackoff
+	for i := 0; i < attempt && backoff < gateway.RetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > gateway.RetryMaxBackoff {
+		backoff = gateway.RetryMaxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+func isRetryable(request *http.Request, response *http.Response, err error) bool {
+	if !idempotentMethods[request.Method] {
+		return false
+	}
+
+	if err != nil {
+		return isTransientError(err)
+	}
+
+	return retryableStatusCodes[response.StatusCode]
+}
+
+// isRateLimited tells whether the request may be sent again after waiting for
+// Retry-After. A 429 means the request was not processed, so even requests
+// that are not idempotent are sent again. A 503 only counts when it says how
+// long to wait.
+func isRateLimited(request *http.Request, response *http.Response) bool {
+	if response == nil {
+		return false
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	_, hasRetryAfter := retryAfter(response)
+	return hasRetryAfter && idempotentMethods[request.Method]
+}
+
+// isTransientError only accepts timeouts and connections that were reset or
+// closed early. Refused connections and local errors will not go away by
+// trying again.
+func isTransientError(err error) bool {
+	if isCertificateError(err) {
+		return false
+	}
+
+	var netErr stdnet.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryReason(response *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("status code %d", response.StatusCode)
+}
+
+// retryAfter returns how long a rate limited (429) or unavailable (503)
+// response asks us to wait before trying again. Retry-After may be given in
+// seconds or as an HTTP date; one that is not in the future gives a wait of 0.
+func retryAfter(response *http.Response) (wait time.Duration, ok bool) {
+	if response == nil {
+		return
+	}
+
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+
+	value := strings.TrimSpace(response.Header.Get("Retry-After"))
+	if value == "" {
+		return
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			seconds = 0
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return
+}
+
+func (gateway Gateway) sayRateLimited(wait time.Duration) {
+	if gateway.ui != nil {
+		gateway.ui.Warn("rate limited, retrying in %s", wait)
+	} else {
+		trace.Logger.Printf("rate limited, retrying in %s\n", wait)
+	}
+}
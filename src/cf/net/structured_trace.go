@@ -0,0 +1,308 @@
+package net
+
+import (
+	"cf"
+	"cf/terminal"
+	"cf/trace"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TraceRecord is a single request/response pair as written by
+// CF_TRACE_FORMAT=json, one record per line. A record with Retry set follows
+// the record of a failed attempt that is about to be retried.
+type TraceRecord struct {
+	StartedAt       time.Time           `json:"started_at"`
+	DurationMs      float64             `json:"duration_ms"`
+	Method          string              `json:"method"`
+	Url             string              `json:"url"`
+	StatusCode      int                 `json:"status,omitempty"`
+	RequestHeaders  map[string][]string `json:"request_headers"`
+	RequestBody     string              `json:"request_body,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody    string              `json:"response_body,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Retry           *TraceRetry         `json:"retry,omitempty"`
+}
+
+type TraceRetry struct {
+	Attempt     int     `json:"attempt"`
+	MaxAttempts int     `json:"max_attempts"`
+	Reason      string  `json:"reason"`
+	WaitMs      float64 `json:"wait_ms"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HttpVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HttpVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string                 `json:"startedDateTime"`
+	Time            float64                `json:"time"`
+	Request         harRequest             `json:"request"`
+	Response        harResponse            `json:"response"`
+	Cache           map[string]interface{} `json:"cache"`
+	Timings         harTimings             `json:"timings"`
+	Comment         string                 `json:"comment,omitempty"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+var harTrace = harDocument{
+	Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: cf.Name(), Version: cf.Version},
+		Entries: []harEntry{},
+	},
+}
+var harTraceMutex sync.Mutex
+
+func doRequestWithStructuredTrace(request *http.Request, httpClient *http.Client) (response *http.Response, err error) {
+	if !trace.Enabled() {
+		return httpClient.Do(request)
+	}
+
+	record := TraceRecord{
+		StartedAt:      time.Now(),
+		Method:         request.Method,
+		Url:            request.URL.String(),
+		RequestHeaders: sanitizeHeaders(request.Header),
+	}
+
+	record.RequestBody, err = readRequestBody(request)
+	if err != nil {
+		return
+	}
+
+	response, err = httpClient.Do(request)
+	if err == nil {
+		record.StatusCode = response.StatusCode
+		record.ResponseHeaders = sanitizeHeaders(response.Header)
+		record.ResponseBody, err = readResponseBody(response)
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+	record.DurationMs = float64(time.Since(record.StartedAt)) / float64(time.Millisecond)
+
+	writeTraceRecord(record)
+	return
+}
+
+// traceRetry records that a failed attempt of the request is retried after
+// the wait, in every trace format.
+func traceRetry(request *http.Request, attempt, maxAttempts int, reason string, wait time.Duration) {
+	if trace.Format() == trace.TEXT_FORMAT {
+		trace.Logger.Printf("\n%s %s %s failed on attempt %d of %d (%s), retrying in %s\n",
+			terminal.HeaderColor("RETRYING:"), request.Method, request.URL, attempt, maxAttempts, reason, wait)
+		return
+	}
+
+	if !trace.Enabled() {
+		return
+	}
+
+	writeTraceRecord(TraceRecord{
+		StartedAt: time.Now(),
+		Method:    request.Method,
+		Url:       request.URL.String(),
+		Retry: &TraceRetry{
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Reason:      reason,
+			WaitMs:      float64(wait) / float64(time.Millisecond),
+		},
+	})
+}
+
+func writeTraceRecord(record TraceRecord) {
+	if trace.Format() == trace.HAR_FORMAT && record.Retry != nil {
+		commentHAREntry(record)
+		return
+	}
+
+	if trace.Format() == trace.HAR_FORMAT {
+		// a HAR file is a single JSON document, so the entries are kept
+		// until WriteHARTrace writes them out when the command ends
+		harTraceMutex.Lock()
+		harTrace.Log.Entries = append(harTrace.Log.Entries, newHAREntry(record))
+		harTraceMutex.Unlock()
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		trace.Logger.Printf("Error writing trace record\n%s\n", err)
+		return
+	}
+	trace.Logger.Println(string(data))
+}
+
+// WriteHARTrace writes the requests traced so far to the trace log as one HAR
+// document, and starts a new one.
+func WriteHARTrace() {
+	harTraceMutex.Lock()
+	defer harTraceMutex.Unlock()
+
+	if len(harTrace.Log.Entries) == 0 {
+		return
+	}
+
+	data, err := json.MarshalIndent(harTrace, "", "  ")
+	harTrace.Log.Entries = []harEntry{}
+	if err != nil {
+		trace.Logger.Printf("Error writing trace record\n%s\n", err)
+		return
+	}
+	trace.Logger.Println(string(data))
+}
+
+// commentHAREntry notes the retry on the entry of the failed attempt, as HAR
+// has no place for entries without a response.
+func commentHAREntry(record TraceRecord) {
+	harTraceMutex.Lock()
+	defer harTraceMutex.Unlock()
+
+	entries := harTrace.Log.Entries
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Request.Method != record.Method || entries[i].Request.Url != record.Url {
+			continue
+		}
+
+		comment := fmt.Sprintf("failed on attempt %d of %d (%s), retrying in %s",
+			record.Retry.Attempt, record.Retry.MaxAttempts, record.Retry.Reason,
+			time.Duration(record.Retry.WaitMs*float64(time.Millisecond)))
+		if entries[i].Comment != "" {
+			comment = entries[i].Comment + "; " + comment
+		}
+		entries[i].Comment = comment
+		return
+	}
+}
+
+func newHAREntry(record TraceRecord) (entry harEntry) {
+	entry = harEntry{
+		StartedDateTime: record.StartedAt.Format(time.RFC3339Nano),
+		Time:            record.DurationMs,
+		Request: harRequest{
+			Method:      record.Method,
+			Url:         record.Url,
+			HttpVersion: "HTTP/1.1",
+			Headers:     harHeaders(record.RequestHeaders),
+			QueryString: []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(record.RequestBody),
+		},
+		Response: harResponse{
+			Status:      record.StatusCode,
+			StatusText:  http.StatusText(record.StatusCode),
+			HttpVersion: "HTTP/1.1",
+			Headers:     harHeaders(record.ResponseHeaders),
+			Cookies:     []harNameValue{},
+			Content: harContent{
+				Size:     len(record.ResponseBody),
+				MimeType: http.Header(record.ResponseHeaders).Get("Content-Type"),
+				Text:     record.ResponseBody,
+			},
+			HeadersSize: -1,
+			BodySize:    len(record.ResponseBody),
+		},
+		Cache:   map[string]interface{}{},
+		Timings: harTimings{Send: 0, Wait: record.DurationMs, Receive: 0},
+		Comment: record.Error,
+	}
+
+	if parsedUrl, err := url.Parse(record.Url); err == nil {
+		for name, values := range parsedUrl.Query() {
+			for _, value := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+			}
+		}
+	}
+
+	if record.RequestBody != "" {
+		entry.Request.PostData = &harPostData{
+			MimeType: http.Header(record.RequestHeaders).Get("Content-Type"),
+			Text:     record.RequestBody,
+		}
+	}
+	return
+}
+
+func harHeaders(headers map[string][]string) (nameValues []harNameValue) {
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nameValues = []harNameValue{}
+	for _, name := range names {
+		for _, value := range headers[name] {
+			nameValues = append(nameValues, harNameValue{Name: name, Value: value})
+		}
+	}
+	return
+}
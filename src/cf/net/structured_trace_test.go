@@ -0,0 +1,192 @@
+package net_test
+
+import (
+	"bytes"
+	. "cf/net"
+	"cf/trace"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	testconfig "testhelpers/configuration"
+	"time"
+)
+
+var _ = Describe("structured trace output", func() {
+	var (
+		stdOut    *bytes.Buffer
+		apiServer *httptest.Server
+		gateway   Gateway
+	)
+
+	performRequest := func(method, path, body string) ApiResponse {
+		request, _ := gateway.NewRequest(method, apiServer.URL+path, "BEARER my-access-token", strings.NewReader(body))
+		return gateway.PerformRequest(request)
+	}
+
+	BeforeEach(func() {
+		stdOut = bytes.NewBuffer([]byte{})
+		trace.SetStdout(stdOut)
+		trace.EnableTrace()
+
+		apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusCreated)
+			fmt.Fprint(writer, `{"access_token":"secret-token","name":"my-app"}`)
+		}))
+
+		gateway = NewCloudControllerGateway(testconfig.NewRepository())
+		gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+		os.Unsetenv(trace.CF_TRACE_FORMAT)
+		os.Unsetenv(trace.CF_TRACE)
+		trace.DisableTrace()
+		trace.SetStdout(os.Stdout)
+	})
+
+	It("writes one sanitized JSON record per request when the format is json", func() {
+		os.Setenv(trace.CF_TRACE_FORMAT, "json")
+
+		apiResponse := performRequest("POST", "/v2/apps?async=true", `{"name":"my-app","password":"secret-password"}`)
+		Expect(apiResponse.IsSuccessful()).To(BeTrue())
+
+		output := stdOut.String()
+		Expect(output).NotTo(ContainSubstring("REQUEST:"))
+		Expect(output).NotTo(ContainSubstring("my-access-token"))
+		Expect(output).NotTo(ContainSubstring("secret-token"))
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		Expect(len(lines)).To(Equal(1))
+
+		record := TraceRecord{}
+		err := json.Unmarshal([]byte(lines[0]), &record)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(record.Method).To(Equal("POST"))
+		Expect(record.Url).To(Equal(apiServer.URL + "/v2/apps?async=true"))
+		Expect(record.StatusCode).To(Equal(http.StatusCreated))
+		Expect(record.RequestHeaders["Authorization"]).To(Equal([]string{PRIVATE_DATA_PLACEHOLDER}))
+		Expect(record.RequestBody).To(ContainSubstring(`"name":"my-app"`))
+		Expect(record.ResponseHeaders["Content-Type"]).To(Equal([]string{"application/json"}))
+		Expect(record.ResponseBody).To(ContainSubstring(`"access_token":"` + PRIVATE_DATA_PLACEHOLDER + `"`))
+		Expect(record.StartedAt.IsZero()).To(BeFalse())
+		Expect(record.DurationMs).To(BeNumerically(">", 0))
+	})
+
+	It("writes one HAR document to the trace when the command ends and the format is har", func() {
+		os.Setenv(trace.CF_TRACE, "/tmp/trace.har")
+		os.Setenv(trace.CF_TRACE_FORMAT, "har")
+
+		performRequest("PUT", "/v2/apps/my-app-guid", `{"name":"my-app"}`)
+		performRequest("GET", "/v2/apps?q=name:my-app", "")
+		Expect(stdOut.String()).To(BeEmpty())
+
+		WriteHARTrace()
+
+		data := stdOut.Bytes()
+		Expect(string(data)).NotTo(ContainSubstring("secret-token"))
+
+		har := map[string]interface{}{}
+		err := json.Unmarshal(data, &har)
+		Expect(err).NotTo(HaveOccurred())
+
+		entries := har["log"].(map[string]interface{})["entries"].([]interface{})
+		lastEntry := entries[len(entries)-1].(map[string]interface{})
+		request := lastEntry["request"].(map[string]interface{})
+		response := lastEntry["response"].(map[string]interface{})
+
+		Expect(request["method"]).To(Equal("GET"))
+		Expect(request["url"]).To(Equal(apiServer.URL + "/v2/apps?q=name:my-app"))
+		Expect(request["queryString"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "q", "value": "name:my-app"},
+		}))
+		Expect(response["status"]).To(Equal(float64(http.StatusCreated)))
+
+		previousEntry := entries[len(entries)-2].(map[string]interface{})
+		previousRequest := previousEntry["request"].(map[string]interface{})
+		Expect(previousRequest["method"]).To(Equal("PUT"))
+		Expect(previousRequest["postData"].(map[string]interface{})["text"]).To(Equal(`{"name":"my-app"}`))
+	})
+
+	It("falls back to json records when tracing har to stdout", func() {
+		os.Setenv(trace.CF_TRACE, "true")
+		os.Setenv(trace.CF_TRACE_FORMAT, "har")
+
+		performRequest("GET", "/v2/apps", "")
+		WriteHARTrace()
+
+		lines := strings.Split(strings.TrimSpace(stdOut.String()), "\n")
+		Expect(len(lines)).To(Equal(1))
+
+		record := TraceRecord{}
+		err := json.Unmarshal([]byte(lines[0]), &record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.Method).To(Equal("GET"))
+	})
+
+	Describe("retried requests", func() {
+		BeforeEach(func() {
+			apiServer.Close()
+
+			attempts := 0
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				attempts++
+				if attempts == 1 {
+					writer.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				fmt.Fprint(writer, `{}`)
+			}))
+
+			gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+			gateway.MaxRetries = 1
+			gateway.RetryBackoff = time.Millisecond
+			gateway.RetryMaxBackoff = time.Millisecond
+		})
+
+		It("writes a retry record after the failed attempt when the format is json", func() {
+			os.Setenv(trace.CF_TRACE_FORMAT, "json")
+
+			apiResponse := performRequest("GET", "/v2/apps", "")
+			Expect(apiResponse.IsSuccessful()).To(BeTrue())
+
+			lines := strings.Split(strings.TrimSpace(stdOut.String()), "\n")
+			Expect(len(lines)).To(Equal(3))
+
+			record := TraceRecord{}
+			err := json.Unmarshal([]byte(lines[1]), &record)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(record.Url).To(Equal(apiServer.URL + "/v2/apps"))
+			Expect(*record.Retry).To(Equal(TraceRetry{
+				Attempt:     1,
+				MaxAttempts: 2,
+				Reason:      "status code 502",
+				WaitMs:      record.Retry.WaitMs,
+			}))
+		})
+
+		It("notes the retry on the entry of the failed attempt when the format is har", func() {
+			os.Setenv(trace.CF_TRACE, "/tmp/trace.har")
+			os.Setenv(trace.CF_TRACE_FORMAT, "har")
+
+			performRequest("GET", "/v2/apps", "")
+			WriteHARTrace()
+
+			har := map[string]interface{}{}
+			err := json.Unmarshal(stdOut.Bytes(), &har)
+			Expect(err).NotTo(HaveOccurred())
+
+			entries := har["log"].(map[string]interface{})["entries"].([]interface{})
+			Expect(len(entries)).To(Equal(2))
+			Expect(entries[0].(map[string]interface{})["comment"]).To(ContainSubstring("failed on attempt 1 of 2 (status code 502), retrying in"))
+			Expect(entries[1].(map[string]interface{})["comment"]).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,174 @@
+package net
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	CF_TIMINGS = "CF_TIMINGS"
+
+	PHASE_RESOURCE_MATCH   = "resource_match"
+	PHASE_BITS_UPLOAD      = "bits upload"
+	PHASE_JOB_POLLING      = "job polling"
+	PHASE_INSTANCE_POLLING = "instance polling"
+)
+
+var Phases = []string{
+	PHASE_RESOURCE_MATCH,
+	PHASE_BITS_UPLOAD,
+	PHASE_JOB_POLLING,
+	PHASE_INSTANCE_POLLING,
+}
+
+var phasePatterns = []struct {
+	method string
+	path   *regexp.Regexp
+	phase  string
+}{
+	{"PUT", regexp.MustCompile(`^/v2/resource_match$`), PHASE_RESOURCE_MATCH},
+	{"PUT", regexp.MustCompile(`^/v2/apps/[^/]+/bits$`), PHASE_BITS_UPLOAD},
+	{"GET", regexp.MustCompile(`^/v2/jobs/[^/]+$`), PHASE_JOB_POLLING},
+	{"GET", regexp.MustCompile(`^/v2/apps/[^/]+/instances$`), PHASE_INSTANCE_POLLING},
+}
+
+type RequestTiming struct {
+	Method        string
+	Path          string
+	Phase         string
+	StatusCode    int
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+}
+
+type PhaseTiming struct {
+	Phase    string
+	Requests int
+	Duration time.Duration
+}
+
+// TimingsRecorder collects the duration of every call made by the gateways it
+// is set on while running a command, for `--timings` or CF_TIMINGS=true.
+type TimingsRecorder struct {
+	mutex    sync.Mutex
+	enabled  bool
+	requests []RequestTiming
+}
+
+func NewTimingsRecorder() *TimingsRecorder {
+	return &TimingsRecorder{}
+}
+
+func (recorder *TimingsRecorder) Enable() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.enabled = true
+}
+
+func (recorder *TimingsRecorder) Enabled() bool {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return recorder.enabled
+}
+
+func (recorder *TimingsRecorder) Record(timing RequestTiming) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.requests = append(recorder.requests, timing)
+}
+
+func (recorder *TimingsRecorder) Requests() (requests []RequestTiming) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	requests = make([]RequestTiming, len(recorder.requests))
+	copy(requests, recorder.requests)
+	return
+}
+
+func (recorder *TimingsRecorder) PhaseTotals() (totals []PhaseTiming) {
+	for _, phase := range Phases {
+		total := PhaseTiming{Phase: phase}
+		for _, request := range recorder.Requests() {
+			if request.Phase == phase {
+				total.Requests++
+				total.Duration += request.Duration
+			}
+		}
+		totals = append(totals, total)
+	}
+	return
+}
+
+// track records the request once its response body has been read or closed,
+// so that the duration covers the whole transfer.
+func (recorder *TimingsRecorder) track(request *http.Request, response *http.Response, err error, startTime time.Time) *http.Response {
+	if recorder == nil || !recorder.Enabled() {
+		return response
+	}
+
+	timing := RequestTiming{
+		Method: request.Method,
+		Path:   request.URL.Path,
+		Phase:  phaseForRequest(request),
+	}
+	if request.ContentLength > 0 {
+		timing.BytesSent = request.ContentLength
+	}
+
+	if err != nil || response == nil {
+		timing.Duration = time.Since(startTime)
+		recorder.Record(timing)
+		return response
+	}
+
+	timing.StatusCode = response.StatusCode
+	response.Body = &timedBody{
+		ReadCloser: response.Body,
+		recorder:   recorder,
+		timing:     timing,
+		startTime:  startTime,
+	}
+	return response
+}
+
+func phaseForRequest(request *http.Request) string {
+	for _, pattern := range phasePatterns {
+		if request.Method == pattern.method && pattern.path.MatchString(request.URL.Path) {
+			return pattern.phase
+		}
+	}
+	return ""
+}
+
+type timedBody struct {
+	io.ReadCloser
+	recorder  *TimingsRecorder
+	timing    RequestTiming
+	startTime time.Time
+	once      sync.Once
+}
+
+func (body *timedBody) Read(p []byte) (n int, err error) {
+	n, err = body.ReadCloser.Read(p)
+	body.timing.BytesReceived += int64(n)
+	if err == io.EOF {
+		body.finish()
+	}
+	return
+}
+
+func (body *timedBody) Close() error {
+	body.finish()
+	return body.ReadCloser.Close()
+}
+
+func (body *timedBody) finish() {
+	body.once.Do(func() {
+		body.timing.Duration = time.Since(body.startTime)
+		body.recorder.Record(body.timing)
+	})
+}
@@ -0,0 +1,77 @@
+package net_test
+
+import (
+	. "cf/net"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	testconfig "testhelpers/configuration"
+)
+
+var _ = Describe("request timings", func() {
+	var (
+		apiServer *httptest.Server
+		gateway   Gateway
+		timings   *TimingsRecorder
+	)
+
+	BeforeEach(func() {
+		apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			fmt.Fprint(writer, `{"resources":[]}`)
+		}))
+
+		timings = NewTimingsRecorder()
+		gateway = NewCloudControllerGateway(testconfig.NewRepository())
+		gateway.SetTrustedCerts(apiServer.TLS.Certificates)
+		gateway.SetTimingsRecorder(timings)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	It("does not record anything unless enabled", func() {
+		request, _ := gateway.NewRequest("GET", apiServer.URL+"/v2/apps", "", nil)
+		gateway.PerformRequestForJSONResponse(request, &struct{}{})
+
+		Expect(timings.Requests()).To(BeEmpty())
+	})
+
+	It("records every gateway call with its phase, status and size", func() {
+		timings.Enable()
+
+		request, _ := gateway.NewRequest("PUT", apiServer.URL+"/v2/resource_match", "", strings.NewReader(`[]`))
+		gateway.PerformRequestForJSONResponse(request, &[]interface{}{})
+
+		request, _ = gateway.NewRequest("GET", apiServer.URL+"/v2/jobs/my-job-guid", "", nil)
+		gateway.PerformRequest(request)
+
+		request, _ = gateway.NewRequest("GET", apiServer.URL+"/v2/apps", "", nil)
+		gateway.PerformRequestForJSONResponse(request, &struct{}{})
+
+		requests := timings.Requests()
+		Expect(len(requests)).To(Equal(3))
+
+		Expect(requests[0].Method).To(Equal("PUT"))
+		Expect(requests[0].Path).To(Equal("/v2/resource_match"))
+		Expect(requests[0].Phase).To(Equal(PHASE_RESOURCE_MATCH))
+		Expect(requests[0].StatusCode).To(Equal(http.StatusOK))
+		Expect(requests[0].BytesSent).To(Equal(int64(2)))
+		Expect(requests[0].BytesReceived).To(Equal(int64(len(`{"resources":[]}`))))
+		Expect(requests[0].Duration).To(BeNumerically(">", 0))
+
+		Expect(requests[1].Phase).To(Equal(PHASE_JOB_POLLING))
+		Expect(requests[2].Phase).To(Equal(""))
+
+		totals := timings.PhaseTotals()
+		Expect(totals[0].Phase).To(Equal(PHASE_RESOURCE_MATCH))
+		Expect(totals[0].Requests).To(Equal(1))
+		Expect(totals[1].Phase).To(Equal(PHASE_BITS_UPLOAD))
+		Expect(totals[1].Requests).To(Equal(0))
+		Expect(totals[2].Phase).To(Equal(PHASE_JOB_POLLING))
+		Expect(totals[2].Requests).To(Equal(1))
+	})
+})
@@ -0,0 +1,108 @@
+package net
+
+import (
+	"cf/configuration"
+	"cf/trace"
+	"crypto/tls"
+	"errors"
+	stdnet "net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	CF_HTTP_TIMEOUT                 = "CF_HTTP_TIMEOUT"
+	CF_HTTP_CONNECT_TIMEOUT         = "CF_HTTP_CONNECT_TIMEOUT"
+	CF_HTTP_TLS_TIMEOUT             = "CF_HTTP_TLS_TIMEOUT"
+	DEFAULT_HTTP_TIMEOUT            = 0 // no limit, so large uploads are not cut off
+	DEFAULT_HTTP_CONNECT_TIMEOUT    = 30 * time.Second
+	DEFAULT_HTTP_TLS_TIMEOUT        = 10 * time.Second
+	DEFAULT_RESPONSE_HEADER_TIMEOUT = 2 * time.Minute
+	DEFAULT_MAX_IDLE_CONNS_PER_HOST = 4
+)
+
+type transportSettings struct {
+	sslDisabled         bool
+	caCertFile          string
+	connectTimeout      time.Duration
+	tlsHandshakeTimeout time.Duration
+}
+
+// transportCache holds the connection pool of a gateway. Gateways are passed
+// around by value, so every copy shares the same cache; the transport is only
+// rebuilt when the TLS or timeout settings it was created with change.
+type transportCache struct {
+	mutex     sync.Mutex
+	transport *http.Transport
+	settings  transportSettings
+}
+
+func newTransportCache() *transportCache {
+	return &transportCache{}
+}
+
+func (cache *transportCache) transportFor(settings transportSettings, config configuration.Reader, trustedCerts []tls.Certificate) (transport *http.Transport, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.transport != nil && cache.settings == settings {
+		transport = cache.transport
+		return
+	}
+
+	tlsConfig, err := NewTLSConfig(config, trustedCerts)
+	if err != nil {
+		return
+	}
+
+	if cache.transport != nil {
+		cache.transport.CloseIdleConnections()
+	}
+
+	transport = newHttpTransport(tlsConfig, settings, config)
+	cache.transport = transport
+	cache.settings = settings
+	return
+}
+
+func newHttpTransport(tlsConfig *tls.Config, settings transportSettings, config configuration.Reader) *http.Transport {
+	dialer := &stdnet.Dialer{
+		Timeout:   settings.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy: func(request *http.Request) (*url.URL, error) {
+			return ProxyFor(config, request.URL)
+		},
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   settings.tlsHandshakeTimeout,
+		ResponseHeaderTimeout: DEFAULT_RESPONSE_HEADER_TIMEOUT,
+		MaxIdleConnsPerHost:   DEFAULT_MAX_IDLE_CONNS_PER_HOST,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+func timeoutFromEnv(name string, defaultTimeout time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultTimeout
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		trace.Logger.Printf("Ignoring invalid value for env var %s: %s\n", name, value)
+		return defaultTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func isTimeoutError(err error) bool {
+	var netErr stdnet.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
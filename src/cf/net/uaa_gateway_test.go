@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 	"net/http"
 	"net/http/httptest"
+	testconfig "testhelpers/configuration"
 )
 
 var failingUAARequest = func(writer http.ResponseWriter, request *http.Request) {
@@ -18,10 +19,11 @@ var failingUAARequest = func(writer http.ResponseWriter, request *http.Request)
 var _ = Describe("Testing with ginkgo", func() {
 	It("TestUAAGatewayErrorHandling", func() {
 
-		gateway := NewUAAGateway()
+		gateway := NewUAAGateway(testconfig.NewRepository())
 
 		ts := httptest.NewTLSServer(http.HandlerFunc(failingUAARequest))
 		defer ts.Close()
+		gateway.SetTrustedCerts(ts.TLS.Certificates)
 
 		request, apiResponse := gateway.NewRequest("GET", ts.URL, "TOKEN", nil)
 		Expect(apiResponse.IsNotSuccessful()).To(BeFalse())
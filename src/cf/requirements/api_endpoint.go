@@ -2,18 +2,20 @@ package requirements
 
 import (
 	"cf"
+	"cf/api"
 	"cf/configuration"
 	"cf/terminal"
 	"fmt"
 )
 
 type ApiEndpointRequirement struct {
-	ui     terminal.UI
-	config configuration.Reader
+	ui           terminal.UI
+	config       configuration.Reader
+	endpointRepo api.EndpointRepository
 }
 
-func NewApiEndpointRequirement(ui terminal.UI, config configuration.Reader) ApiEndpointRequirement {
-	return ApiEndpointRequirement{ui, config}
+func NewApiEndpointRequirement(ui terminal.UI, config configuration.Reader, endpointRepo api.EndpointRepository) ApiEndpointRequirement {
+	return ApiEndpointRequirement{ui, config, endpointRepo}
 }
 
 func (req ApiEndpointRequirement) Execute() (success bool) {
@@ -23,5 +25,14 @@ func (req ApiEndpointRequirement) Execute() (success bool) {
 		req.ui.Say("No API endpoint targeted. Use '%s' or '%s' to target an endpoint.", loginTip, apiTip)
 		return false
 	}
+
+	// the endpoints of an API set with CF_API are looked up on first use
+	if req.config.EnvOverrides().ApiEndpoint != "" && req.config.AuthorizationEndpoint() == "" {
+		_, apiResponse := req.endpointRepo.UpdateEndpoint(req.config.ApiEndpoint())
+		if apiResponse.IsNotSuccessful() {
+			req.ui.Failed("Error targeting the API endpoint from %s\n%s", configuration.CF_API, apiResponse.Message)
+			return false
+		}
+	}
 	return true
 }
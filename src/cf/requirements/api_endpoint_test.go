@@ -5,6 +5,8 @@ import (
 	. "cf/requirements"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"os"
+	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testterm "testhelpers/terminal"
@@ -12,27 +14,44 @@ import (
 
 var _ = Describe("ApiEndpointRequirement", func() {
 	var (
-		ui     *testterm.FakeUI
-		config configuration.Repository
+		ui           *testterm.FakeUI
+		config       configuration.Repository
+		endpointRepo *testapi.FakeEndpointRepo
 	)
 
 	BeforeEach(func() {
 		ui = new(testterm.FakeUI)
 		config = testconfig.NewRepository()
+		endpointRepo = &testapi.FakeEndpointRepo{Config: config}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv(configuration.CF_API)
 	})
 
 	It("succeeds when given a config with an API endpoint", func() {
 		config.SetApiEndpoint("api.example.com")
-		req := NewApiEndpointRequirement(ui, config)
+		req := NewApiEndpointRequirement(ui, config, endpointRepo)
 		success := req.Execute()
 		Expect(success).To(BeTrue())
 	})
 
 	It("fails when given a config without an API endpoint", func() {
-		req := NewApiEndpointRequirement(ui, config)
+		req := NewApiEndpointRequirement(ui, config, endpointRepo)
 		success := req.Execute()
 		Expect(success).To(BeFalse())
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{{"No API endpoint"}})
 	})
+
+	It("looks up the API endpoint set in CF_API", func() {
+		os.Setenv(configuration.CF_API, "https://api.example.com")
+		config = testconfig.NewRepository()
+		endpointRepo.Config = config
+
+		req := NewApiEndpointRequirement(ui, config, endpointRepo)
+		success := req.Execute()
+		Expect(success).To(BeTrue())
+		Expect(endpointRepo.UpdateEndpointReceived).To(Equal("https://api.example.com"))
+	})
 })
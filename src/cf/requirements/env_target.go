@@ -0,0 +1,43 @@
+package requirements
+
+import (
+	"cf/api"
+	"cf/configuration"
+	"cf/terminal"
+)
+
+// targetOrgFromEnv targets the org named in CF_ORG, looking it up by name the
+// first time a command needs it.
+func targetOrgFromEnv(ui terminal.UI, config configuration.ReadWriter, orgRepo api.OrganizationRepository) bool {
+	orgName := config.EnvOverrides().OrgName
+	if orgName == "" {
+		return false
+	}
+
+	org, apiResponse := orgRepo.FindByName(orgName)
+	if apiResponse.IsNotSuccessful() {
+		ui.Failed("Could not target org %s from %s\n%s", orgName, configuration.CF_ORG, apiResponse.Message)
+		return false
+	}
+
+	config.SetOrganizationFields(org.OrganizationFields)
+	return true
+}
+
+// targetSpaceFromEnv targets the space named in CF_SPACE within the targeted
+// org.
+func targetSpaceFromEnv(ui terminal.UI, config configuration.ReadWriter, spaceRepo api.SpaceRepository) bool {
+	spaceName := config.EnvOverrides().SpaceName
+	if spaceName == "" {
+		return false
+	}
+
+	space, apiResponse := spaceRepo.FindByName(spaceName)
+	if apiResponse.IsNotSuccessful() {
+		ui.Failed("Could not target space %s from %s\n%s", spaceName, configuration.CF_SPACE, apiResponse.Message)
+		return false
+	}
+
+	config.SetSpaceFields(space.SpaceFields)
+	return true
+}
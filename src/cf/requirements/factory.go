@@ -27,11 +27,11 @@ type Factory interface {
 
 type apiRequirementFactory struct {
 	ui          terminal.UI
-	config      configuration.Reader
+	config      configuration.ReadWriter
 	repoLocator api.RepositoryLocator
 }
 
-func NewFactory(ui terminal.UI, config configuration.Reader, repoLocator api.RepositoryLocator) (factory apiRequirementFactory) {
+func NewFactory(ui terminal.UI, config configuration.ReadWriter, repoLocator api.RepositoryLocator) (factory apiRequirementFactory) {
 	return apiRequirementFactory{ui, config, repoLocator}
 }
 
@@ -55,6 +55,8 @@ func (f apiRequirementFactory) NewLoginRequirement() Requirement {
 	return NewLoginRequirement(
 		f.ui,
 		f.config,
+		f.repoLocator.GetAuthenticationRepository(),
+		f.repoLocator.GetEndpointRepository(),
 	)
 }
 
@@ -77,6 +79,8 @@ func (f apiRequirementFactory) NewTargetedSpaceRequirement() Requirement {
 	return NewTargetedSpaceRequirement(
 		f.ui,
 		f.config,
+		f.repoLocator.GetOrganizationRepository(),
+		f.repoLocator.GetSpaceRepository(),
 	)
 }
 
@@ -84,6 +88,7 @@ func (f apiRequirementFactory) NewTargetedOrgRequirement() TargetedOrgRequiremen
 	return NewTargetedOrgRequirement(
 		f.ui,
 		f.config,
+		f.repoLocator.GetOrganizationRepository(),
 	)
 }
 
@@ -124,5 +129,6 @@ func (f apiRequirementFactory) NewApiEndpointRequirement() Requirement {
 	return NewApiEndpointRequirement(
 		f.ui,
 		f.config,
+		f.repoLocator.GetEndpointRepository(),
 	)
 }
@@ -1,18 +1,21 @@
 package requirements
 
 import (
+	"cf/api"
 	"cf/configuration"
+	"cf/net"
 	"cf/terminal"
 )
 
 type LoginRequirement struct {
 	ui                     terminal.UI
-	config                 configuration.Reader
+	config                 configuration.ReadWriter
+	authRepo               api.AuthenticationRepository
 	apiEndpointRequirement ApiEndpointRequirement
 }
 
-func NewLoginRequirement(ui terminal.UI, config configuration.Reader) LoginRequirement {
-	return LoginRequirement{ui, config, ApiEndpointRequirement{ui, config}}
+func NewLoginRequirement(ui terminal.UI, config configuration.ReadWriter, authRepo api.AuthenticationRepository, endpointRepo api.EndpointRepository) LoginRequirement {
+	return LoginRequirement{ui, config, authRepo, ApiEndpointRequirement{ui, config, endpointRepo}}
 }
 
 func (req LoginRequirement) Execute() (success bool) {
@@ -20,10 +23,36 @@ func (req LoginRequirement) Execute() (success bool) {
 		return false
 	}
 
-	if !req.config.IsLoggedIn() {
+	if !req.config.IsLoggedIn() && !req.authenticateFromEnv() {
 		req.ui.Say(terminal.NotLoggedInText())
 		return false
 	}
 
 	return true
 }
+
+// authenticateFromEnv logs in with the credentials set in CF_USERNAME and
+// CF_PASSWORD, or CF_CLIENT_ID and CF_CLIENT_SECRET, if any. The session is
+// not saved, so it ends with the command.
+func (req LoginRequirement) authenticateFromEnv() bool {
+	overrides := req.config.EnvOverrides()
+	if overrides.HasUserCredentials() || overrides.HasClientCredentials() {
+		req.config.UseEnvSession()
+	}
+
+	var apiResponse net.ApiResponse
+	switch {
+	case overrides.HasUserCredentials():
+		apiResponse = req.authRepo.Authenticate(overrides.Username, overrides.Password)
+	case overrides.HasClientCredentials():
+		apiResponse = req.authRepo.AuthenticateClient(overrides.ClientId, overrides.ClientSecret)
+	default:
+		return false
+	}
+
+	if apiResponse.IsNotSuccessful() {
+		req.ui.Failed(apiResponse.Message)
+		return false
+	}
+	return true
+}
@@ -5,22 +5,43 @@ import (
 	. "cf/requirements"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"os"
+	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testterm "testhelpers/terminal"
 )
 
 var _ = Describe("LoginRequirement", func() {
-	var ui *testterm.FakeUI
+	var (
+		ui           *testterm.FakeUI
+		authRepo     *testapi.FakeAuthenticationRepository
+		endpointRepo *testapi.FakeEndpointRepo
+	)
 
 	BeforeEach(func() {
 		ui = new(testterm.FakeUI)
+		authRepo = &testapi.FakeAuthenticationRepository{}
+		endpointRepo = &testapi.FakeEndpointRepo{}
 	})
 
+	AfterEach(func() {
+		for _, name := range []string{configuration.CF_API, configuration.CF_USERNAME, configuration.CF_PASSWORD,
+			configuration.CF_CLIENT_ID, configuration.CF_CLIENT_SECRET} {
+			os.Unsetenv(name)
+		}
+	})
+
+	newLoginRequirement := func(config configuration.ReadWriter) LoginRequirement {
+		authRepo.Config = config
+		endpointRepo.Config = config
+		return NewLoginRequirement(ui, config, authRepo, endpointRepo)
+	}
+
 	It("succeeds when given a config with an API endpoint and authentication", func() {
 		config := testconfig.NewRepositoryWithAccessToken(configuration.TokenInfo{Username: "my-user"})
 		config.SetApiEndpoint("api.example.com")
-		req := NewLoginRequirement(ui, config)
+		req := newLoginRequirement(config)
 		success := req.Execute()
 		Expect(success).To(BeTrue())
 	})
@@ -28,7 +49,7 @@ var _ = Describe("LoginRequirement", func() {
 	It("fails when given a config with only an API endpoint", func() {
 		config := testconfig.NewRepository()
 		config.SetApiEndpoint("api.example.com")
-		req := NewLoginRequirement(ui, config)
+		req := newLoginRequirement(config)
 		success := req.Execute()
 		Expect(success).To(BeFalse())
 
@@ -37,11 +58,52 @@ var _ = Describe("LoginRequirement", func() {
 
 	It("fails when given a config with neither an API endpoint nor authentication", func() {
 		config := testconfig.NewRepository()
-		req := NewLoginRequirement(ui, config)
+		req := newLoginRequirement(config)
 		success := req.Execute()
 		Expect(success).To(BeFalse())
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{{"No API endpoint"}})
 		testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{{"Not logged in."}})
 	})
+
+	Describe("with credentials in the environment", func() {
+		BeforeEach(func() {
+			os.Setenv(configuration.CF_API, "https://api.example.com")
+		})
+
+		It("logs in with CF_USERNAME and CF_PASSWORD", func() {
+			os.Setenv(configuration.CF_USERNAME, "my-user")
+			os.Setenv(configuration.CF_PASSWORD, "my-password")
+			config := testconfig.NewRepository()
+
+			success := newLoginRequirement(config).Execute()
+			Expect(success).To(BeTrue())
+			Expect(endpointRepo.UpdateEndpointReceived).To(Equal("https://api.example.com"))
+			Expect(authRepo.Email).To(Equal("my-user"))
+			Expect(authRepo.Password).To(Equal("my-password"))
+			Expect(config.IsLoggedIn()).To(BeTrue())
+		})
+
+		It("logs in with CF_CLIENT_ID and CF_CLIENT_SECRET", func() {
+			os.Setenv(configuration.CF_CLIENT_ID, "my-client")
+			os.Setenv(configuration.CF_CLIENT_SECRET, "my-secret")
+			config := testconfig.NewRepository()
+
+			success := newLoginRequirement(config).Execute()
+			Expect(success).To(BeTrue())
+			Expect(authRepo.ClientId).To(Equal("my-client"))
+			Expect(authRepo.ClientSecret).To(Equal("my-secret"))
+		})
+
+		It("fails when the credentials are rejected", func() {
+			os.Setenv(configuration.CF_USERNAME, "my-user")
+			os.Setenv(configuration.CF_PASSWORD, "wrong-password")
+			authRepo.AuthError = true
+
+			testassert.AssertPanic(testterm.FailedWasCalled, func() {
+				newLoginRequirement(testconfig.NewRepository()).Execute()
+			})
+			testassert.SliceContains(ui.Outputs, testassert.Lines{{"FAILED"}, {"Error authenticating"}})
+		})
+	})
 })
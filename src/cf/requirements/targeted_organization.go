@@ -2,6 +2,7 @@ package requirements
 
 import (
 	"cf"
+	"cf/api"
 	"cf/configuration"
 	"cf/models"
 	"cf/terminal"
@@ -14,16 +15,17 @@ type TargetedOrgRequirement interface {
 }
 
 type targetedOrgApiRequirement struct {
-	ui     terminal.UI
-	config configuration.Reader
+	ui      terminal.UI
+	config  configuration.ReadWriter
+	orgRepo api.OrganizationRepository
 }
 
-func NewTargetedOrgRequirement(ui terminal.UI, config configuration.Reader) TargetedOrgRequirement {
-	return targetedOrgApiRequirement{ui, config}
+func NewTargetedOrgRequirement(ui terminal.UI, config configuration.ReadWriter, orgRepo api.OrganizationRepository) TargetedOrgRequirement {
+	return targetedOrgApiRequirement{ui, config, orgRepo}
 }
 
 func (req targetedOrgApiRequirement) Execute() (success bool) {
-	if !req.config.HasOrganization() {
+	if !req.config.HasOrganization() && !targetOrgFromEnv(req.ui, req.config, req.orgRepo) {
 		message := fmt.Sprintf("No org targeted, use '%s' to target an org.",
 			terminal.CommandColor(cf.Name()+" target -o ORG"))
 		req.ui.Failed(message)
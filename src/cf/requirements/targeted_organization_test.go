@@ -1,10 +1,13 @@
 package requirements_test
 
 import (
+	"cf/configuration"
 	"cf/models"
 	. "cf/requirements"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"os"
+	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testterm "testhelpers/terminal"
@@ -18,14 +21,14 @@ var _ = Describe("Testing with ginkgo", func() {
 		org.Guid = "my-org-guid"
 		config := testconfig.NewRepositoryWithDefaults()
 
-		req := NewTargetedOrgRequirement(ui, config)
+		req := NewTargetedOrgRequirement(ui, config, &testapi.FakeOrgRepository{})
 		success := req.Execute()
 		Expect(success).To(BeTrue())
 
 		config.SetOrganizationFields(models.OrganizationFields{})
 
 		testassert.AssertPanic(testterm.FailedWasCalled, func() {
-			NewTargetedOrgRequirement(ui, config).Execute()
+			NewTargetedOrgRequirement(ui, config, &testapi.FakeOrgRepository{}).Execute()
 		})
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{
@@ -33,4 +36,20 @@ var _ = Describe("Testing with ginkgo", func() {
 			{"No org targeted"},
 		})
 	})
+
+	It("targets the org named in CF_ORG", func() {
+		os.Setenv(configuration.CF_ORG, "env-org")
+		defer os.Unsetenv(configuration.CF_ORG)
+
+		org := models.Organization{}
+		org.Name = "env-org"
+		org.Guid = "env-org-guid"
+		orgRepo := &testapi.FakeOrgRepository{Organizations: []models.Organization{org}}
+		config := testconfig.NewRepository()
+
+		req := NewTargetedOrgRequirement(new(testterm.FakeUI), config, orgRepo)
+		Expect(req.Execute()).To(BeTrue())
+		Expect(orgRepo.FindByNameName).To(Equal("env-org"))
+		Expect(req.GetOrganizationFields()).To(Equal(org.OrganizationFields))
+	})
 })
@@ -2,29 +2,32 @@ package requirements
 
 import (
 	"cf"
+	"cf/api"
 	"cf/configuration"
 	"cf/terminal"
 	"fmt"
 )
 
 type TargetedSpaceRequirement struct {
-	ui     terminal.UI
-	config configuration.Reader
+	ui        terminal.UI
+	config    configuration.ReadWriter
+	orgRepo   api.OrganizationRepository
+	spaceRepo api.SpaceRepository
 }
 
-func NewTargetedSpaceRequirement(ui terminal.UI, config configuration.Reader) TargetedSpaceRequirement {
-	return TargetedSpaceRequirement{ui, config}
+func NewTargetedSpaceRequirement(ui terminal.UI, config configuration.ReadWriter, orgRepo api.OrganizationRepository, spaceRepo api.SpaceRepository) TargetedSpaceRequirement {
+	return TargetedSpaceRequirement{ui, config, orgRepo, spaceRepo}
 }
 
 func (req TargetedSpaceRequirement) Execute() (success bool) {
-	if !req.config.HasOrganization() {
+	if !req.config.HasOrganization() && !targetOrgFromEnv(req.ui, req.config, req.orgRepo) {
 		message := fmt.Sprintf("No org and space targeted, use '%s' to target an org and space",
 			terminal.CommandColor(cf.Name()+" target -o ORG -s SPACE"))
 		req.ui.Failed(message)
 		return false
 	}
 
-	if !req.config.HasSpace() {
+	if !req.config.HasSpace() && !targetSpaceFromEnv(req.ui, req.config, req.spaceRepo) {
 		message := fmt.Sprintf("No space targeted, use '%s' to target a space", terminal.CommandColor("cf target -s"))
 		req.ui.Failed(message)
 		return false
@@ -1,10 +1,13 @@
 package requirements_test
 
 import (
+	"cf/configuration"
 	"cf/models"
 	. "cf/requirements"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"os"
+	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testconfig "testhelpers/configuration"
 	testterm "testhelpers/terminal"
@@ -21,14 +24,14 @@ var _ = Describe("Testing with ginkgo", func() {
 		space.Guid = "my-space-guid"
 		config := testconfig.NewRepositoryWithDefaults()
 
-		req := NewTargetedSpaceRequirement(ui, config)
+		req := NewTargetedSpaceRequirement(ui, config, &testapi.FakeOrgRepository{}, &testapi.FakeSpaceRepository{})
 		success := req.Execute()
 		Expect(success).To(BeTrue())
 
 		config.SetSpaceFields(models.SpaceFields{})
 
 		testassert.AssertPanic(testterm.FailedWasCalled, func() {
-			NewTargetedSpaceRequirement(ui, config).Execute()
+			NewTargetedSpaceRequirement(ui, config, &testapi.FakeOrgRepository{}, &testapi.FakeSpaceRepository{}).Execute()
 		})
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{
@@ -40,7 +43,7 @@ var _ = Describe("Testing with ginkgo", func() {
 		config.SetOrganizationFields(models.OrganizationFields{})
 
 		testassert.AssertPanic(testterm.FailedWasCalled, func() {
-			NewTargetedSpaceRequirement(ui, config).Execute()
+			NewTargetedSpaceRequirement(ui, config, &testapi.FakeOrgRepository{}, &testapi.FakeSpaceRepository{}).Execute()
 		})
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{
@@ -48,4 +51,30 @@ var _ = Describe("Testing with ginkgo", func() {
 			{"No org and space targeted"},
 		})
 	})
+
+	It("targets the org and space named in CF_ORG and CF_SPACE", func() {
+		os.Setenv(configuration.CF_ORG, "env-org")
+		os.Setenv(configuration.CF_SPACE, "env-space")
+		defer os.Unsetenv(configuration.CF_ORG)
+		defer os.Unsetenv(configuration.CF_SPACE)
+
+		org := models.Organization{}
+		org.Name = "env-org"
+		org.Guid = "env-org-guid"
+		orgRepo := &testapi.FakeOrgRepository{Organizations: []models.Organization{org}}
+
+		space := models.Space{}
+		space.Name = "env-space"
+		space.Guid = "env-space-guid"
+		spaceRepo := &testapi.FakeSpaceRepository{Spaces: []models.Space{space}}
+
+		config := testconfig.NewRepository()
+		Expect(config.OrganizationFields().Name).To(Equal("env-org"))
+
+		success := NewTargetedSpaceRequirement(new(testterm.FakeUI), config, orgRepo, spaceRepo).Execute()
+		Expect(success).To(BeTrue())
+		Expect(spaceRepo.FindByNameName).To(Equal("env-space"))
+		Expect(config.OrganizationFields()).To(Equal(org.OrganizationFields))
+		Expect(config.SpaceFields()).To(Equal(space.SpaceFields))
+	})
 })
@@ -2,6 +2,7 @@ package requirements
 
 import (
 	"cf/api"
+	"cf/net"
 	"cf/terminal"
 )
 
@@ -17,7 +18,7 @@ func NewValidAccessTokenRequirement(ui terminal.UI, appRepo api.ApplicationRepos
 func (req ValidAccessTokenRequirement) Execute() (success bool) {
 	_, apiResponse := req.appRepo.Read("checking_for_valid_access_token")
 
-	if apiResponse.IsNotSuccessful() && apiResponse.StatusCode == 401 {
+	if net.IsAuthError(apiResponse.Err()) {
 		req.ui.Say(terminal.NotLoggedInText())
 		return false
 	}
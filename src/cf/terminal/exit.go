@@ -0,0 +1,36 @@
+package terminal
+
+import (
+	"os"
+	"sync"
+)
+
+var exitHooks []func()
+var exitHooksMutex sync.Mutex
+
+// OnExit registers a function to run when the command ends, whether it
+// finishes or cf exits early through Failed or FailWithUsage.
+func OnExit(hook func()) {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+
+	exitHooks = append(exitHooks, hook)
+}
+
+// RunExitHooks runs the registered functions, last registered first. Each one
+// runs only once.
+func RunExitHooks() {
+	exitHooksMutex.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMutex.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+func Exit(code int) {
+	RunExitHooks()
+	os.Exit(code)
+}
@@ -0,0 +1,20 @@
+package terminal_test
+
+import (
+	. "cf/terminal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("exit hooks", func() {
+	It("runs the hooks once, last registered first", func() {
+		calls := []string{}
+		OnExit(func() { calls = append(calls, "first") })
+		OnExit(func() { calls = append(calls, "second") })
+
+		RunExitHooks()
+		RunExitHooks()
+
+		Expect(calls).To(Equal([]string{"second", "first"}))
+	})
+})
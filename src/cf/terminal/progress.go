@@ -0,0 +1,46 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// ProgressSpinner redraws a spinner and the elapsed time on a single line
+// while waiting for something, e.g. an async job, and clears it when done.
+type ProgressSpinner struct {
+	out       io.Writer
+	frame     int
+	lineWidth int
+}
+
+func NewProgressSpinner(out io.Writer) *ProgressSpinner {
+	return &ProgressSpinner{out: out}
+}
+
+func (spinner *ProgressSpinner) Update(elapsed time.Duration, done bool) {
+	if done {
+		if spinner.lineWidth > 0 {
+			fmt.Fprintf(spinner.out, "\r%s\r", strings.Repeat(" ", spinner.lineWidth))
+		}
+		spinner.frame = 0
+		spinner.lineWidth = 0
+		return
+	}
+
+	line := fmt.Sprintf("%s %s", spinnerFrames[spinner.frame%len(spinnerFrames)], elapsed/time.Second*time.Second)
+	fmt.Fprintf(spinner.out, "\r%s", line)
+	spinner.frame++
+	spinner.lineWidth = len(line)
+}
+
+// IsTerminal reports whether file is an interactive terminal, as opposed to
+// a pipe or a regular file.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,24 @@
+package terminal_test
+
+import (
+	"bytes"
+	. "cf/terminal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("ProgressSpinner", func() {
+	It("redraws the spinner and elapsed time on one line and clears it when done", func() {
+		out := &bytes.Buffer{}
+		spinner := NewProgressSpinner(out)
+
+		spinner.Update(1500*time.Millisecond, false)
+		spinner.Update(6*time.Second, false)
+		Expect(out.String()).To(Equal("\r| 1s\r/ 6s"))
+
+		out.Reset()
+		spinner.Update(7*time.Second, true)
+		Expect(out.String()).To(Equal("\r    \r"))
+	})
+})
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"github.com/codegangsta/cli"
 	"io"
-	"os"
 	"strings"
 	"time"
 )
@@ -91,9 +90,11 @@ func (c terminalUI) Failed(message string, args ...interface{}) {
 	c.Say(FailureColor("FAILED"))
 	c.Say(message)
 
-	trace.Logger.Print("FAILED")
-	trace.Logger.Print(message)
-	os.Exit(1)
+	if trace.Format() == trace.TEXT_FORMAT {
+		trace.Logger.Print("FAILED")
+		trace.Logger.Print(message)
+	}
+	Exit(1)
 }
 
 func (c terminalUI) FailWithUsage(ctxt *cli.Context, cmdName string) {
@@ -101,7 +102,7 @@ func (c terminalUI) FailWithUsage(ctxt *cli.Context, cmdName string) {
 	c.Say("Incorrect Usage.\n")
 	cli.ShowCommandHelp(ctxt, cmdName)
 	c.Say("")
-	os.Exit(1)
+	Exit(1)
 }
 
 func (c terminalUI) ConfigFailure(err error) {
@@ -118,7 +119,11 @@ func (ui terminalUI) ShowConfiguration(config configuration.Reader) {
 		ui.Say(NotLoggedInText())
 		return
 	} else {
-		ui.Say("User:         %s", EntityNameColor(config.UserEmail()))
+		user := config.UserEmail()
+		if user == "" {
+			user = config.Username()
+		}
+		ui.Say("User:         %s", EntityNameColor(user))
 	}
 
 	if !config.HasOrganization() && !config.HasSpace() {
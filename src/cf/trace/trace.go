@@ -5,9 +5,17 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 )
 
-const CF_TRACE = "CF_TRACE"
+const (
+	CF_TRACE        = "CF_TRACE"
+	CF_TRACE_FORMAT = "CF_TRACE_FORMAT"
+
+	TEXT_FORMAT = "text"
+	JSON_FORMAT = "json"
+	HAR_FORMAT  = "har"
+)
 
 type Printer interface {
 	Print(v ...interface{})
@@ -36,6 +44,41 @@ func DisableTrace() {
 	Logger = new(nullLogger)
 }
 
+func Enabled() bool {
+	_, disabled := Logger.(*nullLogger)
+	return !disabled
+}
+
+// Format returns the trace format selected with CF_TRACE_FORMAT, defaulting
+// to the human readable text dumps. A HAR document is only written to a trace
+// file, so har falls back to json when tracing to stdout.
+func Format() string {
+	format := strings.ToLower(os.Getenv(CF_TRACE_FORMAT))
+	switch format {
+	case JSON_FORMAT:
+		return format
+	case HAR_FORMAT:
+		if FilePath() == "" {
+			return JSON_FORMAT
+		}
+		return format
+	default:
+		return TEXT_FORMAT
+	}
+}
+
+// FilePath returns the log file CF_TRACE points at, or an empty string when
+// tracing to stdout or not at all.
+func FilePath() string {
+	cf_trace := os.Getenv(CF_TRACE)
+	switch cf_trace {
+	case "", "false", "true":
+		return ""
+	default:
+		return cf_trace
+	}
+}
+
 func SetStdout(s io.Writer) {
 	stdOut = s
 }
@@ -87,4 +87,33 @@ var _ = Describe("Testing with ginkgo", func() {
 			})
 		}
 	})
+
+	It("TestTraceFormatDefaultsToText", func() {
+		os.Setenv(trace.CF_TRACE_FORMAT, "")
+		Expect(trace.Format()).To(Equal(trace.TEXT_FORMAT))
+
+		os.Setenv(trace.CF_TRACE_FORMAT, "xml")
+		Expect(trace.Format()).To(Equal(trace.TEXT_FORMAT))
+	})
+
+	It("TestTraceFormatSetToJsonOrHar", func() {
+		defer os.Setenv(trace.CF_TRACE_FORMAT, "")
+
+		os.Setenv(trace.CF_TRACE_FORMAT, "json")
+		Expect(trace.Format()).To(Equal(trace.JSON_FORMAT))
+
+		os.Setenv(trace.CF_TRACE_FORMAT, "HAR")
+		Expect(trace.Format()).To(Equal(trace.HAR_FORMAT))
+	})
+
+	It("TestTraceFilePath", func() {
+		os.Setenv(trace.CF_TRACE, "true")
+		Expect(trace.FilePath()).To(Equal(""))
+
+		os.Setenv(trace.CF_TRACE, "/tmp/trace.log")
+		Expect(trace.FilePath()).To(Equal("/tmp/trace.log"))
+
+		os.Setenv(trace.CF_TRACE, "")
+		Expect(trace.FilePath()).To(Equal(""))
+	})
 })
@@ -23,6 +23,7 @@ type cliDependencies struct {
 	configRepo     configuration.Repository
 	manifestRepo   manifest.ManifestRepository
 	apiRepoLocator api.RepositoryLocator
+	timings        *net.TimingsRecorder
 }
 
 func setupDependencies() (deps *cliDependencies) {
@@ -44,11 +45,26 @@ func setupDependencies() (deps *cliDependencies) {
 		}
 	})
 
-	deps.apiRepoLocator = api.NewRepositoryLocator(deps.configRepo, map[string]net.Gateway{
-		"auth":             net.NewUAAGateway(),
-		"cloud-controller": net.NewCloudControllerGateway(),
-		"uaa":              net.NewUAAGateway(),
-	})
+	deps.timings = net.NewTimingsRecorder()
+
+	gateways := map[string]net.Gateway{
+		"auth":             net.NewUAAGateway(deps.configRepo),
+		"cloud-controller": net.NewCloudControllerGateway(deps.configRepo),
+		"uaa":              net.NewUAAGateway(deps.configRepo),
+	}
+	for name, gateway := range gateways {
+		gateway.SetUI(deps.termUI)
+		gateway.SetTimingsRecorder(deps.timings)
+		if terminal.IsTerminal(os.Stdout) {
+			gateway.SetJobProgressCallback(terminal.NewProgressSpinner(os.Stdout).Update)
+		}
+		if terminal.IsTerminal(os.Stdin) {
+			gateway.EnableReauthentication()
+		}
+		gateways[name] = gateway
+	}
+
+	deps.apiRepoLocator = api.NewRepositoryLocator(deps.configRepo, gateways)
 
 	return
 }
@@ -63,9 +79,12 @@ func main() {
 	deps := setupDependencies()
 	defer teardownDependencies(deps)
 
+	terminal.OnExit(net.WriteHARTrace)
+	defer terminal.RunExitHooks()
+
 	cmdFactory := commands.NewFactory(deps.termUI, deps.configRepo, deps.manifestRepo, deps.apiRepoLocator)
 	reqFactory := requirements.NewFactory(deps.termUI, deps.configRepo, deps.apiRepoLocator)
-	cmdRunner := commands.NewRunner(cmdFactory, reqFactory)
+	cmdRunner := commands.NewRunner(deps.termUI, cmdFactory, reqFactory, deps.timings)
 
 	app, err := app.NewApp(cmdRunner)
 	if err != nil {
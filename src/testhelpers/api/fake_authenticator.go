@@ -1,6 +1,7 @@
 package api
 
 import (
+	"cf/api"
 	"cf/configuration"
 	"cf/net"
 )
@@ -10,6 +11,12 @@ type FakeAuthenticationRepository struct {
 	Email    string
 	Password string
 
+	ClientId     string
+	ClientSecret string
+	Passcode     string
+
+	LoginPrompts map[string]api.AuthPrompt
+
 	AuthError    bool
 	AccessToken  string
 	RefreshToken string
@@ -34,6 +41,49 @@ func (auth *FakeAuthenticationRepository) Authenticate(email string, password st
 	return
 }
 
+func (auth *FakeAuthenticationRepository) AuthenticateClient(clientId string, clientSecret string) (apiResponse net.ApiResponse) {
+	auth.ClientId = clientId
+	auth.ClientSecret = clientSecret
+
+	if auth.AuthError {
+		apiResponse = net.NewApiResponseWithMessage("Error authenticating.")
+		return
+	}
+
+	if auth.AccessToken == "" {
+		auth.AccessToken = "BEARER some_access_token"
+	}
+
+	auth.Config.SetAccessToken(auth.AccessToken)
+	auth.Config.SetRefreshToken("")
+	auth.Config.SetClientCredentials(clientId, clientSecret)
+
+	return
+}
+
+func (auth *FakeAuthenticationRepository) AuthenticateWithPasscode(passcode string) (apiResponse net.ApiResponse) {
+	auth.Passcode = passcode
+
+	if auth.AuthError {
+		apiResponse = net.NewApiResponseWithMessage("Error authenticating.")
+		return
+	}
+
+	if auth.AccessToken == "" {
+		auth.AccessToken = "BEARER some_access_token"
+	}
+
+	auth.Config.SetAccessToken(auth.AccessToken)
+	auth.Config.SetRefreshToken(auth.RefreshToken)
+
+	return
+}
+
+func (auth *FakeAuthenticationRepository) GetLoginPrompts() (prompts map[string]api.AuthPrompt, apiResponse net.ApiResponse) {
+	prompts = auth.LoginPrompts
+	return
+}
+
 func (auth *FakeAuthenticationRepository) RefreshAuthToken() (updatedToken string, apiResponse net.ApiResponse) {
 	return
 }
@@ -16,10 +16,11 @@ type FakeDomainRepository struct {
 	ListDomainsDomains     []models.DomainFields
 	ListDomainsApiResponse net.ApiResponse
 
-	FindByNameInOrgName      string
-	FindByNameInOrgGuid      string
+	FindByNameInOrgName        string
+	FindByNameInOrgGuid        string
 	FindByNameInOrgDomain      models.DomainFields
 	FindByNameInOrgApiResponse net.ApiResponse
+	FindByNameInOrgDomains     map[string]models.DomainFields
 
 	FindByNameName     string
 	FindByNameDomain   models.DomainFields
@@ -28,6 +29,7 @@ type FakeDomainRepository struct {
 
 	CreateDomainName          string
 	CreateDomainOwningOrgGuid string
+	CreateApiResponse         net.ApiResponse
 
 	CreateSharedDomainName string
 
@@ -77,6 +79,16 @@ func (repo *FakeDomainRepository) FindByName(name string) (domain models.DomainF
 func (repo *FakeDomainRepository) FindByNameInOrg(name string, owningOrgGuid string) (domain models.DomainFields, apiResponse net.ApiResponse) {
 	repo.FindByNameInOrgName = name
 	repo.FindByNameInOrgGuid = owningOrgGuid
+
+	if repo.FindByNameInOrgDomains != nil {
+		var found bool
+		domain, found = repo.FindByNameInOrgDomains[name]
+		if !found {
+			apiResponse = net.NewNotFoundApiResponse("Domain %s not found", name)
+		}
+		return
+	}
+
 	domain = repo.FindByNameInOrgDomain
 	apiResponse = repo.FindByNameInOrgApiResponse
 	return
@@ -85,6 +97,7 @@ func (repo *FakeDomainRepository) FindByNameInOrg(name string, owningOrgGuid str
 func (repo *FakeDomainRepository) Create(domainName string, owningOrgGuid string) (createdDomain models.DomainFields, apiResponse net.ApiResponse) {
 	repo.CreateDomainName = domainName
 	repo.CreateDomainOwningOrgGuid = owningOrgGuid
+	apiResponse = repo.CreateApiResponse
 	return
 }
 
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"cf"
 	"cf/models"
 	"cf/net"
+	"net/http"
 )
 
 type FakeRouteRepository struct {
@@ -18,6 +20,8 @@ type FakeRouteRepository struct {
 
 	CreatedHost       string
 	CreatedDomainGuid string
+	CreatedHosts      []string
+	CreateErr         bool
 
 	CreateInSpaceHost         string
 	CreateInSpaceDomainGuid   string
@@ -25,11 +29,13 @@ type FakeRouteRepository struct {
 	CreateInSpaceCreatedRoute models.Route
 	CreateInSpaceErr          bool
 
-	BoundRouteGuid string
-	BoundAppGuid   string
+	BoundRouteGuid  string
+	BoundAppGuid    string
+	BoundRouteGuids []string
 
-	UnboundRouteGuid string
-	UnboundAppGuid   string
+	UnboundRouteGuid  string
+	UnboundAppGuid    string
+	UnboundRouteGuids []string
 
 	ListErr bool
 	Routes  []models.Route
@@ -80,9 +86,14 @@ func (repo *FakeRouteRepository) FindByHostAndDomain(host, domain string) (route
 func (repo *FakeRouteRepository) Create(host, domainGuid string) (createdRoute models.Route, apiResponse net.ApiResponse) {
 	repo.CreatedHost = host
 	repo.CreatedDomainGuid = domainGuid
+	repo.CreatedHosts = append(repo.CreatedHosts, host)
 
-	createdRoute.Guid = host + "-route-guid"
+	if repo.CreateErr {
+		apiResponse = net.NewApiResponse("The host is taken: "+host, cf.ROUTE_HOST_TAKEN, http.StatusBadRequest)
+		return
+	}
 
+	createdRoute.Guid = host + "-route-guid"
 	return
 }
 
@@ -92,7 +103,7 @@ func (repo *FakeRouteRepository) CreateInSpace(host, domainGuid, spaceGuid strin
 	repo.CreateInSpaceSpaceGuid = spaceGuid
 
 	if repo.CreateInSpaceErr {
-		apiResponse = net.NewApiResponseWithMessage("Error")
+		apiResponse = net.NewApiResponse("The host is taken: "+host, cf.ROUTE_HOST_TAKEN, http.StatusBadRequest)
 	} else {
 		createdRoute = repo.CreateInSpaceCreatedRoute
 	}
@@ -103,12 +114,14 @@ func (repo *FakeRouteRepository) CreateInSpace(host, domainGuid, spaceGuid strin
 func (repo *FakeRouteRepository) Bind(routeGuid, appGuid string) (apiResponse net.ApiResponse) {
 	repo.BoundRouteGuid = routeGuid
 	repo.BoundAppGuid = appGuid
+	repo.BoundRouteGuids = append(repo.BoundRouteGuids, routeGuid)
 	return
 }
 
 func (repo *FakeRouteRepository) Unbind(routeGuid, appGuid string) (apiResponse net.ApiResponse) {
 	repo.UnboundRouteGuid = routeGuid
 	repo.UnboundAppGuid = appGuid
+	repo.UnboundRouteGuids = append(repo.UnboundRouteGuids, routeGuid)
 	return
 }
 
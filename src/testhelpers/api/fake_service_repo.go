@@ -32,6 +32,9 @@ type FakeServiceRepo struct {
 
 	FindInstanceByNameMap generic.Map
 
+	FindInstanceByNameResponses []net.ApiResponse
+	findInstanceByNameCallCount int
+
 	DeleteServiceServiceInstance models.ServiceInstance
 
 	RenameServiceServiceInstance models.ServiceInstance
@@ -113,6 +116,11 @@ func (repo *FakeServiceRepo) FindInstanceByName(name string) (instance models.Se
 		apiResponse = net.NewNotFoundApiResponse("%s %s not found", "Service instance", name)
 	}
 
+	if len(repo.FindInstanceByNameResponses) > repo.findInstanceByNameCallCount {
+		apiResponse = repo.FindInstanceByNameResponses[repo.findInstanceByNameCallCount]
+	}
+	repo.findInstanceByNameCallCount += 1
+
 	return
 }
 
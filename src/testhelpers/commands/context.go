@@ -3,11 +3,13 @@ package commands
 import (
 	"cf/app"
 	"cf/commands"
+	"cf/net"
 	"flag"
 	"fmt"
 	"github.com/codegangsta/cli"
 	"strings"
 	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
 )
 
 func NewContext(cmdName string, args []string) *cli.Context {
@@ -42,7 +44,7 @@ func NewContext(cmdName string, args []string) *cli.Context {
 func findCommand(cmdName string) (cmd cli.Command) {
 	cmdFactory := commands.ConcreteFactory{}
 	reqFactory := &testreq.FakeReqFactory{}
-	cmdRunner := commands.NewRunner(cmdFactory, reqFactory)
+	cmdRunner := commands.NewRunner(&testterm.FakeUI{}, cmdFactory, reqFactory, net.NewTimingsRecorder())
 	myApp, _ := app.NewApp(cmdRunner)
 
 	for _, cmd := range myApp.Commands {
@@ -11,11 +11,17 @@ type FakePersistor struct {
 	}
 
 	SaveArgs struct {
-		Data *configuration.Data
+		Data   *configuration.Data
+		Locked bool
 	}
 	SaveReturns struct {
 		Err error
 	}
+
+	ChangedReturns bool
+
+	Locked     bool
+	WipeCalled bool
 }
 
 func NewFakePersistor() *FakePersistor {
@@ -35,8 +41,27 @@ func (fp *FakePersistor) Delete() {
 
 }
 
+func (fp *FakePersistor) Wipe() (err error) {
+	fp.WipeCalled = true
+	return
+}
+
+func (fp *FakePersistor) Lock() (err error) {
+	fp.Locked = true
+	return
+}
+
+func (fp *FakePersistor) Unlock() {
+	fp.Locked = false
+}
+
+func (fp *FakePersistor) Changed() bool {
+	return fp.ChangedReturns
+}
+
 func (fp *FakePersistor) Save(data *configuration.Data) (err error) {
 	fp.SaveArgs.Data = data
+	fp.SaveArgs.Locked = fp.Locked
 	err = fp.SaveReturns.Err
 	return
 }
@@ -7,16 +7,25 @@ import (
 type FakeManifestRepository struct {
 	ReadManifestArgs struct {
 		Path string
+		Vars manifest.Variables
 	}
 	ReadManifestReturns struct {
 		Manifest *manifest.Manifest
-		Path string
-		Errors manifest.ManifestErrors
+		Path     string
+		Errors   manifest.ManifestErrors
+	}
+	WriteManifestArgs struct {
+		Manifest *manifest.Manifest
+		Path     string
+	}
+	WriteManifestReturns struct {
+		Error error
 	}
 }
 
-func (repo *FakeManifestRepository) ReadManifest(inputPath string) (m *manifest.Manifest, path string, errs manifest.ManifestErrors) {
+func (repo *FakeManifestRepository) ReadManifest(inputPath string, vars manifest.Variables) (m *manifest.Manifest, path string, errs manifest.ManifestErrors) {
 	repo.ReadManifestArgs.Path = inputPath
+	repo.ReadManifestArgs.Vars = vars
 	if repo.ReadManifestReturns.Manifest != nil {
 		m = repo.ReadManifestReturns.Manifest
 	} else {
@@ -26,3 +35,10 @@ func (repo *FakeManifestRepository) ReadManifest(inputPath string) (m *manifest.
 	errs = repo.ReadManifestReturns.Errors
 	return
 }
+
+func (repo *FakeManifestRepository) WriteManifest(m *manifest.Manifest, path string) (err error) {
+	repo.WriteManifestArgs.Manifest = m
+	repo.WriteManifestArgs.Path = path
+	err = repo.WriteManifestReturns.Error
+	return
+}